@@ -0,0 +1,310 @@
+'use client';
+
+import { useState, useEffect, useCallback } from 'react';
+import { useRouter } from 'next/navigation';
+import { useAuth } from '@/lib/auth-context';
+import { authAPI, twoFactorAPI, settingsAPI } from '@/lib/api';
+import { TwoFactorStatus, TwoFactorSetupResponse } from '@/lib/types';
+import { Navbar, Card, Button, Input, LoadingSpinner } from '@/components';
+
+export default function SecurityPage() {
+  const router = useRouter();
+  const { admin, isAuthenticated, isSuperAdmin, isLoading: authLoading, login, checkAuth } = useAuth();
+
+  const [status, setStatus] = useState<TwoFactorStatus | null>(null);
+  const [setup, setSetup] = useState<TwoFactorSetupResponse | null>(null);
+  const [recoveryCodes, setRecoveryCodes] = useState<string[] | null>(null);
+  const [code, setCode] = useState('');
+  const [disablePassword, setDisablePassword] = useState('');
+  const [twoFactorError, setTwoFactorError] = useState<string | null>(null);
+  const [isWorking, setIsWorking] = useState(false);
+
+  // Change password state
+  const [currentPassword, setCurrentPassword] = useState('');
+  const [newPassword, setNewPassword] = useState('');
+  const [passwordError, setPasswordError] = useState<string | null>(null);
+  const [passwordMessage, setPasswordMessage] = useState<string | null>(null);
+  const [isChangingPassword, setIsChangingPassword] = useState(false);
+
+  const loadStatus = useCallback(async () => {
+    try {
+      setStatus(await twoFactorAPI.getStatus());
+    } catch (err) {
+      console.error('Failed to load two-factor status');
+    }
+  }, []);
+
+  useEffect(() => {
+    if (!authLoading && !isAuthenticated) {
+      router.push('/auth/login');
+      return;
+    }
+    if (isAuthenticated) {
+      loadStatus();
+    }
+  }, [authLoading, isAuthenticated, router, loadStatus]);
+
+  const run = async (action: () => Promise<void>) => {
+    setIsWorking(true);
+    setTwoFactorError(null);
+    try {
+      await action();
+    } catch (err: any) {
+      setTwoFactorError(err.message || 'Something went wrong');
+    } finally {
+      setIsWorking(false);
+    }
+  };
+
+  const handleStartSetup = () =>
+    run(async () => {
+      setRecoveryCodes(null);
+      setSetup(await twoFactorAPI.setup());
+    });
+
+  const handleEnable = (e: React.FormEvent) => {
+    e.preventDefault();
+    run(async () => {
+      const response = await twoFactorAPI.enable(code);
+      setRecoveryCodes(response.recovery_codes);
+      setSetup(null);
+      setCode('');
+      await loadStatus();
+      await checkAuth();
+    });
+  };
+
+  const handleDisable = (e: React.FormEvent) => {
+    e.preventDefault();
+    run(async () => {
+      await twoFactorAPI.disable(disablePassword, code);
+      setRecoveryCodes(null);
+      setDisablePassword('');
+      setCode('');
+      await loadStatus();
+      await checkAuth();
+    });
+  };
+
+  const handleRegenerate = () =>
+    run(async () => {
+      const response = await twoFactorAPI.regenerateRecoveryCodes(code);
+      setRecoveryCodes(response.recovery_codes);
+      setCode('');
+      await loadStatus();
+    });
+
+  const handleToggleRequired = () =>
+    run(async () => {
+      if (!status) return;
+      await settingsAPI.updateSettings({ require_2fa: !status.required });
+      await loadStatus();
+    });
+
+  const handleChangePassword = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setIsChangingPassword(true);
+    setPasswordError(null);
+    setPasswordMessage(null);
+
+    if (newPassword.length < 6) {
+      setPasswordError('Password must be at least 6 characters');
+      setIsChangingPassword(false);
+      return;
+    }
+
+    try {
+      const response = await authAPI.changePassword({
+        current_password: currentPassword,
+        new_password: newPassword,
+      });
+      // Other sessions have ended; this one continues with the new tokens
+      if (admin) {
+        login(response.token, response.refresh_token, admin);
+      }
+      setCurrentPassword('');
+      setNewPassword('');
+      setPasswordMessage('Password changed. You have been logged out everywhere else.');
+    } catch (err: any) {
+      setPasswordError(err.message || 'Failed to change password');
+    } finally {
+      setIsChangingPassword(false);
+    }
+  };
+
+  if (authLoading || !status) {
+    return (
+      <div className="min-h-screen flex items-center justify-center">
+        <LoadingSpinner size="lg" />
+      </div>
+    );
+  }
+
+  if (!isAuthenticated) {
+    return null;
+  }
+
+  return (
+    <div className="min-h-screen bg-slate-50 font-sans">
+      <Navbar />
+
+      <main className="max-w-3xl mx-auto px-4 sm:px-6 lg:px-8 py-10 space-y-6">
+        <div>
+          <h1 className="text-3xl font-bold text-gray-900">Account Security</h1>
+          <p className="text-gray-600 mt-1">Two-factor authentication and password</p>
+        </div>
+
+        {status.required && !status.enabled && (
+          <div className="p-4 bg-yellow-50 border border-yellow-200 rounded-lg">
+            <p className="text-sm text-yellow-800">
+              Two-factor authentication is required for all admins. Set it up to continue using the dashboard.
+            </p>
+          </div>
+        )}
+
+        <Card>
+          <h2 className="text-lg font-semibold text-gray-900 mb-4">Two-Factor Authentication</h2>
+
+          {twoFactorError && (
+            <div className="mb-4 p-4 bg-red-50 border border-red-200 rounded-lg">
+              <p className="text-sm text-red-600">{twoFactorError}</p>
+            </div>
+          )}
+
+          {recoveryCodes && (
+            <div className="mb-4 p-4 bg-green-50 border border-green-200 rounded-lg">
+              <p className="text-sm text-green-800 mb-2">
+                Store these recovery codes somewhere safe. Each can be used once instead of a code, and they will not be shown again.
+              </p>
+              <div className="grid grid-cols-2 gap-1 font-mono text-sm text-gray-900">
+                {recoveryCodes.map((recoveryCode) => (
+                  <span key={recoveryCode}>{recoveryCode}</span>
+                ))}
+              </div>
+            </div>
+          )}
+
+          {status.enabled ? (
+            <div className="space-y-4">
+              <p className="text-sm text-gray-600">
+                Enabled. {status.recovery_codes_remaining} recovery codes left.
+              </p>
+              <form onSubmit={handleDisable} className="space-y-4">
+                <Input
+                  label="Authentication Code"
+                  type="text"
+                  value={code}
+                  onChange={(e) => setCode(e.target.value)}
+                  placeholder="6-digit code or a recovery code"
+                  autoComplete="one-time-code"
+                  required
+                />
+                <div className="flex gap-2">
+                  <Button type="button" variant="secondary" onClick={handleRegenerate} isLoading={isWorking}>
+                    New Recovery Codes
+                  </Button>
+                </div>
+                {!status.required && (
+                  <>
+                    <Input
+                      label="Password"
+                      type="password"
+                      value={disablePassword}
+                      onChange={(e) => setDisablePassword(e.target.value)}
+                      placeholder="Confirm with your password to disable"
+                    />
+                    <Button type="submit" variant="danger" isLoading={isWorking}>
+                      Disable Two-Factor
+                    </Button>
+                  </>
+                )}
+              </form>
+            </div>
+          ) : setup ? (
+            <form onSubmit={handleEnable} className="space-y-4">
+              <p className="text-sm text-gray-600">
+                Add this account to your authenticator app, then enter the code it shows.
+              </p>
+              <div className="p-3 bg-gray-50 rounded-lg text-sm break-all">
+                <p className="text-gray-500">Secret key</p>
+                <p className="font-mono text-gray-900">{setup.secret}</p>
+                <a href={setup.otpauth_uri} className="text-indigo-600 underline">
+                  Open in authenticator app
+                </a>
+              </div>
+              <Input
+                label="Authentication Code"
+                type="text"
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                placeholder="6-digit code"
+                autoComplete="one-time-code"
+                required
+              />
+              <Button type="submit" isLoading={isWorking}>
+                Enable Two-Factor
+              </Button>
+            </form>
+          ) : (
+            <div className="space-y-4">
+              <p className="text-sm text-gray-600">
+                Protect your account with a code from an authenticator app in addition to your password.
+              </p>
+              <Button onClick={handleStartSetup} isLoading={isWorking}>
+                Set Up Two-Factor
+              </Button>
+            </div>
+          )}
+
+          {isSuperAdmin && (
+            <div className="mt-6 pt-6 border-t border-gray-200 flex items-center justify-between">
+              <div>
+                <p className="text-sm font-medium text-gray-900">Require for all admins</p>
+                <p className="text-sm text-gray-600">
+                  Admins without two-factor authentication can only reach this page.
+                </p>
+              </div>
+              <Button variant="secondary" onClick={handleToggleRequired} isLoading={isWorking}>
+                {status.required ? 'Stop Requiring' : 'Require'}
+              </Button>
+            </div>
+          )}
+        </Card>
+
+        <Card>
+          <h2 className="text-lg font-semibold text-gray-900 mb-4">Change Password</h2>
+          <form onSubmit={handleChangePassword} className="space-y-4">
+            {passwordError && (
+              <div className="p-4 bg-red-50 border border-red-200 rounded-lg">
+                <p className="text-sm text-red-600">{passwordError}</p>
+              </div>
+            )}
+            {passwordMessage && (
+              <div className="p-4 bg-green-50 border border-green-200 rounded-lg">
+                <p className="text-sm text-green-800">{passwordMessage}</p>
+              </div>
+            )}
+            <Input
+              label="Current Password"
+              type="password"
+              value={currentPassword}
+              onChange={(e) => setCurrentPassword(e.target.value)}
+              required
+            />
+            <Input
+              label="New Password"
+              type="password"
+              value={newPassword}
+              onChange={(e) => setNewPassword(e.target.value)}
+              placeholder="At least 6 characters"
+              required
+            />
+            <Button type="submit" isLoading={isChangingPassword}>
+              Change Password
+            </Button>
+          </form>
+        </Card>
+      </main>
+    </div>
+  );
+}
@@ -17,6 +17,8 @@ export default function LoginPage() {
   const [isLoading, setIsLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
   const [superAdminExists, setSuperAdminExists] = useState<boolean | null>(null);
+  const [challengeToken, setChallengeToken] = useState<string | null>(null);
+  const [code, setCode] = useState('');
 
   useEffect(() => {
     // Check if super admin exists
@@ -44,7 +46,11 @@ export default function LoginPage() {
 
     try {
       const response = await authAPI.login({ username, password });
-      login(response.token, response.admin);
+      if ('two_factor_required' in response) {
+        setChallengeToken(response.challenge_token);
+        return;
+      }
+      login(response.token, response.refresh_token, response.admin);
       router.push('/admin/dashboard');
     } catch (err: any) {
       setError(err.message || 'Login failed. Please check your credentials.');
@@ -53,6 +59,30 @@ export default function LoginPage() {
     }
   };
 
+  const handleTwoFactorSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    if (!challengeToken) return;
+    setIsLoading(true);
+    setError(null);
+
+    try {
+      const response = await authAPI.loginTwoFactor({ challenge_token: challengeToken, code });
+      login(response.token, response.refresh_token, response.admin);
+      router.push('/admin/dashboard');
+    } catch (err: any) {
+      setError(err.message || 'Invalid code. Please try again.');
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  const handleBackToPassword = () => {
+    setChallengeToken(null);
+    setCode('');
+    setPassword('');
+    setError(null);
+  };
+
   if (authLoading) {
     return (
       <div className="min-h-screen flex items-center justify-center bg-gray-50">
@@ -88,39 +118,76 @@ export default function LoginPage() {
             </div>
           )}
 
-          <form onSubmit={handleSubmit} className="space-y-4">
-            {error && (
-              <div className="p-4 bg-red-50 border border-red-200 rounded-lg">
-                <p className="text-sm text-red-600">{error}</p>
-              </div>
-            )}
-
-            <Input
-              label="Username"
-              type="text"
-              value={username}
-              onChange={(e) => setUsername(e.target.value)}
-              placeholder="Enter your username"
-              required
-            />
-
-            <Input
-              label="Password"
-              type="password"
-              value={password}
-              onChange={(e) => setPassword(e.target.value)}
-              placeholder="Enter your password"
-              required
-            />
-
-            <Button
-              type="submit"
-              className="w-full"
-              isLoading={isLoading}
-            >
-              Sign In
-            </Button>
-          </form>
+          {challengeToken ? (
+            <form onSubmit={handleTwoFactorSubmit} className="space-y-4">
+              {error && (
+                <div className="p-4 bg-red-50 border border-red-200 rounded-lg">
+                  <p className="text-sm text-red-600">{error}</p>
+                </div>
+              )}
+
+              <Input
+                label="Authentication Code"
+                type="text"
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                placeholder="6-digit code or a recovery code"
+                autoComplete="one-time-code"
+                autoFocus
+                required
+              />
+
+              <Button
+                type="submit"
+                className="w-full"
+                isLoading={isLoading}
+              >
+                Verify
+              </Button>
+
+              <button
+                type="button"
+                onClick={handleBackToPassword}
+                className="w-full text-sm text-gray-600 hover:text-gray-900"
+              >
+                Use a different account
+              </button>
+            </form>
+          ) : (
+            <form onSubmit={handleSubmit} className="space-y-4">
+              {error && (
+                <div className="p-4 bg-red-50 border border-red-200 rounded-lg">
+                  <p className="text-sm text-red-600">{error}</p>
+                </div>
+              )}
+
+              <Input
+                label="Username"
+                type="text"
+                value={username}
+                onChange={(e) => setUsername(e.target.value)}
+                placeholder="Enter your username"
+                required
+              />
+
+              <Input
+                label="Password"
+                type="password"
+                value={password}
+                onChange={(e) => setPassword(e.target.value)}
+                placeholder="Enter your password"
+                required
+              />
+
+              <Button
+                type="submit"
+                className="w-full"
+                isLoading={isLoading}
+              >
+                Sign In
+              </Button>
+            </form>
+          )}
 
           <div className="mt-6 text-center">
             <Link href="/" className="text-sm text-gray-600 hover:text-gray-900">
@@ -66,7 +66,7 @@ export default function RegisterPage() {
 
     try {
       const response = await authAPI.register({ username, email, password });
-      login(response.token, response.admin);
+      login(response.token, response.refresh_token, response.admin);
       router.push('/admin/dashboard');
     } catch (err: any) {
       setError(err.message || 'Registration failed. Please try again.');
@@ -0,0 +1,130 @@
+'use client';
+
+import { useState, Suspense } from 'react';
+import Link from 'next/link';
+import Image from 'next/image';
+import { useSearchParams } from 'next/navigation';
+import { authAPI } from '@/lib/api';
+import { Card, Button, Input, PageLoader } from '@/components';
+
+function ResetPasswordContent() {
+  const searchParams = useSearchParams();
+  const token = searchParams.get('token') || '';
+
+  const [password, setPassword] = useState('');
+  const [confirmPassword, setConfirmPassword] = useState('');
+  const [isLoading, setIsLoading] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+  const [isDone, setIsDone] = useState(false);
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setIsLoading(true);
+    setError(null);
+
+    if (password !== confirmPassword) {
+      setError('Passwords do not match');
+      setIsLoading(false);
+      return;
+    }
+
+    if (password.length < 6) {
+      setError('Password must be at least 6 characters');
+      setIsLoading(false);
+      return;
+    }
+
+    try {
+      await authAPI.resetPassword({ token, new_password: password });
+      setIsDone(true);
+    } catch (err: any) {
+      setError(err.message || 'Password reset failed. Ask your super admin for a new link.');
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  return (
+    <div className="min-h-screen flex items-center justify-center bg-gray-50 py-12 px-4">
+      <div className="max-w-md w-full">
+        {/* Logo */}
+        <div className="text-center mb-8">
+          <div className="flex justify-center gap-2 mb-4">
+            <Image src="/stone.png" alt="Stone" width={40} height={40} />
+            <Image src="/paper.png" alt="Paper" width={40} height={40} />
+            <Image src="/scissor.png" alt="Scissors" width={40} height={40} />
+          </div>
+          <h1 className="text-2xl font-bold text-gray-900">Reset Password</h1>
+          <p className="text-gray-600 mt-2">ThrowDown Tournament Manager</p>
+        </div>
+
+        <Card>
+          {isDone ? (
+            <div className="p-4 bg-green-50 border border-green-200 rounded-lg">
+              <p className="text-sm text-green-800">
+                Your password has been reset.{' '}
+                <Link href="/auth/login" className="font-semibold underline">
+                  Sign in
+                </Link>
+              </p>
+            </div>
+          ) : !token ? (
+            <div className="p-4 bg-red-50 border border-red-200 rounded-lg">
+              <p className="text-sm text-red-600">
+                This reset link is incomplete. Ask your super admin for a new one.
+              </p>
+            </div>
+          ) : (
+            <form onSubmit={handleSubmit} className="space-y-4">
+              {error && (
+                <div className="p-4 bg-red-50 border border-red-200 rounded-lg">
+                  <p className="text-sm text-red-600">{error}</p>
+                </div>
+              )}
+
+              <Input
+                label="New Password"
+                type="password"
+                value={password}
+                onChange={(e) => setPassword(e.target.value)}
+                placeholder="At least 6 characters"
+                required
+              />
+
+              <Input
+                label="Confirm Password"
+                type="password"
+                value={confirmPassword}
+                onChange={(e) => setConfirmPassword(e.target.value)}
+                placeholder="Repeat the new password"
+                required
+              />
+
+              <Button
+                type="submit"
+                className="w-full"
+                isLoading={isLoading}
+              >
+                Reset Password
+              </Button>
+            </form>
+          )}
+
+          <div className="mt-6 text-center">
+            <Link href="/" className="text-sm text-gray-600 hover:text-gray-900">
+              ← Back to Home
+            </Link>
+          </div>
+        </Card>
+      </div>
+    </div>
+  );
+}
+
+export default function ResetPasswordPage() {
+  return (
+    <Suspense fallback={<PageLoader />}>
+      <ResetPasswordContent />
+    </Suspense>
+  );
+}
@@ -110,6 +110,16 @@ export default function Navbar() {
                       Super Admin
                     </Link>
                   )}
+                  <Link
+                    href="/admin/security"
+                    className={`px-3 py-2 rounded-md text-sm font-medium transition-colors ${
+                      pathname === '/admin/security'
+                        ? 'bg-gray-900 text-white'
+                        : 'text-gray-600 hover:text-gray-900 hover:bg-gray-100'
+                    }`}
+                  >
+                    Security
+                  </Link>
                 </div>
 
                 {/* User info */}
@@ -212,6 +222,16 @@ export default function Navbar() {
                   Super Admin
                 </Link>
               )}
+              <Link
+                href="/admin/security"
+                className={`block px-3 py-2 rounded-md text-base font-medium ${
+                  pathname === '/admin/security'
+                    ? 'bg-gray-900 text-white'
+                    : 'text-gray-600 hover:text-gray-900 hover:bg-gray-100'
+                }`}
+              >
+                Security
+              </Link>
             </>
           )}
         </div>
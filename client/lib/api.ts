@@ -4,6 +4,23 @@ import {
   AuthResponse,
   LoginRequest,
   RegisterRequest,
+  ChangePasswordRequest,
+  ResetPasswordRequest,
+  PasswordResetResponse,
+  TwoFactorChallenge,
+  TwoFactorLoginRequest,
+  TwoFactorStatus,
+  TwoFactorSetupResponse,
+  RecoveryCodesResponse,
+  Settings,
+  AdminPermissions,
+  APIKeysResponse,
+  CreateAPIKeyRequest,
+  CreateAPIKeyResponse,
+  PermissionsResponse,
+  Role,
+  RoleRequest,
+  RolesResponse,
   CreateAdminRequest,
   Admin,
   AdminsResponse,
@@ -37,6 +54,38 @@ const getAuthHeaders = (): HeadersInit => {
   };
 };
 
+// Exchanges the stored refresh token for a new token pair; concurrent
+// callers share one request, as a refresh token can only be used once
+let refreshing: Promise<boolean> | null = null;
+
+const refreshSession = (): Promise<boolean> => {
+  if (!refreshing) {
+    refreshing = (async () => {
+      const refreshToken = localStorage.getItem('refresh_token');
+      if (!refreshToken) return false;
+
+      const response = await fetch(`${API_BASE_URL}/auth/refresh`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ refresh_token: refreshToken }),
+      });
+      if (!response.ok) {
+        localStorage.removeItem('token');
+        localStorage.removeItem('refresh_token');
+        return false;
+      }
+
+      const data = await response.json();
+      localStorage.setItem('token', data.token);
+      localStorage.setItem('refresh_token', data.refresh_token);
+      return true;
+    })().finally(() => {
+      refreshing = null;
+    });
+  }
+  return refreshing;
+};
+
 // Generic fetch wrapper with error handling
 async function fetchAPI<T>(
   endpoint: string,
@@ -44,17 +93,38 @@ async function fetchAPI<T>(
 ): Promise<T> {
   const url = `${API_BASE_URL}${endpoint}`;
   
-  const response = await fetch(url, {
-    ...options,
-    headers: {
-      ...getAuthHeaders(),
-      ...options.headers,
-    },
-  });
+  const request = () =>
+    fetch(url, {
+      ...options,
+      headers: {
+        ...getAuthHeaders(),
+        ...options.headers,
+      },
+    });
+
+  let response = await request();
+
+  // The access token has expired: refresh it and try once more
+  if (
+    response.status === 401 &&
+    typeof window !== 'undefined' &&
+    !['/auth/login', '/auth/login/2fa', '/auth/register', '/auth/logout', '/auth/password-reset'].includes(endpoint) &&
+    (await refreshSession())
+  ) {
+    response = await request();
+  }
 
   const data = await response.json();
 
   if (!response.ok) {
+    // Two-factor authentication is required but not set up yet
+    if (
+      data.two_factor_setup_required &&
+      typeof window !== 'undefined' &&
+      window.location.pathname !== '/admin/security'
+    ) {
+      window.location.href = '/admin/security';
+    }
     throw new Error(data.error || 'An error occurred');
   }
 
@@ -77,24 +147,144 @@ export const authAPI = {
     });
   },
 
-  // Login
-  login: async (data: LoginRequest): Promise<AuthResponse> => {
+  // Login; admins with two-factor authentication get a challenge instead of tokens
+  login: async (data: LoginRequest): Promise<AuthResponse | TwoFactorChallenge> => {
     return fetchAPI('/auth/login', {
       method: 'POST',
       body: JSON.stringify(data),
     });
   },
 
+  // Complete a two-factor login with a TOTP or recovery code
+  loginTwoFactor: async (data: TwoFactorLoginRequest): Promise<AuthResponse> => {
+    return fetchAPI('/auth/login/2fa', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  },
+
+  // Logout, ending the session of the refresh token
+  logout: async (refreshToken: string): Promise<{ message: string }> => {
+    return fetchAPI('/auth/logout', {
+      method: 'POST',
+      body: JSON.stringify({ refresh_token: refreshToken }),
+    });
+  },
+
+  // Change own password; other sessions end and a new token pair is returned
+  changePassword: async (
+    data: ChangePasswordRequest
+  ): Promise<Omit<AuthResponse, 'admin'>> => {
+    return fetchAPI('/auth/password', {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    });
+  },
+
+  // Set a new password with a reset token
+  resetPassword: async (data: ResetPasswordRequest): Promise<{ message: string }> => {
+    return fetchAPI('/auth/password-reset', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  },
+
   // Get current user
   getMe: async (): Promise<{ admin: Admin }> => {
     return fetchAPI('/auth/me');
   },
+
+  // Get the effective permissions of the current user
+  getPermissions: async (): Promise<AdminPermissions> => {
+    return fetchAPI('/auth/permissions');
+  },
+};
+
+// ============ TWO-FACTOR API ============
+
+export const twoFactorAPI = {
+  // Get the two-factor status of the current admin
+  getStatus: async (): Promise<TwoFactorStatus> => {
+    return fetchAPI('/auth/2fa');
+  },
+
+  // Start setup, returning the secret to add to an authenticator app
+  setup: async (): Promise<TwoFactorSetupResponse> => {
+    return fetchAPI('/auth/2fa/setup', {
+      method: 'POST',
+    });
+  },
+
+  // Confirm setup with a code, returning the recovery codes
+  enable: async (code: string): Promise<RecoveryCodesResponse> => {
+    return fetchAPI('/auth/2fa/enable', {
+      method: 'POST',
+      body: JSON.stringify({ code }),
+    });
+  },
+
+  // Turn two-factor authentication off
+  disable: async (password: string, code: string): Promise<{ message: string }> => {
+    return fetchAPI('/auth/2fa/disable', {
+      method: 'POST',
+      body: JSON.stringify({ password, code }),
+    });
+  },
+
+  // Replace the recovery codes
+  regenerateRecoveryCodes: async (code: string): Promise<RecoveryCodesResponse> => {
+    return fetchAPI('/auth/2fa/recovery-codes', {
+      method: 'POST',
+      body: JSON.stringify({ code }),
+    });
+  },
+};
+
+// ============ API KEY API ============
+
+export const apiKeyAPI = {
+  // List the API keys of the current user
+  getAPIKeys: async (): Promise<APIKeysResponse> => {
+    return fetchAPI('/auth/api-keys');
+  },
+
+  // Create an API key, the key is only returned once
+  createAPIKey: async (data: CreateAPIKeyRequest): Promise<CreateAPIKeyResponse> => {
+    return fetchAPI('/auth/api-keys', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  },
+
+  // Revoke one of the current user's API keys
+  revokeAPIKey: async (id: number): Promise<{ message: string }> => {
+    return fetchAPI(`/auth/api-keys/${id}`, {
+      method: 'DELETE',
+    });
+  },
+};
+
+// ============ SETTINGS API ============
+
+export const settingsAPI = {
+  // Get system settings (requires settings:manage)
+  getSettings: async (): Promise<Settings> => {
+    return fetchAPI('/settings');
+  },
+
+  // Update system settings (requires settings:manage)
+  updateSettings: async (data: Partial<Settings>): Promise<Settings> => {
+    return fetchAPI('/settings', {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    });
+  },
 };
 
 // ============ ADMIN MANAGEMENT API ============
 
 export const adminAPI = {
-  // Create a new admin (super admin only)
+  // Create a new admin (requires admins:manage)
   createAdmin: async (data: CreateAdminRequest): Promise<{ message: string; admin: Admin }> => {
     return fetchAPI('/admins', {
       method: 'POST',
@@ -102,17 +292,88 @@ export const adminAPI = {
     });
   },
 
-  // Get all admins (super admin only)
+  // Get all admins (requires admins:manage)
   getAllAdmins: async (): Promise<AdminsResponse> => {
     return fetchAPI('/admins');
   },
 
-  // Delete an admin (super admin only)
+  // Issue a password reset link for an admin (requires admins:manage)
+  resetAdminPassword: async (id: number): Promise<PasswordResetResponse> => {
+    return fetchAPI(`/admins/${id}/password-reset`, {
+      method: 'POST',
+    });
+  },
+
+  // Reset the two-factor authentication of an admin (requires admins:manage)
+  resetAdminTwoFactor: async (id: number): Promise<{ message: string }> => {
+    return fetchAPI(`/admins/${id}/2fa`, {
+      method: 'DELETE',
+    });
+  },
+
+  // Delete an admin (requires admins:manage)
   deleteAdmin: async (id: number): Promise<{ message: string }> => {
     return fetchAPI(`/admins/${id}`, {
       method: 'DELETE',
     });
   },
+
+  // Revoke every API key of an admin (requires admins:manage)
+  revokeAdminAPIKeys: async (id: number): Promise<{ message: string; api_keys_revoked: number }> => {
+    return fetchAPI(`/admins/${id}/api-keys`, {
+      method: 'DELETE',
+    });
+  },
+
+  // Get the effective permissions of an admin (requires admins:manage)
+  getAdminPermissions: async (id: number): Promise<AdminPermissions> => {
+    return fetchAPI(`/admins/${id}/permissions`);
+  },
+
+  // Assign a custom role to an admin, null for the default permissions (requires admins:manage)
+  assignRole: async (id: number, roleId: number | null): Promise<AdminPermissions> => {
+    return fetchAPI(`/admins/${id}/role`, {
+      method: 'PUT',
+      body: JSON.stringify({ role_id: roleId }),
+    });
+  },
+};
+
+// ============ ROLE API ============
+
+export const roleAPI = {
+  // List every permission and the defaults of admins without a role
+  getPermissions: async (): Promise<PermissionsResponse> => {
+    return fetchAPI('/permissions');
+  },
+
+  // List custom roles (requires admins:manage)
+  getRoles: async (): Promise<RolesResponse> => {
+    return fetchAPI('/roles');
+  },
+
+  // Create a custom role (requires admins:manage)
+  createRole: async (data: RoleRequest): Promise<Role> => {
+    return fetchAPI('/roles', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  },
+
+  // Update a custom role (requires admins:manage)
+  updateRole: async (id: number, data: RoleRequest): Promise<Role> => {
+    return fetchAPI(`/roles/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    });
+  },
+
+  // Delete a custom role no admin holds (requires admins:manage)
+  deleteRole: async (id: number): Promise<{ message: string }> => {
+    return fetchAPI(`/roles/${id}`, {
+      method: 'DELETE',
+    });
+  },
 };
 
 // ============ PLAYER API ============
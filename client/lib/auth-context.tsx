@@ -10,7 +10,7 @@ interface AuthContextType {
   isAuthenticated: boolean;
   isSuperAdmin: boolean;
   isAdmin: boolean;
-  login: (token: string, admin: Admin) => void;
+  login: (token: string, refreshToken: string, admin: Admin) => void;
   logout: () => void;
   checkAuth: () => Promise<void>;
 }
@@ -35,6 +35,7 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
     } catch (error) {
       // Token is invalid, clear it
       localStorage.removeItem('token');
+      localStorage.removeItem('refresh_token');
       setAdmin(null);
     } finally {
       setIsLoading(false);
@@ -45,13 +46,20 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
     checkAuth();
   }, [checkAuth]);
 
-  const login = (token: string, adminData: Admin) => {
+  const login = (token: string, refreshToken: string, adminData: Admin) => {
     localStorage.setItem('token', token);
+    localStorage.setItem('refresh_token', refreshToken);
     setAdmin(adminData);
   };
 
   const logout = () => {
+    // End the session on the server too, so the tokens cannot be reused
+    const refreshToken = localStorage.getItem('refresh_token');
+    if (refreshToken) {
+      authAPI.logout(refreshToken).catch(() => {});
+    }
     localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
     setAdmin(null);
   };
 
@@ -9,15 +9,155 @@ export interface Admin {
   username: string;
   email: string;
   role: AdminRole;
+  role_id?: number;
+  two_factor_enabled: boolean;
   created_at: string;
 }
 
+// Permissions and custom roles
+export type Permission =
+  | 'players:write'
+  | 'matches:submit'
+  | 'matches:import'
+  | 'matches:view:any'
+  | 'matches:edit:any'
+  | 'matches:delete:any'
+  | 'tournaments:manage'
+  | 'seasons:manage'
+  | 'ratings:manage'
+  | 'admins:manage'
+  | 'settings:manage'
+  | 'audit:view'
+  | 'data:export';
+
+export interface PermissionInfo {
+  name: Permission;
+  description: string;
+}
+
+export interface PermissionsResponse {
+  permissions: PermissionInfo[];
+  default_permissions: Permission[];
+}
+
+export interface Role {
+  id: number;
+  name: string;
+  description: string;
+  permissions: Permission[];
+  created_at: string;
+  updated_at: string;
+}
+
+export interface RoleRequest {
+  name: string;
+  description?: string;
+  permissions: Permission[];
+}
+
+export interface RolesResponse {
+  roles: Role[];
+  total: number;
+}
+
+export interface AdminPermissions {
+  admin_id: number;
+  role: AdminRole;
+  custom_role: Role | null;
+  permissions: Permission[];
+}
+
+// API keys for scoreboards and bots
+export interface APIKey {
+  id: number;
+  admin_id: number;
+  name: string;
+  prefix: string;
+  scopes: Permission[];
+  expires_at?: string;
+  last_used_at?: string;
+  last_used_ip?: string;
+  revoked_at?: string;
+  created_at: string;
+}
+
+export interface CreateAPIKeyRequest {
+  name: string;
+  scopes: Permission[];
+  expires_at?: string;
+}
+
+export interface CreateAPIKeyResponse {
+  message: string;
+  key: string;
+  api_key: APIKey;
+}
+
+export interface APIKeysResponse {
+  api_keys: APIKey[];
+  total: number;
+}
+
 export interface AuthResponse {
   token: string;
+  refresh_token: string;
+  expires_in: number;
   admin: Admin;
   message?: string;
 }
 
+// Returned by login instead of tokens when the admin uses two-factor authentication
+export interface TwoFactorChallenge {
+  message: string;
+  two_factor_required: true;
+  challenge_token: string;
+  expires_in: number;
+}
+
+export interface TwoFactorLoginRequest {
+  challenge_token: string;
+  code: string;
+}
+
+export interface TwoFactorStatus {
+  enabled: boolean;
+  required: boolean;
+  recovery_codes_remaining: number;
+}
+
+export interface TwoFactorSetupResponse {
+  message: string;
+  secret: string;
+  otpauth_uri: string;
+}
+
+export interface RecoveryCodesResponse {
+  message: string;
+  recovery_codes: string[];
+}
+
+export interface Settings {
+  require_2fa: boolean;
+}
+
+export interface ChangePasswordRequest {
+  current_password: string;
+  new_password: string;
+}
+
+export interface ResetPasswordRequest {
+  token: string;
+  new_password: string;
+}
+
+export interface PasswordResetResponse {
+  message: string;
+  emailed: boolean;
+  expires_at: string;
+  reset_token?: string;
+  reset_url?: string;
+}
+
 export interface LoginRequest {
   username: string;
   password: string;
@@ -33,6 +173,7 @@ export interface CreateAdminRequest {
   username: string;
   email: string;
   password: string;
+  role_id?: number;
 }
 
 // Player types
@@ -73,6 +214,7 @@ export interface Match {
   player1_elo_after: number;
   player2_elo_after: number;
   created_by_admin_id?: number;
+  created_by_api_key_id?: number;
   created_at: string;
 }
 
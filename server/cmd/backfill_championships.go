@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"stone-paper-scissors/config"
+	"stone-paper-scissors/migrations"
 	"stone-paper-scissors/models"
 	"time"
 )
@@ -14,7 +15,7 @@ func BackfillChampionshipHistory() error {
 
 	// Get all matches ordered by time
 	var matches []models.Match
-	if err := db.Order("created_at ASC").Find(&matches).Error; err != nil {
+	if err := db.Order("played_at ASC, id ASC").Find(&matches).Error; err != nil {
 		return fmt.Errorf("failed to fetch matches: %v", err)
 	}
 
@@ -64,11 +65,11 @@ func BackfillChampionshipHistory() error {
 		if i == 0 {
 			// First match - initialize champion
 			currentChampionID = topPlayerID
-			championshipStart = match.CreatedAt
+			championshipStart = match.PlayedAt
 			log.Printf("Initial champion: Player ID %d at %s\n", currentChampionID, championshipStart.Format("2006-01-02"))
 		} else if topPlayerID != currentChampionID {
 			// Champion changed!
-			endTime := match.CreatedAt
+			endTime := match.PlayedAt
 
 			// Save previous champion's reign
 			reign := models.ChampionshipReign{
@@ -84,7 +85,7 @@ func BackfillChampionshipHistory() error {
 
 			// Start new reign
 			currentChampionID = topPlayerID
-			championshipStart = match.CreatedAt
+			championshipStart = match.PlayedAt
 		}
 	}
 
@@ -99,8 +100,8 @@ func BackfillChampionshipHistory() error {
 		currentChampionID, championshipStart.Format("2006-01-02"),
 		int(time.Since(championshipStart).Hours()/24))
 
-	// Clear existing championship data
-	if err := db.Exec("DELETE FROM championship_reigns").Error; err != nil {
+	// Clear existing all-time championship data (season reigns are kept)
+	if err := db.Exec("DELETE FROM championship_reigns WHERE season_id IS NULL").Error; err != nil {
 		return fmt.Errorf("failed to clear existing championship data: %v", err)
 	}
 
@@ -144,9 +145,9 @@ func main() {
 	// Load environment and connect to database
 	config.ConnectDatabase()
 
-	// Run auto-migration to ensure table exists
-	if err := config.DB.AutoMigrate(&models.ChampionshipReign{}); err != nil {
-		log.Fatalf("Failed to migrate: %v", err)
+	// Refuse to run against a database that is not at this build's schema version
+	if err := migrations.Check(config.DB); err != nil {
+		log.Fatalf("%v (run `go run ./cmd/migrate up`)", err)
 	}
 
 	// Backfill championship history
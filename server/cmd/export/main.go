@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/services"
+)
+
+func main() {
+	out := flag.String("out", "", "archive to write (default: sps-export-<timestamp>.zip)")
+	flag.Parse()
+
+	if *out == "" {
+		*out = fmt.Sprintf("sps-export-%s.zip", time.Now().UTC().Format("20060102-150405"))
+	}
+
+	// Load environment and connect to database
+	config.ConnectDatabase()
+
+	file, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", *out, err)
+	}
+
+	manifest, err := services.WriteExport(config.DB, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(*out)
+		log.Fatalf("Export failed: %v", err)
+	}
+
+	for _, entity := range manifest.Entities {
+		log.Printf("%-24s %d rows\n", entity.Name, entity.Count)
+	}
+	log.Printf("\n🎉 Exported to %s (format version %d)\n", *out, manifest.Version)
+}
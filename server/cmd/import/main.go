@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/migrations"
+	"stone-paper-scissors/models"
+	"stone-paper-scissors/services"
+)
+
+// printReport logs the outcome of every row of the import
+func printReport(report *models.ImportReport) {
+	log.Printf("%d rows: %d valid, %d invalid\n", report.Total, report.Valid, report.Invalid)
+
+	for _, row := range report.Rows {
+		if row.Status == models.ImportInvalid {
+			log.Printf("Row %d: %s\n", row.Row, row.Error)
+		}
+	}
+	if report.Invalid > 0 {
+		return
+	}
+
+	if len(report.PlayersCreated) > 0 {
+		log.Printf("New players: %s\n", strings.Join(report.PlayersCreated, ", "))
+	}
+	if !report.DryRun {
+		log.Printf("Imported %d matches, re-rated %d matches and %d players\n",
+			report.Imported, report.MatchesUpdated, report.PlayersUpdated)
+	}
+}
+
+func main() {
+	file := flag.String("file", "", "CSV or JSON file with the matches to import")
+	format := flag.String("format", "", "csv or json (default: from the file extension)")
+	dryRun := flag.Bool("dry-run", false, "only validate the file, do not write anything")
+	flag.Parse()
+
+	if *file == "" {
+		log.Fatal("Usage: import -file matches.csv [-format csv|json] [-dry-run]")
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(*file), ".")
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *file, err)
+	}
+	defer f.Close()
+
+	rows, err := services.ParseImport(*format, f)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *file, err)
+	}
+
+	// Load environment and connect to database
+	config.ConnectDatabase()
+
+	// Refuse to run against a database that is not at this build's schema version
+	if err := migrations.Check(config.DB); err != nil {
+		log.Fatalf("%v (run `go run ./cmd/migrate up`)", err)
+	}
+
+	report, err := services.ImportMatches(rows, nil, nil, *dryRun)
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		log.Fatal(validationErr.Message)
+	}
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+
+	printReport(report)
+
+	if report.Invalid > 0 {
+		log.Fatal("\nSome rows are invalid, nothing was imported")
+	}
+	if *dryRun {
+		log.Println("\nDry run complete, no changes were written")
+		return
+	}
+	log.Println("\n🎉 Matches imported!")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"strconv"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/migrations"
+)
+
+const usage = "Usage: migrate up | down | status | to <version>"
+
+// printRan logs the migrations that were applied or rolled back
+func printRan(verb string, ran []migrations.Migration) {
+	if len(ran) == 0 {
+		log.Println("Nothing to do")
+		return
+	}
+	for _, migration := range ran {
+		log.Printf("%s %04d %s\n", verb, migration.Version, migration.Name)
+	}
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatal(usage)
+	}
+
+	// Load environment and connect to database
+	config.ConnectDatabase()
+	db := config.DB
+
+	switch flag.Arg(0) {
+	case "up":
+		ran, err := migrations.Up(db)
+		printRan("Applied", ran)
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+
+	case "down":
+		migration, err := migrations.Down(db)
+		if err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+		if migration == nil {
+			log.Println("Nothing to roll back")
+			return
+		}
+		log.Printf("Rolled back %04d %s\n", migration.Version, migration.Name)
+
+	case "to":
+		if flag.NArg() != 2 {
+			log.Fatal(usage)
+		}
+		version, err := strconv.Atoi(flag.Arg(1))
+		if err != nil {
+			log.Fatalf("Invalid version %q", flag.Arg(1))
+		}
+		current, err := migrations.Current(db)
+		if err != nil {
+			log.Fatalf("Failed to read the schema version: %v", err)
+		}
+		verb := "Applied"
+		if version < current {
+			verb = "Rolled back"
+		}
+		ran, err := migrations.To(db, version)
+		printRan(verb, ran)
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+
+	case "status":
+		statuses, err := migrations.Statuses(db)
+		if err != nil {
+			log.Fatalf("Failed to read migrations: %v", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			log.Printf("%04d %-30s %s\n", status.Version, status.Name, state)
+		}
+		if err := migrations.Check(db); err != nil {
+			log.Println(err)
+			return
+		}
+		log.Printf("Schema is up to date (version %d)\n", migrations.Latest())
+
+	default:
+		log.Fatal(usage)
+	}
+}
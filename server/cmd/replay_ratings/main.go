@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/migrations"
+	"stone-paper-scissors/services"
+)
+
+// printReport logs the players and matches whose stored values differ from the replay
+func printReport(report *services.ReplayReport) {
+	log.Printf("Replayed %d matches with the %s rating system\n", report.MatchesReplayed, report.RatingSystem)
+
+	if len(report.PlayersChanged) == 0 && len(report.MatchesChanged) == 0 {
+		log.Println("Stored ratings already match the match log, nothing to do")
+		return
+	}
+
+	log.Printf("\n=== Players (%d changed) ===\n", len(report.PlayersChanged))
+	for _, p := range report.PlayersChanged {
+		log.Printf("%-20s ELO %8.2f -> %8.2f | W %d -> %d | L %d -> %d | D %d -> %d | Total %d -> %d\n",
+			p.PlayerName, p.EloBefore, p.EloAfter,
+			p.MatchesWonBefore, p.MatchesWonAfter,
+			p.MatchesLostBefore, p.MatchesLostAfter,
+			p.MatchesDrawnBefore, p.MatchesDrawnAfter,
+			p.TotalMatchesBefore, p.TotalMatchesAfter)
+	}
+
+	log.Printf("\n=== Matches (%d changed) ===\n", len(report.MatchesChanged))
+	for _, m := range report.MatchesChanged {
+		log.Printf("Match #%d: P1 %.2f/%.2f -> %.2f/%.2f | P2 %.2f/%.2f -> %.2f/%.2f\n",
+			m.MatchID,
+			m.Player1EloBefore, m.Player1EloAfter, m.Player1EloBeforeReplay, m.Player1EloAfterReplay,
+			m.Player2EloBefore, m.Player2EloAfter, m.Player2EloBeforeReplay, m.Player2EloAfterReplay)
+	}
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only report the differences, do not write anything")
+	flag.Parse()
+
+	// Load environment and connect to database
+	config.ConnectDatabase()
+
+	// Refuse to run against a database that is not at this build's schema version
+	if err := migrations.Check(config.DB); err != nil {
+		log.Fatalf("%v (run `go run ./cmd/migrate up`)", err)
+	}
+
+	report, err := services.RecomputeRatings(*dryRun)
+	if err != nil {
+		log.Fatalf("Replay failed: %v", err)
+	}
+
+	printReport(report)
+
+	if *dryRun {
+		log.Println("\nDry run complete, no changes were written")
+		return
+	}
+	log.Println("\n🎉 Ratings recomputed from the match log!")
+}
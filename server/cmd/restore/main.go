@@ -0,0 +1,68 @@
+package main
+
+import (
+	"archive/zip"
+	"flag"
+	"log"
+	"os"
+
+	"stone-paper-scissors/migrations"
+	"stone-paper-scissors/services"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func main() {
+	archivePath := flag.String("archive", "", "export archive to restore")
+	dbPath := flag.String("db", "", "SQLite database file to create")
+	adminPassword := flag.String("admin-password", "", "password for every restored admin (archives carry no password hashes)")
+	flag.Parse()
+
+	if *archivePath == "" || *dbPath == "" {
+		log.Fatal("Usage: restore -archive sps-export.zip -db restored.db [-admin-password secret]")
+	}
+	if _, err := os.Stat(*dbPath); err == nil {
+		log.Fatalf("%s already exists, restore only creates a fresh database", *dbPath)
+	}
+
+	archive, err := zip.OpenReader(*archivePath)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *archivePath, err)
+	}
+	defer archive.Close()
+
+	// Without a password the restored admins cannot log in until one is set
+	passwordHash := "!"
+	if *adminPassword != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(*adminPassword), bcrypt.DefaultCost)
+		if err != nil {
+			log.Fatalf("Failed to hash password: %v", err)
+		}
+		passwordHash = string(hash)
+	}
+
+	db, err := gorm.Open(sqlite.Open(*dbPath), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", *dbPath, err)
+	}
+	if _, err := migrations.Up(db); err != nil {
+		os.Remove(*dbPath)
+		log.Fatalf("Failed to migrate: %v", err)
+	}
+
+	manifest, err := services.RestoreExport(db, &archive.Reader, passwordHash)
+	if err != nil {
+		os.Remove(*dbPath)
+		log.Fatalf("Restore failed: %v", err)
+	}
+
+	for _, entity := range manifest.Entities {
+		log.Printf("%-24s %d rows\n", entity.Name, entity.Count)
+	}
+	if *adminPassword == "" {
+		log.Println("\nNo -admin-password given, restored admins cannot log in until a password is set")
+	}
+	log.Printf("\n🎉 Restored the %s export of %s into %s\n", manifest.Driver, manifest.CreatedAt.Format("2006-01-02 15:04:05"), *dbPath)
+}
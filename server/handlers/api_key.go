@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+	"stone-paper-scissors/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// requestPermissions returns the permissions of the current request: those
+// of the admin, narrowed to the scopes of the API key it was made with
+func requestPermissions(c *fiber.Ctx) ([]models.Permission, error) {
+	admin := c.Locals("admin").(*models.Admin)
+	if key, ok := c.Locals("api_key").(*models.APIKey); ok {
+		return services.APIKeyPermissions(admin, key)
+	}
+	return services.EffectivePermissions(admin)
+}
+
+// hasPermissions reports whether the current request holds every one of permissions
+func hasPermissions(c *fiber.Ctx, permissions ...models.Permission) (bool, error) {
+	held, err := requestPermissions(c)
+	if err != nil {
+		return false, err
+	}
+	return services.Holds(held, permissions...), nil
+}
+
+// apiKeyID returns the ID of the API key the request was made with, if any
+func apiKeyID(c *fiber.Ctx) *uint {
+	if key, ok := c.Locals("api_key").(*models.APIKey); ok {
+		return &key.ID
+	}
+	return nil
+}
+
+// GetAPIKeys lists the API keys of the current admin
+func GetAPIKeys(c *fiber.Ctx) error {
+	admin := c.Locals("admin").(*models.Admin)
+
+	keys, err := services.ListAPIKeys(admin.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch API keys",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"api_keys": keys,
+		"total":    len(keys),
+	})
+}
+
+// CreateAPIKey issues an API key for the current admin, scoped to
+// permissions they hold. The key is only shown in this response.
+func CreateAPIKey(c *fiber.Ctx) error {
+	admin := c.Locals("admin").(*models.Admin)
+
+	var req models.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if ok, err := checkGrantable(c, req.Scopes); !ok {
+		return err
+	}
+
+	key, record, err := services.CreateAPIKey(admin.ID, req)
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": validationErr.Message,
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create API key",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "API key created, store it now as it will not be shown again",
+		"key":     key,
+		"api_key": record,
+	})
+}
+
+// RevokeAPIKey revokes one of the current admin's API keys
+func RevokeAPIKey(c *fiber.Ctx) error {
+	admin := c.Locals("admin").(*models.Admin)
+
+	id, ok := parseID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid API key ID",
+		})
+	}
+
+	key, err := services.RevokeAPIKey(admin.ID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "API key not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke API key",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "API key revoked successfully",
+		"api_key": key,
+	})
+}
+
+// RevokeAdminAPIKeys revokes every API key of an admin (requires admins:manage)
+func RevokeAdminAPIKeys(c *fiber.Ctx) error {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid admin ID",
+		})
+	}
+
+	var admin models.Admin
+	if result := config.DB.First(&admin, id); result.Error != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Admin not found",
+		})
+	}
+	if ok, err := checkManageable(c, &admin); !ok {
+		return err
+	}
+
+	revoked, err := services.RevokeAdminAPIKeys(config.DB, admin.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke API keys",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":          "API keys revoked successfully",
+		"api_keys_revoked": revoked,
+	})
+}
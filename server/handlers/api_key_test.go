@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+	"stone-paper-scissors/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// apiKeyTestApp serves routes needing a permission, or a session, to
+// requests authenticated by AuthMiddleware
+func apiKeyTestApp() *fiber.App {
+	app := fiber.New()
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Get("/matches", AuthMiddleware, RequirePermission(models.PermMatchesSubmit), ok)
+	app.Get("/players", AuthMiddleware, RequirePermission(models.PermPlayersWrite), ok)
+	app.Get("/admins", AuthMiddleware, RequirePermission(models.PermAdminsManage), ok)
+	app.Get("/account", AuthMiddleware, SessionOnly, ok)
+	return app
+}
+
+func apiKeyRequest(t *testing.T, app *fiber.App, path, key string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("X-API-Key", key)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestCreateAPIKeyRefusesAccountScopes(t *testing.T) {
+	setupTestDB(t)
+
+	admin := models.Admin{Username: "root", Email: "root@example.com", PasswordHash: "x", Role: models.RoleSuperAdmin}
+	if err := config.DB.Create(&admin).Error; err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Post("/api-keys", func(c *fiber.Ctx) error {
+		c.Locals("admin", &admin)
+		return c.Next()
+	}, CreateAPIKey)
+
+	for body, want := range map[string]int{
+		`{"name":"bot","scopes":["admins:manage"]}`:                   fiber.StatusBadRequest,
+		`{"name":"bot","scopes":["matches:submit","settings:manage"]}`: fiber.StatusBadRequest,
+		`{"name":"bot","scopes":["matches:submit"]}`:                   fiber.StatusCreated,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api-keys", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != want {
+			t.Errorf("%s: got status %d, want %d", body, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	setupTestDB(t)
+
+	admin := createTestAdmin(t, "bot-owner", models.PermMatchesSubmit, models.PermPlayersWrite, models.PermAdminsManage)
+	// A key from before account scopes were refused
+	key := services.APIKeyPrefix + "legacy"
+	record := models.APIKey{
+		AdminID: admin.ID,
+		Name:    "scoreboard",
+		Prefix:  key[:len(services.APIKeyPrefix)+4],
+		KeyHash: services.HashToken(key),
+		Scopes:  models.PermissionList{models.PermMatchesSubmit, models.PermAdminsManage},
+	}
+	if err := config.DB.Create(&record).Error; err != nil {
+		t.Fatal(err)
+	}
+	app := apiKeyTestApp()
+
+	for path, want := range map[string]int{
+		"/matches": fiber.StatusOK,        // in scope
+		"/players": fiber.StatusForbidden, // held by the admin, not in scope
+		"/admins":  fiber.StatusForbidden, // only held when logged in
+		"/account": fiber.StatusForbidden, // session only
+	} {
+		if status := apiKeyRequest(t, app, path, key); status != want {
+			t.Errorf("%s: got status %d, want %d", path, status, want)
+		}
+	}
+	if status := apiKeyRequest(t, app, "/matches", key+"x"); status != fiber.StatusUnauthorized {
+		t.Errorf("unknown key: got status %d, want 401", status)
+	}
+
+	// Narrowing the admin's role narrows the key
+	if err := config.DB.Model(&models.Role{}).Where("id = ?", *admin.RoleID).
+		Update("permissions", models.PermissionList{models.PermPlayersWrite}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if status := apiKeyRequest(t, app, "/matches", key); status != fiber.StatusForbidden {
+		t.Errorf("scope the admin lost: got status %d, want 403", status)
+	}
+
+	if _, err := services.RevokeAPIKey(admin.ID, record.ID); err != nil {
+		t.Fatal(err)
+	}
+	if status := apiKeyRequest(t, app, "/players", key); status != fiber.StatusUnauthorized {
+		t.Errorf("revoked key: got status %d, want 401", status)
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+
+	"stone-paper-scissors/models"
+	"stone-paper-scissors/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// GetLockouts lists the usernames and IP addresses that are currently refused
+// logins after failed attempts (requires admins:manage)
+func GetLockouts(c *fiber.Ctx) error {
+	lockouts, err := services.ListLockouts()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch lockouts",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"lockouts": lockouts,
+		"total":    len(lockouts),
+	})
+}
+
+// UnlockLogin clears a lockout so that logins are allowed again (requires admins:manage)
+func UnlockLogin(c *fiber.Ctx) error {
+	currentAdmin := c.Locals("admin").(*models.Admin)
+
+	id, ok := parseID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid lockout ID",
+		})
+	}
+
+	lockout, err := services.UnlockLogin(id, currentAdmin.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Lockout not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to unlock login",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Login unlocked successfully",
+		"lockout": lockout,
+	})
+}
+
+// GetAuditEvents lists audit events newest first, optionally filtered by
+// type (requires audit:view)
+func GetAuditEvents(c *fiber.Ctx) error {
+	eventType := c.Query("type")
+	limit := c.QueryInt("limit", 50)
+	offset := c.QueryInt("offset", 0)
+
+	events, total, err := services.ListAuditEvents(eventType, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch audit events",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"events": events,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
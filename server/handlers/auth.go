@@ -1,16 +1,21 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"stone-paper-scissors/config"
 	"stone-paper-scissors/models"
+	"stone-paper-scissors/services"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 var jwtSecret = []byte(getJWTSecret())
@@ -35,14 +40,15 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-// GenerateToken generates a JWT token for an admin
-func GenerateToken(admin *models.Admin) (string, error) {
+// GenerateToken generates a short-lived JWT access token for an admin's session
+func GenerateToken(admin *models.Admin, familyID string) (string, error) {
 	claims := jwt.MapClaims{
 		"id":       admin.ID,
 		"username": admin.Username,
 		"email":    admin.Email,
 		"role":     admin.Role,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(),
+		"fam":      familyID,
+		"exp":      time.Now().Add(services.AccessTokenTTL()).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -66,6 +72,51 @@ func ParseToken(tokenString string) (*jwt.MapClaims, error) {
 	return nil, fiber.ErrUnauthorized
 }
 
+// challengeTokenTTL is how long the second step of a login can take
+const challengeTokenTTL = 5 * time.Minute
+
+// GenerateChallengeToken generates the JWT proving that an admin with
+// two-factor authentication has given their password. It only grants the
+// second step of the login, once, and only until the password changes.
+func GenerateChallengeToken(admin *models.Admin) (string, error) {
+	challenge, err := services.CreateLoginChallenge(admin.ID, challengeTokenTTL)
+	if err != nil {
+		return "", err
+	}
+	claims := jwt.MapClaims{
+		"id":      admin.ID,
+		"purpose": "2fa",
+		"jti":     challenge.TokenID,
+		"pwd":     passwordStamp(admin),
+		"exp":     challenge.ExpiresAt.Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
+}
+
+// passwordStamp identifies the current password of admin in challenge
+// tokens, so that changing the password voids them
+func passwordStamp(admin *models.Admin) string {
+	if admin.PasswordChangedAt == nil {
+		return ""
+	}
+	return admin.PasswordChangedAt.UTC().Format(time.RFC3339Nano)
+}
+
+// startSession opens a session for an admin and returns its access and refresh tokens
+func startSession(admin *models.Admin) (token, refreshToken string, err error) {
+	familyID, refreshToken, err := services.StartSession(admin.ID)
+	if err != nil {
+		return "", "", err
+	}
+	token, err = GenerateToken(admin, familyID)
+	if err != nil {
+		return "", "", err
+	}
+	return token, refreshToken, nil
+}
+
 // CheckSuperAdminExists checks if any super admin exists in the system
 func CheckSuperAdminExists(c *fiber.Ctx) error {
 	var count int64
@@ -134,8 +185,8 @@ func RegisterSuperAdmin(c *fiber.Ctx) error {
 		})
 	}
 
-	// Generate token
-	token, err := GenerateToken(&admin)
+	// Generate tokens
+	token, refreshToken, err := startSession(&admin)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate token",
@@ -143,18 +194,61 @@ func RegisterSuperAdmin(c *fiber.Ctx) error {
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "Super admin registered successfully",
-		"token":   token,
+		"message":       "Super admin registered successfully",
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(services.AccessTokenTTL().Seconds()),
 		"admin": models.AdminResponse{
-			ID:        admin.ID,
-			Username:  admin.Username,
-			Email:     admin.Email,
-			Role:      admin.Role,
-			CreatedAt: admin.CreatedAt,
+			ID:               admin.ID,
+			Username:         admin.Username,
+			Email:            admin.Email,
+			Role:             admin.Role,
+			RoleID:           admin.RoleID,
+			TwoFactorEnabled: admin.TOTPEnabled,
+			CreatedAt:        admin.CreatedAt,
 		},
 	})
 }
 
+// checkLoginThrottle answers the request and returns true when logins for the
+// username or the address are refused after too many failures
+func checkLoginThrottle(c *fiber.Ctx, username, ip string) (bool, error) {
+	err := services.CheckLoginAllowed(username, ip)
+	if err == nil {
+		return false, nil
+	}
+	var throttled *services.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		return true, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check login attempts",
+		})
+	}
+	services.RecordAudit(config.DB, models.AuditEvent{
+		Type:     models.AuditLoginFailed,
+		Username: username,
+		IP:       ip,
+		Detail:   "refused: " + throttled.Error(),
+	})
+	retryAfter := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+	return true, c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error":       throttled.Error(),
+		"retry_after": retryAfter,
+	})
+}
+
+// loginFailed counts a failed login and answers it
+func loginFailed(c *fiber.Ctx, username, ip string, adminID *uint, reason string) error {
+	if err := services.RecordLoginFailure(username, ip, adminID, reason); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record login attempt",
+		})
+	}
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"error": "Invalid credentials",
+	})
+}
+
 // Login handles admin login
 func Login(c *fiber.Ctx) error {
 	var req models.AdminLoginRequest
@@ -170,23 +264,54 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
+	// Refuse logins while the username or the address is throttled
+	ip := c.IP()
+	if refused, err := checkLoginThrottle(c, req.Username, ip); refused {
+		return err
+	}
+
 	// Find admin by username
 	var admin models.Admin
 	if result := config.DB.Where("username = ?", req.Username).First(&admin); result.Error != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid credentials",
-		})
+		return loginFailed(c, req.Username, ip, nil, "unknown username")
 	}
 
 	// Check password
 	if !CheckPasswordHash(req.Password, admin.PasswordHash) {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid credentials",
+		return loginFailed(c, req.Username, ip, &admin.ID, "wrong password")
+	}
+
+	// With two-factor authentication the password only earns a challenge,
+	// completed with a code at /auth/login/2fa
+	if admin.TOTPEnabled {
+		challenge, err := GenerateChallengeToken(&admin)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to generate token",
+			})
+		}
+		return c.JSON(fiber.Map{
+			"message":             "Two-factor code required",
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+			"expires_in":          int(challengeTokenTTL.Seconds()),
+		})
+	}
+
+	return completeLogin(c, &admin)
+}
+
+// completeLogin clears the failed logins of an admin who has proven who they
+// are and starts a session
+func completeLogin(c *fiber.Ctx, admin *models.Admin) error {
+	if err := services.RecordLoginSuccess(admin.Username); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record login attempt",
 		})
 	}
 
-	// Generate token
-	token, err := GenerateToken(&admin)
+	// Generate tokens
+	token, refreshToken, err := startSession(admin)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate token",
@@ -194,30 +319,227 @@ func Login(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(fiber.Map{
-		"message": "Login successful",
-		"token":   token,
+		"message":       "Login successful",
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(services.AccessTokenTTL().Seconds()),
 		"admin": models.AdminResponse{
-			ID:        admin.ID,
-			Username:  admin.Username,
-			Email:     admin.Email,
-			Role:      admin.Role,
-			CreatedAt: admin.CreatedAt,
+			ID:               admin.ID,
+			Username:         admin.Username,
+			Email:            admin.Email,
+			Role:             admin.Role,
+			RoleID:           admin.RoleID,
+			TwoFactorEnabled: admin.TOTPEnabled,
+			CreatedAt:        admin.CreatedAt,
 		},
 	})
 }
 
+// RefreshToken exchanges a refresh token for a new access token and refresh token
+func RefreshToken(c *fiber.Ctx) error {
+	var req models.RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Refresh token is required",
+		})
+	}
+
+	record, refreshToken, err := services.RotateRefreshToken(req.RefreshToken)
+	if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to refresh token",
+		})
+	}
+
+	// Sessions of deleted admins cannot be refreshed
+	var admin models.Admin
+	if result := config.DB.First(&admin, record.AdminID); result.Error != nil {
+		services.RevokeSession(config.DB, refreshToken)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Admin not found",
+		})
+	}
+
+	token, err := GenerateToken(&admin, record.FamilyID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate token",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":       "Token refreshed",
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(services.AccessTokenTTL().Seconds()),
+	})
+}
+
+// Logout ends the session a refresh token belongs to; its access tokens stop
+// working immediately
+func Logout(c *fiber.Ctx) error {
+	var req models.RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Refresh token is required",
+		})
+	}
+
+	if _, err := services.RevokeSession(config.DB, req.RefreshToken); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to log out",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Logged out successfully",
+	})
+}
+
+// ChangePassword changes the password of the current admin. Every session of
+// the admin ends, their API keys are revoked and a new session is started
+// for this client.
+func ChangePassword(c *fiber.Ctx) error {
+	admin := c.Locals("admin").(*models.Admin)
+
+	var req models.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Current password and new password are required",
+		})
+	}
+
+	if len(req.NewPassword) < 6 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Password must be at least 6 characters",
+		})
+	}
+
+	if !CheckPasswordHash(req.CurrentPassword, admin.PasswordHash) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Current password is incorrect",
+		})
+	}
+
+	hashedPassword, err := HashPassword(req.NewPassword)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to hash password",
+		})
+	}
+
+	if err := services.ChangePassword(admin.ID, hashedPassword); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to change password",
+		})
+	}
+
+	token, refreshToken, err := startSession(admin)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate token",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":       "Password changed successfully",
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(services.AccessTokenTTL().Seconds()),
+	})
+}
+
+// ResetPassword sets a new password with a one-time reset token. Every
+// session of the admin ends and their API keys are revoked; they log in
+// again with the new password.
+func ResetPassword(c *fiber.Ctx) error {
+	var req models.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Token == "" || req.NewPassword == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Token and new password are required",
+		})
+	}
+
+	if len(req.NewPassword) < 6 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Password must be at least 6 characters",
+		})
+	}
+
+	hashedPassword, err := HashPassword(req.NewPassword)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to hash password",
+		})
+	}
+
+	if err := services.ResetPassword(req.Token, hashedPassword); err != nil {
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to reset password",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Password reset successfully, please log in",
+	})
+}
+
 // GetMe returns the current authenticated admin
 func GetMe(c *fiber.Ctx) error {
 	admin := c.Locals("admin").(*models.Admin)
 
+	required, err := services.TwoFactorRequired()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch settings",
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"admin": models.AdminResponse{
-			ID:        admin.ID,
-			Username:  admin.Username,
-			Email:     admin.Email,
-			Role:      admin.Role,
-			CreatedAt: admin.CreatedAt,
+			ID:               admin.ID,
+			Username:         admin.Username,
+			Email:            admin.Email,
+			Role:             admin.Role,
+			RoleID:           admin.RoleID,
+			TwoFactorEnabled: admin.TOTPEnabled,
+			CreatedAt:        admin.CreatedAt,
 		},
+		"two_factor_required": required,
 	})
 }
 
@@ -231,16 +553,10 @@ func GetMe(c *fiber.Ctx) error {
 // 	})
 // }
 
-// CreateAdmin creates a new admin (only super admin can do this)
+// CreateAdmin creates a new admin, optionally with a custom role (requires admins:manage)
 func CreateAdmin(c *fiber.Ctx) error {
 	currentAdmin := c.Locals("admin").(*models.Admin)
 
-	if currentAdmin.Role != models.RoleSuperAdmin {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Only super admin can create admins",
-		})
-	}
-
 	var req models.CreateAdminRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -268,6 +584,21 @@ func CreateAdmin(c *fiber.Ctx) error {
 		})
 	}
 
+	// Admins can only hand out permissions they hold themselves
+	granted := models.DefaultAdminPermissions
+	if req.RoleID != nil {
+		var role models.Role
+		if result := config.DB.First(&role, *req.RoleID); result.Error != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Role not found",
+			})
+		}
+		granted = role.Permissions
+	}
+	if ok, err := checkGrantable(c, granted); !ok {
+		return err
+	}
+
 	// Hash password
 	hashedPassword, err := HashPassword(req.Password)
 	if err != nil {
@@ -282,6 +613,7 @@ func CreateAdmin(c *fiber.Ctx) error {
 		Email:        req.Email,
 		PasswordHash: hashedPassword,
 		Role:         models.RoleAdmin,
+		RoleID:       req.RoleID,
 		CreatedByID:  &currentAdmin.ID,
 	}
 
@@ -294,25 +626,19 @@ func CreateAdmin(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": "Admin created successfully",
 		"admin": models.AdminResponse{
-			ID:        admin.ID,
-			Username:  admin.Username,
-			Email:     admin.Email,
-			Role:      admin.Role,
-			CreatedAt: admin.CreatedAt,
+			ID:               admin.ID,
+			Username:         admin.Username,
+			Email:            admin.Email,
+			Role:             admin.Role,
+			RoleID:           admin.RoleID,
+			TwoFactorEnabled: admin.TOTPEnabled,
+			CreatedAt:        admin.CreatedAt,
 		},
 	})
 }
 
-// GetAllAdmins returns all admins (only super admin can see this)
+// GetAllAdmins returns all admins (requires admins:manage)
 func GetAllAdmins(c *fiber.Ctx) error {
-	currentAdmin := c.Locals("admin").(*models.Admin)
-
-	if currentAdmin.Role != models.RoleSuperAdmin {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Only super admin can view all admins",
-		})
-	}
-
 	var admins []models.Admin
 	if result := config.DB.Where("role = ?", models.RoleAdmin).Find(&admins); result.Error != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -323,11 +649,13 @@ func GetAllAdmins(c *fiber.Ctx) error {
 	var response []models.AdminResponse
 	for _, admin := range admins {
 		response = append(response, models.AdminResponse{
-			ID:        admin.ID,
-			Username:  admin.Username,
-			Email:     admin.Email,
-			Role:      admin.Role,
-			CreatedAt: admin.CreatedAt,
+			ID:               admin.ID,
+			Username:         admin.Username,
+			Email:            admin.Email,
+			Role:             admin.Role,
+			RoleID:           admin.RoleID,
+			TwoFactorEnabled: admin.TOTPEnabled,
+			CreatedAt:        admin.CreatedAt,
 		})
 	}
 
@@ -337,16 +665,8 @@ func GetAllAdmins(c *fiber.Ctx) error {
 	})
 }
 
-// DeleteAdmin deletes an admin and all their matches (only super admin)
+// DeleteAdmin deletes an admin and all their matches (requires admins:manage)
 func DeleteAdmin(c *fiber.Ctx) error {
-	currentAdmin := c.Locals("admin").(*models.Admin)
-
-	if currentAdmin.Role != models.RoleSuperAdmin {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Only super admin can delete admins",
-		})
-	}
-
 	id := c.Params("id")
 
 	var admin models.Admin
@@ -361,35 +681,148 @@ func DeleteAdmin(c *fiber.Ctx) error {
 			"error": "Cannot delete super admin",
 		})
 	}
+	if ok, err := checkManageable(c, &admin); !ok {
+		return err
+	}
+
+	// Delete the admin and their matches, then replay the match log so that
+	// the ratings and the championship no longer count those matches.
+	// Tournament results are kept, as their tournaments progressed on them.
+	var failure string
+	err := services.WriteTransaction(func(tx *gorm.DB) error {
+		failure = "Failed to delete admin's matches"
+		result := tx.Where("created_by_admin_id = ?", admin.ID).
+			Where("id NOT IN (?)", tx.Model(&models.TournamentMatch{}).Select("match_id").Where("match_id IS NOT NULL")).
+			Delete(&models.Match{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		failure = "Failed to delete admin"
+		if err := tx.Delete(&admin).Error; err != nil {
+			return err
+		}
+
+		// Log the admin out everywhere
+		failure = "Failed to revoke admin's sessions"
+		if _, err := services.RevokeAdminSessions(tx, admin.ID); err != nil {
+			return err
+		}
+		failure = "Failed to revoke admin's API keys"
+		if _, err := services.RevokeAdminAPIKeys(tx, admin.ID); err != nil {
+			return err
+		}
+
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		failure = "Failed to recompute ratings"
+		if _, err := services.ReplayRatings(tx, false); err != nil {
+			return err
+		}
+		return services.RefreshChampion(tx)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": failure,
+		})
+	}
 
-	// Start transaction
-	tx := config.DB.Begin()
+	return c.JSON(fiber.Map{
+		"message": "Admin and all their matches deleted successfully",
+	})
+}
 
-	// Delete all matches created by this admin
-	if err := tx.Where("created_by_admin_id = ?", admin.ID).Delete(&models.Match{}).Error; err != nil {
-		tx.Rollback()
+// RevokeAdminSessions logs an admin out of every session (requires admins:manage)
+func RevokeAdminSessions(c *fiber.Ctx) error {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid admin ID",
+		})
+	}
+
+	var admin models.Admin
+	if result := config.DB.First(&admin, id); result.Error != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Admin not found",
+		})
+	}
+	if ok, err := checkManageable(c, &admin); !ok {
+		return err
+	}
+
+	revoked, err := services.RevokeAdminSessions(config.DB, admin.ID)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to delete admin's matches",
+			"error": "Failed to revoke sessions",
 		})
 	}
 
-	// Delete the admin
-	if err := tx.Delete(&admin).Error; err != nil {
-		tx.Rollback()
+	return c.JSON(fiber.Map{
+		"message":          "Sessions revoked successfully",
+		"sessions_revoked": revoked,
+	})
+}
+
+// ResetAdminPassword issues a one-time password reset token for an admin
+// (requires admins:manage). With SMTP configured the reset link is mailed to the
+// admin; otherwise it is returned to hand over.
+func ResetAdminPassword(c *fiber.Ctx) error {
+	currentAdmin := c.Locals("admin").(*models.Admin)
+
+	id, ok := parseID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid admin ID",
+		})
+	}
+
+	var admin models.Admin
+	if result := config.DB.First(&admin, id); result.Error != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Admin not found",
+		})
+	}
+	if ok, err := checkManageable(c, &admin); !ok {
+		return err
+	}
+
+	token, record, err := services.CreatePasswordReset(admin.ID, &currentAdmin.ID)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to delete admin",
+			"error": "Failed to create password reset",
 		})
 	}
 
-	tx.Commit()
+	if services.MailConfigured() {
+		if err := services.SendPasswordResetEmail(&admin, token, record.ExpiresAt); err != nil {
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+				"error": "Failed to send password reset email",
+			})
+		}
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message":    "Password reset link sent to " + admin.Email,
+			"emailed":    true,
+			"expires_at": record.ExpiresAt,
+		})
+	}
 
-	return c.JSON(fiber.Map{
-		"message": "Admin and all their matches deleted successfully",
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":     "Password reset created, give the link to the admin",
+		"emailed":     false,
+		"reset_token": token,
+		"reset_url":   services.PasswordResetURL(token),
+		"expires_at":  record.ExpiresAt,
 	})
 }
 
-// AuthMiddleware validates JWT token and sets admin in context
+// AuthMiddleware validates JWT token or API key and sets admin in context
 func AuthMiddleware(c *fiber.Ctx) error {
+	if key := c.Get("X-API-Key"); key != "" {
+		return apiKeyAuth(c, key)
+	}
+
 	authHeader := c.Get("Authorization")
 	if authHeader == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -413,43 +846,144 @@ func AuthMiddleware(c *fiber.Ctx) error {
 		})
 	}
 
+	// Access tokens stop working as soon as their session is revoked
+	familyID, _ := (*claims)["fam"].(string)
+	if familyID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid or expired token",
+		})
+	}
+	active, err := services.SessionActive(familyID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check session",
+		})
+	}
+	if !active {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Session has been revoked",
+		})
+	}
+
 	// Get admin from database
 	adminID := uint((*claims)["id"].(float64))
 	var admin models.Admin
-	if result := config.DB.First(&admin, adminID); result.Error != nil {
+	if result := config.DB.Preload("CustomRole").First(&admin, adminID); result.Error != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Admin not found",
 		})
 	}
 
+	// When two-factor authentication is required, admins without it can only
+	// reach the auth routes, where they set it up
+	if !twoFactorSetupRoute(c.Path()) {
+		if ok, err := checkTwoFactorSetup(c, &admin); !ok {
+			return err
+		}
+	}
+
 	// Set admin in context
 	c.Locals("admin", &admin)
 
 	return c.Next()
 }
 
-// SuperAdminOnly middleware - only allows super admins
-func SuperAdminOnly(c *fiber.Ctx) error {
-	admin := c.Locals("admin").(*models.Admin)
+// twoFactorSetupRoute reports whether path stays open to admins who must
+// still set up two-factor authentication: the account routes under /auth
+// they need for it, but not API keys, which would bypass the second factor.
+// Routes match regardless of case, so neither does this.
+func twoFactorSetupRoute(path string) bool {
+	path = strings.ToLower(path)
+	return strings.HasPrefix(path, "/api/v1/auth/") && !strings.HasPrefix(path, "/api/v1/auth/api-keys")
+}
 
-	if admin.Role != models.RoleSuperAdmin {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Super admin access required",
+// checkTwoFactorSetup responds with 403 when two-factor authentication is
+// required and admin has not set it up. It reports whether the request may
+// go on.
+func checkTwoFactorSetup(c *fiber.Ctx, admin *models.Admin) (bool, error) {
+	if admin.TOTPEnabled {
+		return true, nil
+	}
+	required, err := services.TwoFactorRequired()
+	if err != nil {
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch settings",
 		})
 	}
+	if required {
+		return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":                     "Two-factor authentication must be enabled for your account",
+			"two_factor_setup_required": true,
+		})
+	}
+	return true, nil
+}
+
+// apiKeyAuth authenticates a request made with an API key in place of an
+// access token. Keys act for their admin; the account routes refuse them
+// with SessionOnly.
+func apiKeyAuth(c *fiber.Ctx, key string) error {
+	record, err := services.AuthenticateAPIKey(key, c.IP())
+	if errors.Is(err, services.ErrInvalidAPIKey) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid or expired API key",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check API key",
+		})
+	}
+
+	var admin models.Admin
+	if result := config.DB.Preload("CustomRole").First(&admin, record.AdminID); result.Error != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Admin not found",
+		})
+	}
+
+	// Keys of admins who must still set up two-factor authentication do not
+	// work, or they would bypass the second factor
+	if ok, err := checkTwoFactorSetup(c, &admin); !ok {
+		return err
+	}
+
+	c.Locals("admin", &admin)
+	c.Locals("api_key", record)
 
 	return c.Next()
 }
 
-// AdminOnly middleware - allows both admin and super admin
-func AdminOnly(c *fiber.Ctx) error {
-	admin := c.Locals("admin").(*models.Admin)
-
-	if admin.Role != models.RoleAdmin && admin.Role != models.RoleSuperAdmin {
+// SessionOnly middleware - refuses requests made with an API key, so that
+// keys cannot manage the account of their admin (e.g. create more keys). It
+// runs after AuthMiddleware.
+func SessionOnly(c *fiber.Ctx) error {
+	if _, ok := c.Locals("api_key").(*models.APIKey); ok {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Admin access required",
+			"error": "API keys cannot be used for account routes",
 		})
 	}
 
 	return c.Next()
 }
+
+// RequirePermission middleware - only allows requests holding every one of
+// permissions. It runs after AuthMiddleware.
+func RequirePermission(permissions ...models.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ok, err := hasPermissions(c, permissions...)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to check permissions",
+			})
+		}
+		if !ok {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":                "Permission denied",
+				"required_permissions": permissions,
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -8,9 +8,14 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-// GetCurrentChampion returns the current #1 ranked player's championship reign
+// GetCurrentChampion returns the current #1 ranked player's championship reign (?season=<id|current>)
 func GetCurrentChampion(c *fiber.Ctx) error {
-	reign, err := services.GetCurrentChampion()
+	seasonID, err := seasonScope(c)
+	if err != nil {
+		return seasonError(c, err, "Failed to fetch season")
+	}
+
+	reign, err := services.GetCurrentChampion(seasonID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "No current champion found",
@@ -20,12 +25,17 @@ func GetCurrentChampion(c *fiber.Ctx) error {
 	return c.JSON(reign)
 }
 
-// GetChampionshipHistory returns all championship reigns
+// GetChampionshipHistory returns all championship reigns (?season=<id|current>)
 func GetChampionshipHistory(c *fiber.Ctx) error {
 	limitStr := c.Query("limit", "50")
 	limit, _ := strconv.Atoi(limitStr)
 
-	reigns, err := services.GetChampionshipHistory(limit)
+	seasonID, err := seasonScope(c)
+	if err != nil {
+		return seasonError(c, err, "Failed to fetch season")
+	}
+
+	reigns, err := services.GetChampionshipHistory(seasonID, limit)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch championship history",
@@ -38,9 +48,14 @@ func GetChampionshipHistory(c *fiber.Ctx) error {
 	})
 }
 
-// GetChampionStats returns aggregated statistics for all champions
+// GetChampionStats returns aggregated statistics for all champions (?season=<id|current>)
 func GetChampionStats(c *fiber.Ctx) error {
-	stats, err := services.GetChampionStats()
+	seasonID, err := seasonScope(c)
+	if err != nil {
+		return seasonError(c, err, "Failed to fetch season")
+	}
+
+	stats, err := services.GetChampionStats(seasonID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch champion stats",
@@ -53,7 +68,7 @@ func GetChampionStats(c *fiber.Ctx) error {
 	})
 }
 
-// GetPlayerChampionshipHistory returns championship history for a specific player
+// GetPlayerChampionshipHistory returns championship history for a specific player (?season=<id|current>)
 func GetPlayerChampionshipHistory(c *fiber.Ctx) error {
 	playerID, err := strconv.ParseUint(c.Params("id"), 10, 32)
 	if err != nil {
@@ -62,7 +77,12 @@ func GetPlayerChampionshipHistory(c *fiber.Ctx) error {
 		})
 	}
 
-	reigns, err := services.GetPlayerChampionshipHistory(uint(playerID))
+	seasonID, err := seasonScope(c)
+	if err != nil {
+		return seasonError(c, err, "Failed to fetch season")
+	}
+
+	reigns, err := services.GetPlayerChampionshipHistory(uint(playerID), seasonID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch player championship history",
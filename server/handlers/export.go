@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ExportData downloads the whole database as a zip archive of JSON lines
+// files with a manifest (requires data:export). Password hashes are left out.
+func ExportData(c *fiber.Ctx) error {
+	var archive bytes.Buffer
+	if _, err := services.WriteExport(config.DB, &archive); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to export data",
+		})
+	}
+
+	filename := fmt.Sprintf("sps-export-%s.zip", time.Now().UTC().Format("20060102-150405"))
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return c.Send(archive.Bytes())
+}
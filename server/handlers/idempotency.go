@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"stone-paper-scissors/models"
+	"stone-paper-scissors/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// IdempotencyMiddleware makes a write safe to retry. A request carrying an
+// Idempotency-Key header is processed once; retries with the same key and
+// body get the stored response back, and a key reused with a different body
+// is rejected. Server errors are not stored, so those requests can be retried.
+// Must run after AuthMiddleware, keys are scoped to the admin.
+func IdempotencyMiddleware(c *fiber.Ctx) error {
+	key := strings.TrimSpace(c.Get("Idempotency-Key"))
+	if key == "" {
+		return c.Next()
+	}
+	if len(key) > 255 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Idempotency-Key must be at most 255 characters",
+		})
+	}
+
+	admin := c.Locals("admin").(*models.Admin)
+	hash := services.HashRequest(c.Method(), c.Path(), c.Body())
+
+	record, replay, err := services.BeginIdempotentRequest(admin.ID, key, hash)
+	switch {
+	case errors.Is(err, services.ErrIdempotencyKeyReused):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Idempotency-Key has already been used with a different request",
+		})
+	case errors.Is(err, services.ErrIdempotencyInProgress):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "A request with this Idempotency-Key is still being processed",
+		})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check idempotency key",
+		})
+	}
+
+	if replay {
+		c.Set("Idempotent-Replayed", "true")
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Status(record.StatusCode).SendString(record.ResponseBody)
+	}
+
+	// Release the key if the handler fails or panics so the client can retry
+	completed := false
+	defer func() {
+		if !completed {
+			services.AbandonIdempotentRequest(record)
+		}
+	}()
+
+	if err := c.Next(); err != nil {
+		return err
+	}
+
+	status := c.Response().StatusCode()
+	if status >= fiber.StatusInternalServerError {
+		return nil
+	}
+	if err := services.CompleteIdempotentRequest(record, status, c.Response().Body()); err != nil {
+		return nil
+	}
+	completed = true
+	return nil
+}
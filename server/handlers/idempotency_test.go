@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+	"stone-paper-scissors/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// idempotentRequest posts body with an Idempotency-Key and returns the
+// status, the response body and whether it was replayed
+func idempotentRequest(t *testing.T, app *fiber.App, path, key, body string) (int, string, bool) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(response), resp.Header.Get("Idempotent-Replayed") == "true"
+}
+
+func TestIdempotentMatchSubmission(t *testing.T) {
+	setupTestDB(t)
+	services.SetRatingSystem(services.EloSystem{})
+
+	admin := models.Admin{Username: "root", Email: "root@example.com", PasswordHash: "x", Role: models.RoleSuperAdmin}
+	other := models.Admin{Username: "other", Email: "other@example.com", PasswordHash: "x", Role: models.RoleSuperAdmin}
+	alice := models.Player{Name: "Alice", Elo: 1000}
+	bob := models.Player{Name: "Bob", Elo: 1000}
+	for _, row := range []interface{}{&admin, &other, &alice, &bob} {
+		if err := config.DB.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	app := fiber.New()
+	app.Post("/:admin/matches", func(c *fiber.Ctx) error {
+		if c.Params("admin") == "other" {
+			c.Locals("admin", &other)
+		} else {
+			c.Locals("admin", &admin)
+		}
+		return c.Next()
+	}, IdempotencyMiddleware, SubmitMatch)
+
+	win := fmt.Sprintf(`{"player1_id":%d,"player2_id":%d,"player1_score":2,"player2_score":0}`, alice.ID, bob.ID)
+	loss := fmt.Sprintf(`{"player1_id":%d,"player2_id":%d,"player1_score":0,"player2_score":2}`, alice.ID, bob.ID)
+	matchCount := func() int64 {
+		t.Helper()
+		var count int64
+		if err := config.DB.Model(&models.Match{}).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	status, first, replayed := idempotentRequest(t, app, "/root/matches", "retry-1", win)
+	if status != fiber.StatusCreated || replayed {
+		t.Fatalf("first request: got status %d, replayed %v", status, replayed)
+	}
+
+	// A retry gets the stored response back without recording the match again
+	status, again, replayed := idempotentRequest(t, app, "/root/matches", "retry-1", win)
+	if status != fiber.StatusCreated || !replayed || again != first {
+		t.Errorf("retry: got status %d, replayed %v, body %s, want the first response replayed", status, replayed, again)
+	}
+	if count := matchCount(); count != 1 {
+		t.Errorf("got %d matches after a retry, want 1", count)
+	}
+
+	// The same key with a different request is refused
+	if status, _, _ := idempotentRequest(t, app, "/root/matches", "retry-1", loss); status != fiber.StatusConflict {
+		t.Errorf("reused key: got status %d, want 409", status)
+	}
+
+	// Keys are scoped to the admin, and requests without a key are not deduplicated
+	if status, _, replayed := idempotentRequest(t, app, "/other/matches", "retry-1", win); status != fiber.StatusCreated || replayed {
+		t.Errorf("another admin's key: got status %d, replayed %v, want a new match", status, replayed)
+	}
+	idempotentRequest(t, app, "/root/matches", "", win)
+	idempotentRequest(t, app, "/root/matches", "", win)
+	if count := matchCount(); count != 4 {
+		t.Errorf("got %d matches, want 4", count)
+	}
+
+	// An expired key can be used again
+	if err := config.DB.Model(&models.IdempotencyKey{}).Where("idempotency_key = ?", "retry-1").
+		Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if status, _, replayed := idempotentRequest(t, app, "/root/matches", "retry-1", loss); status != fiber.StatusCreated || replayed {
+		t.Errorf("expired key: got status %d, replayed %v, want a new match", status, replayed)
+	}
+
+	if status, _, _ := idempotentRequest(t, app, "/root/matches", strings.Repeat("k", 256), win); status != fiber.StatusBadRequest {
+		t.Errorf("long key: got status %d, want 400", status)
+	}
+}
+
+func TestIdempotencyDoesNotStoreServerErrors(t *testing.T) {
+	setupTestDB(t)
+
+	admin := models.Admin{Username: "root", Email: "root@example.com", PasswordHash: "x", Role: models.RoleSuperAdmin}
+	if err := config.DB.Create(&admin).Error; err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	app := fiber.New()
+	app.Post("/flaky", func(c *fiber.Ctx) error {
+		c.Locals("admin", &admin)
+		return c.Next()
+	}, IdempotencyMiddleware, func(c *fiber.Ctx) error {
+		calls++
+		if calls == 1 {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "try again"})
+		}
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"calls": calls})
+	})
+
+	for i, want := range []int{fiber.StatusInternalServerError, fiber.StatusCreated, fiber.StatusCreated} {
+		if status, _, _ := idempotentRequest(t, app, "/flaky", "once", `{}`); status != want {
+			t.Errorf("request %d: got status %d, want %d", i+1, status, want)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("handler ran %d times, want 2: once failing and once succeeding", calls)
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"stone-paper-scissors/models"
+	"stone-paper-scissors/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// importFormat picks the format of an import from ?format, the file name or
+// the content type
+func importFormat(c *fiber.Ctx, filename string) string {
+	if format := c.Query("format"); format != "" {
+		return format
+	}
+	if ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), "."); ext != "" {
+		return ext
+	}
+	if strings.Contains(strings.ToLower(c.Get(fiber.HeaderContentType)), "csv") {
+		return "csv"
+	}
+	return "json"
+}
+
+// ImportMatches loads historical match results from a CSV or JSON body (or a
+// multipart "file" upload). Nothing is stored unless every row is valid; pass
+// ?dry_run=true to only validate.
+func ImportMatches(c *fiber.Ctx) error {
+	admin := c.Locals("admin").(*models.Admin)
+	dryRun := c.QueryBool("dry_run", false)
+
+	var body io.Reader = bytes.NewReader(c.Body())
+	filename := ""
+	if file, err := c.FormFile("file"); err == nil {
+		opened, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Failed to read the uploaded file",
+			})
+		}
+		defer opened.Close()
+		body = opened
+		filename = file.Filename
+	}
+
+	rows, err := services.ParseImport(importFormat(c, filename), body)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	report, err := services.ImportMatches(rows, &admin.ID, apiKeyID(c), dryRun)
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": validationErr.Message,
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to import matches",
+		})
+	}
+
+	if report.Invalid > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Some rows are invalid, nothing was imported",
+			"report": report,
+		})
+	}
+
+	message := "Matches imported successfully and ratings recomputed"
+	if dryRun {
+		message = "Dry run complete, no changes were written"
+	}
+	return c.JSON(fiber.Map{
+		"message": message,
+		"report":  report,
+	})
+}
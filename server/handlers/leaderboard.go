@@ -14,6 +14,7 @@ type LeaderboardEntry struct {
 	ID           uint    `json:"id"`
 	Name         string  `json:"name"`
 	Elo          float64 `json:"elo"`
+	Deviation    float64 `json:"rating_deviation,omitempty"`
 	MatchesWon   int     `json:"matches_won"`
 	MatchesLost  int     `json:"matches_lost"`
 	MatchesDrawn int     `json:"matches_drawn"`
@@ -21,11 +22,60 @@ type LeaderboardEntry struct {
 	WinRate      float64 `json:"win_rate"`
 }
 
-// GetLeaderboard returns the ranked leaderboard of all players
+// seasonLeaderboardEntries builds leaderboard entries from season ratings
+func seasonLeaderboardEntries(ratings []models.SeasonRating, offset int) []LeaderboardEntry {
+	rating := services.Rating()
+	leaderboard := make([]LeaderboardEntry, 0, len(ratings))
+	for i, row := range ratings {
+		var winRate float64
+		if row.TotalMatches > 0 {
+			winRate = float64(row.MatchesWon) / float64(row.TotalMatches) * 100
+		}
+
+		leaderboard = append(leaderboard, LeaderboardEntry{
+			Rank:         offset + i + 1,
+			ID:           row.PlayerID,
+			Name:         row.Player.Name,
+			Elo:          row.Elo,
+			Deviation:    rating.Uncertainty(services.RatingFromSeason(&row)),
+			MatchesWon:   row.MatchesWon,
+			MatchesLost:  row.MatchesLost,
+			MatchesDrawn: row.MatchesDrawn,
+			TotalMatches: row.TotalMatches,
+			WinRate:      winRate,
+		})
+	}
+	return leaderboard
+}
+
+// GetLeaderboard returns the ranked leaderboard of all players, or of the
+// players of one season with ?season=<id|current>
 func GetLeaderboard(c *fiber.Ctx) error {
 	limit := c.QueryInt("limit", 100)
 	offset := c.QueryInt("offset", 0)
 
+	season, err := seasonFromQuery(c)
+	if err != nil {
+		return seasonError(c, err, "Failed to fetch leaderboard")
+	}
+	if season != nil {
+		ratings, total, err := services.GetSeasonLeaderboard(season.ID, limit, offset)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch leaderboard",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"leaderboard":   seasonLeaderboardEntries(ratings, offset),
+			"total":         total,
+			"limit":         limit,
+			"offset":        offset,
+			"season":        season,
+			"rating_system": services.Rating().Name(),
+		})
+	}
+
 	var players []models.Player
 	var total int64
 
@@ -39,6 +89,7 @@ func GetLeaderboard(c *fiber.Ctx) error {
 		})
 	}
 
+	rating := services.Rating()
 	var leaderboard []LeaderboardEntry
 	for i, player := range players {
 		var winRate float64
@@ -51,6 +102,7 @@ func GetLeaderboard(c *fiber.Ctx) error {
 			ID:           player.ID,
 			Name:         player.Name,
 			Elo:          player.Elo,
+			Deviation:    rating.Uncertainty(services.RatingFromPlayer(&player)),
 			MatchesWon:   player.MatchesWon,
 			MatchesLost:  player.MatchesLost,
 			MatchesDrawn: player.MatchesDrawn,
@@ -60,17 +112,39 @@ func GetLeaderboard(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(fiber.Map{
-		"leaderboard": leaderboard,
-		"total":       total,
-		"limit":       limit,
-		"offset":      offset,
+		"leaderboard":   leaderboard,
+		"total":         total,
+		"limit":         limit,
+		"offset":        offset,
+		"rating_system": rating.Name(),
 	})
 }
 
-// GetTopPlayers returns top N players
+// GetTopPlayers returns top N players (?season=<id|current> for a season)
 func GetTopPlayers(c *fiber.Ctx) error {
 	n := c.QueryInt("n", 10)
 
+	season, err := seasonFromQuery(c)
+	if err != nil {
+		return seasonError(c, err, "Failed to fetch top players")
+	}
+	if season != nil {
+		ratings, _, err := services.GetSeasonLeaderboard(season.ID, n, 0)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch top players",
+			})
+		}
+
+		leaderboard := seasonLeaderboardEntries(ratings, 0)
+		return c.JSON(fiber.Map{
+			"top_players":   leaderboard,
+			"count":         len(leaderboard),
+			"season":        season,
+			"rating_system": services.Rating().Name(),
+		})
+	}
+
 	var players []models.Player
 	if result := config.DB.Order("elo DESC").Limit(n).Find(&players); result.Error != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -78,6 +152,7 @@ func GetTopPlayers(c *fiber.Ctx) error {
 		})
 	}
 
+	rating := services.Rating()
 	var leaderboard []LeaderboardEntry
 	for i, player := range players {
 		var winRate float64
@@ -90,6 +165,7 @@ func GetTopPlayers(c *fiber.Ctx) error {
 			ID:           player.ID,
 			Name:         player.Name,
 			Elo:          player.Elo,
+			Deviation:    rating.Uncertainty(services.RatingFromPlayer(&player)),
 			MatchesWon:   player.MatchesWon,
 			MatchesLost:  player.MatchesLost,
 			MatchesDrawn: player.MatchesDrawn,
@@ -99,8 +175,9 @@ func GetTopPlayers(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(fiber.Map{
-		"top_players": leaderboard,
-		"count":       len(leaderboard),
+		"top_players":   leaderboard,
+		"count":         len(leaderboard),
+		"rating_system": rating.Name(),
 	})
 }
 
@@ -134,6 +211,7 @@ func GetPlayerRank(c *fiber.Ctx) error {
 			ID:           player.ID,
 			Name:         player.Name,
 			Elo:          player.Elo,
+			Deviation:    services.Rating().Uncertainty(services.RatingFromPlayer(&player)),
 			MatchesWon:   player.MatchesWon,
 			MatchesLost:  player.MatchesLost,
 			MatchesDrawn: player.MatchesDrawn,
@@ -168,22 +246,39 @@ func PredictMatch(c *fiber.Ctx) error {
 		})
 	}
 
-	player1WinProb := services.CalculateWinProbability(player1.Elo, player2.Elo)
-	player2WinProb := services.CalculateWinProbability(player2.Elo, player1.Elo)
+	rating := services.Rating()
+	player1Rating := services.RatingFromPlayer(&player1)
+	player2Rating := services.RatingFromPlayer(&player2)
 
-	return c.JSON(fiber.Map{
+	player1WinProb := rating.WinProbability(player1Rating, player2Rating)
+	player2WinProb := rating.WinProbability(player2Rating, player1Rating)
+
+	response := fiber.Map{
 		"player1": fiber.Map{
-			"id":              player1.ID,
-			"name":            player1.Name,
-			"elo":             player1.Elo,
-			"win_probability": player1WinProb,
+			"id":               player1.ID,
+			"name":             player1.Name,
+			"elo":              player1.Elo,
+			"rating_deviation": rating.Uncertainty(player1Rating),
+			"win_probability":  player1WinProb,
 		},
 		"player2": fiber.Map{
-			"id":              player2.ID,
-			"name":            player2.Name,
-			"elo":             player2.Elo,
-			"win_probability": player2WinProb,
+			"id":               player2.ID,
+			"name":             player2.Name,
+			"elo":              player2.Elo,
+			"rating_deviation": rating.Uncertainty(player2Rating),
+			"win_probability":  player2WinProb,
 		},
 		"elo_difference": player1.Elo - player2.Elo,
-	})
+		"rating_system":  rating.Name(),
+	}
+
+	// Add the players' history against each other when they have met before
+	if player1.ID != player2.ID {
+		h2h, err := services.GetHeadToHead(&player1, &player2, 5)
+		if err == nil && h2h.Meetings > 0 {
+			response["head_to_head"] = h2h
+		}
+	}
+
+	return c.JSON(response)
 }
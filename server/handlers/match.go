@@ -1,12 +1,15 @@
 package handlers
 
 import (
+"errors"
 "fmt"
 "stone-paper-scissors/config"
 "stone-paper-scissors/models"
 "stone-paper-scissors/services"
+"time"
 
 "github.com/gofiber/fiber/v2"
+"gorm.io/gorm"
 )
 
 // SubmitMatch records a match result and updates ELO ratings
@@ -21,6 +24,13 @@ return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 // Get admin from context (set by AuthMiddleware)
 admin := c.Locals("admin").(*models.Admin)
 
+// Validate round-by-round throws before touching any player
+if _, err := services.BuildRounds(req.Rounds, req.Player1Score, req.Player2Score); err != nil {
+return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+"error": err.Error(),
+})
+}
+
 var player1, player2 models.Player
 
 // If player IDs are provided, use them; otherwise create/find by name
@@ -82,113 +92,65 @@ return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 })
 }
 
-// Calculate new ELO ratings
-eloResult := services.CalculateElo(
-player1.Elo,
-player2.Elo,
-req.Player1Score,
-req.Player2Score,
-player1.TotalMatches,
-player2.TotalMatches,
-)
-
-// Determine winner
-var winnerID *uint
-if req.Player1Score > req.Player2Score {
-winnerID = &player1.ID
-player1.MatchesWon++
-player2.MatchesLost++
-} else if req.Player2Score > req.Player1Score {
-winnerID = &player2.ID
-player2.MatchesWon++
-player1.MatchesLost++
-} else {
-// Draw
-player1.MatchesDrawn++
-player2.MatchesDrawn++
+// Matches entered late carry the time they were played
+var playedAt time.Time
+if req.PlayedAt != "" {
+var err error
+if playedAt, err = services.ParsePlayedAt(req.PlayedAt); err != nil {
+return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+"error": err.Error(),
+})
+}
 }
 
-// Create match record with admin ID
-match := models.Match{
+// Rate and store the match and track championship changes in a single
+// transaction, which is retried if it races another submission
+var match *models.Match
+err := services.WriteTransaction(func(tx *gorm.DB) error {
+var err error
+match, err = services.RecordMatch(tx, services.MatchInput{
 Player1ID:        player1.ID,
 Player2ID:        player2.ID,
 Player1Score:     req.Player1Score,
 Player2Score:     req.Player2Score,
-WinnerID:         winnerID,
-Player1EloBefore: player1.Elo,
-Player2EloBefore: player2.Elo,
-Player1EloAfter:  eloResult.Player1NewElo,
-Player2EloAfter:  eloResult.Player2NewElo,
-Player1EloChange: eloResult.Player1EloChange,
-Player2EloChange: eloResult.Player2EloChange,
+Rounds:           req.Rounds,
 CreatedByAdminID: &admin.ID,
-}
-
-// Update player stats
-player1.Elo = eloResult.Player1NewElo
-player2.Elo = eloResult.Player2NewElo
-player1.TotalMatches++
-player2.TotalMatches++
-
-// Start transaction
-tx := config.DB.Begin()
-
-if err := tx.Save(&player1).Error; err != nil {
-tx.Rollback()
-return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-"error": "Failed to update player 1",
+CreatedByAPIKeyID: apiKeyID(c),
+PlayedAt:         playedAt,
 })
+if err != nil {
+return err
 }
-
-if err := tx.Save(&player2).Error; err != nil {
-tx.Rollback()
-return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-"error": "Failed to update player 2",
+return services.RefreshChampion(tx)
+})
+var validationErr *services.ValidationError
+if errors.As(err, &validationErr) {
+return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+"error": err.Error(),
 })
 }
-
-if err := tx.Create(&match).Error; err != nil {
-tx.Rollback()
+if err != nil {
 return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-"error": "Failed to create match record",
+"error": "Failed to record match",
 })
 }
 
-tx.Commit()
-
-// Track championship changes after successful match
-// Get current leaderboard to check for championship changes
-var topPlayer models.Player
-if err := config.DB.Order("elo DESC").First(&topPlayer).Error; err == nil {
-// Get previous champion if exists
-prevChamp, _ := services.GetCurrentChampion()
-var oldChampID *uint
-if prevChamp != nil {
-oldChampID = &prevChamp.PlayerID
-}
-
-// If there's a new champion, track the change
-if oldChampID == nil || *oldChampID != topPlayer.ID {
-services.TrackChampionshipChange(topPlayer.ID, oldChampID)
-}
-}
-
 // Prepare response
 winnerName := ""
-if winnerID != nil {
-if *winnerID == player1.ID {
-winnerName = player1.Name
+if match.WinnerID != nil {
+if *match.WinnerID == match.Player1ID {
+winnerName = match.Player1.Name
 } else {
-winnerName = player2.Name
+winnerName = match.Player2.Name
 }
 }
 
 response := models.MatchResponse{
 ID:               match.ID,
-Player1ID:        player1.ID,
-Player2ID:        player2.ID,
-Player1Name:      player1.Name,
-Player2Name:      player2.Name,
+Player1ID:        match.Player1ID,
+Player2ID:        match.Player2ID,
+Player1Name:      match.Player1.Name,
+Player2Name:      match.Player2.Name,
 Player1Score:     match.Player1Score,
 Player2Score:     match.Player2Score,
 WinnerName:       winnerName,
@@ -199,7 +161,10 @@ Player2EloBefore: match.Player2EloBefore,
 Player1EloAfter:  match.Player1EloAfter,
 Player2EloAfter:  match.Player2EloAfter,
 CreatedByAdminID: match.CreatedByAdminID,
+CreatedByAPIKeyID: match.CreatedByAPIKeyID,
+PlayedAt:         match.PlayedAt.Format("2006-01-02 15:04:05"),
 CreatedAt:        match.CreatedAt.Format("2006-01-02 15:04:05"),
+Rounds:           match.Rounds,
 }
 
 return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -217,7 +182,7 @@ offset := c.QueryInt("offset", 0)
 query := config.DB.Model(&models.Match{}).
 Preload("Player1").
 Preload("Player2").
-Order("created_at DESC").
+Order("played_at DESC, id DESC").
 Limit(limit).
 Offset(offset)
 
@@ -268,6 +233,8 @@ Player2EloBefore: match.Player2EloBefore,
 Player1EloAfter:  match.Player1EloAfter,
 Player2EloAfter:  match.Player2EloAfter,
 CreatedByAdminID: match.CreatedByAdminID,
+CreatedByAPIKeyID: match.CreatedByAPIKeyID,
+PlayedAt:         match.PlayedAt.Format("2006-01-02 15:04:05"),
 CreatedAt:        match.CreatedAt.Format("2006-01-02 15:04:05"),
 })
 }
@@ -285,7 +252,9 @@ func GetMatch(c *fiber.Ctx) error {
 id := c.Params("id")
 
 var match models.Match
-if result := config.DB.Preload("Player1").Preload("Player2").First(&match, id); result.Error != nil {
+if result := config.DB.Preload("Player1").Preload("Player2").Preload("Rounds", func(db *gorm.DB) *gorm.DB {
+return db.Order("round_number ASC")
+}).First(&match, id); result.Error != nil {
 return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 "error": "Match not found",
 })
@@ -316,7 +285,10 @@ Player2EloBefore: match.Player2EloBefore,
 Player1EloAfter:  match.Player1EloAfter,
 Player2EloAfter:  match.Player2EloAfter,
 CreatedByAdminID: match.CreatedByAdminID,
+CreatedByAPIKeyID: match.CreatedByAPIKeyID,
+PlayedAt:         match.PlayedAt.Format("2006-01-02 15:04:05"),
 CreatedAt:        match.CreatedAt.Format("2006-01-02 15:04:05"),
+Rounds:           match.Rounds,
 }
 
 return c.JSON(response)
@@ -330,9 +302,15 @@ offset := c.QueryInt("offset", 0)
 
 currentAdmin := c.Locals("admin").(*models.Admin)
 
-// Only super admin can view other admin's matches, or the admin themselves
-if currentAdmin.Role != models.RoleSuperAdmin {
+// Other admins' matches need matches:view:any
 if adminId != fmt.Sprintf("%d", currentAdmin.ID) {
+allowed, err := hasPermissions(c, models.PermMatchesViewAny)
+if err != nil {
+return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+"error": "Failed to check permissions",
+})
+}
+if !allowed {
 return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 "error": "You can only view your own matches",
 })
@@ -346,7 +324,7 @@ query := config.DB.Model(&models.Match{}).
 Preload("Player1").
 Preload("Player2").
 Where("created_by_admin_id = ?", adminId).
-Order("created_at DESC")
+Order("played_at DESC, id DESC")
 
 query.Count(&total)
 
@@ -383,6 +361,8 @@ Player2EloBefore: match.Player2EloBefore,
 Player1EloAfter:  match.Player1EloAfter,
 Player2EloAfter:  match.Player2EloAfter,
 CreatedByAdminID: match.CreatedByAdminID,
+CreatedByAPIKeyID: match.CreatedByAPIKeyID,
+PlayedAt:         match.PlayedAt.Format("2006-01-02 15:04:05"),
 CreatedAt:        match.CreatedAt.Format("2006-01-02 15:04:05"),
 })
 }
@@ -395,10 +375,10 @@ return c.JSON(fiber.Map{
 })
 }
 
-// DeleteMatch deletes a match (admin can delete their own, super admin can delete any)
+// DeleteMatch deletes a match (admins with matches:submit can delete their
+// own, matches:delete:any allows deleting any)
 func DeleteMatch(c *fiber.Ctx) error {
 id := c.Params("id")
-currentAdmin := c.Locals("admin").(*models.Admin)
 
 var match models.Match
 if result := config.DB.Preload("Player1").Preload("Player2").First(&match, id); result.Error != nil {
@@ -408,74 +388,162 @@ return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 }
 
 // Check permissions
-if currentAdmin.Role != models.RoleSuperAdmin {
-if match.CreatedByAdminID == nil || *match.CreatedByAdminID != currentAdmin.ID {
+allowed, err := canModifyMatch(c, &match, models.PermMatchesDeleteAny)
+if err != nil {
+return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+"error": "Failed to check permissions",
+})
+}
+if !allowed {
 return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 "error": "You can only delete matches you created",
 })
 }
-}
 
-// Reverse the ELO changes and match counts
-tx := config.DB.Begin()
+// Delete the match and replay the match log so that every later match
+// involving either player is re-rated as if this one never happened
+var report *services.ReplayReport
+var failure string
+err = services.WriteTransaction(func(tx *gorm.DB) error {
+failure = "Failed to delete match"
+if err := services.DeleteMatch(tx, match.ID); err != nil {
+return err
+}
 
-var player1, player2 models.Player
-if err := tx.First(&player1, match.Player1ID).Error; err != nil {
-tx.Rollback()
-return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-"error": "Failed to find player 1",
+failure = "Failed to recompute ratings"
+var err error
+report, err = services.ReplayRatings(tx, false)
+if err != nil {
+return err
+}
+return services.RefreshChampion(tx)
+})
+var validationErr *services.ValidationError
+if errors.As(err, &validationErr) {
+return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+"error": validationErr.Message,
 })
 }
-if err := tx.First(&player2, match.Player2ID).Error; err != nil {
-tx.Rollback()
+if errors.Is(err, gorm.ErrRecordNotFound) {
+return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+"error": "Match not found",
+})
+}
+if err != nil {
 return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-"error": "Failed to find player 2",
+"error": failure,
 })
 }
 
-// Reverse ELO
-player1.Elo = match.Player1EloBefore
-player2.Elo = match.Player2EloBefore
-player1.TotalMatches--
-player2.TotalMatches--
+return c.JSON(fiber.Map{
+"message":         "Match deleted successfully and ratings recomputed",
+"players_updated": len(report.PlayersChanged),
+"matches_updated": len(report.MatchesChanged),
+})
+}
 
-// Reverse win/loss/draw counts
-if match.WinnerID != nil {
-if *match.WinnerID == player1.ID {
-player1.MatchesWon--
-player2.MatchesLost--
-} else {
-player2.MatchesWon--
-player1.MatchesLost--
+// canModifyMatch reports whether the current request may change or delete
+// match: anyPermission allows changing any match, matches:submit only the
+// ones the admin created
+func canModifyMatch(c *fiber.Ctx, match *models.Match, anyPermission models.Permission) (bool, error) {
+admin := c.Locals("admin").(*models.Admin)
+if match.CreatedByAdminID != nil && *match.CreatedByAdminID == admin.ID {
+return hasPermissions(c, models.PermMatchesSubmit)
 }
-} else {
-player1.MatchesDrawn--
-player2.MatchesDrawn--
+return hasPermissions(c, anyPermission)
 }
 
-if err := tx.Save(&player1).Error; err != nil {
-tx.Rollback()
-return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-"error": "Failed to update player 1",
+// UpdateMatch corrects the result of a match and re-rates every later match
+func UpdateMatch(c *fiber.Ctx) error {
+id, ok := parseID(c, "id")
+if !ok {
+return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+"error": "Invalid match ID",
 })
 }
-if err := tx.Save(&player2).Error; err != nil {
-tx.Rollback()
-return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-"error": "Failed to update player 2",
+currentAdmin := c.Locals("admin").(*models.Admin)
+
+var req models.UpdateMatchRequest
+if err := c.BodyParser(&req); err != nil {
+return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+"error": "Invalid request body",
 })
 }
 
-if err := tx.Delete(&match).Error; err != nil {
-tx.Rollback()
+var match models.Match
+if result := config.DB.First(&match, id); result.Error != nil {
+return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+"error": "Match not found",
+})
+}
+
+// Same rule as deleting a match
+allowed, err := canModifyMatch(c, &match, models.PermMatchesEditAny)
+if err != nil {
 return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-"error": "Failed to delete match",
+"error": "Failed to check permissions",
+})
+}
+if !allowed {
+return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+"error": "You can only edit matches you created",
 })
 }
 
-tx.Commit()
+var edit *models.MatchEdit
+err = services.WriteTransaction(func(tx *gorm.DB) error {
+var err error
+edit, err = services.EditMatch(tx, match.ID, req, currentAdmin.ID)
+if err != nil {
+return err
+}
+return services.RefreshChampion(tx)
+})
+var validationErr *services.ValidationError
+if errors.As(err, &validationErr) {
+return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+"error": err.Error(),
+})
+}
+if errors.Is(err, gorm.ErrRecordNotFound) {
+return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+"error": "Match not found",
+})
+}
+if err != nil {
+return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+"error": "Failed to edit match",
+})
+}
 
 return c.JSON(fiber.Map{
-"message": "Match deleted successfully and ELO changes reversed",
+"message":         "Match updated successfully and ratings recomputed",
+"edit":            edit,
+"players_updated": edit.PlayersUpdated,
+"matches_updated": edit.MatchesUpdated,
+})
+}
+
+// GetMatchEdits returns the audit trail of corrections to a match
+func GetMatchEdits(c *fiber.Ctx) error {
+id, ok := parseID(c, "id")
+if !ok {
+return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+"error": "Invalid match ID",
 })
 }
+
+edits, err := services.GetMatchEdits(id)
+if errors.Is(err, gorm.ErrRecordNotFound) {
+return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+"error": "Match not found",
+})
+}
+if err != nil {
+return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+"error": "Failed to fetch match edits",
+})
+}
+
+return c.JSON(edits)
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/migrations"
+	"stone-paper-scissors/models"
+	"stone-paper-scissors/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupTestDB points config.DB at a migrated SQLite database for the test
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=5000"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	previous := config.DB
+	config.DB = db
+	t.Cleanup(func() {
+		config.DB = previous
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+// matchTestApp serves the match handlers as admin, without authentication
+func matchTestApp(admin *models.Admin) *fiber.App {
+	app := fiber.New()
+	asAdmin := func(c *fiber.Ctx) error {
+		c.Locals("admin", admin)
+		return c.Next()
+	}
+	app.Post("/matches", asAdmin, SubmitMatch)
+	app.Delete("/matches/:id", asAdmin, DeleteMatch)
+	return app
+}
+
+func submitTestMatch(app *fiber.App, player1, player2 uint, score1, score2 int) (int, error) {
+	body := fmt.Sprintf(`{"player1_id":%d,"player2_id":%d,"player1_score":%d,"player2_score":%d}`, player1, player2, score1, score2)
+	req := httptest.NewRequest(http.MethodPost, "/matches", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		return 0, err
+	}
+	return resp.StatusCode, nil
+}
+
+func deleteTestMatch(app *fiber.App, id uint) (int, error) {
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/matches/%d", id), nil)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		return 0, err
+	}
+	return resp.StatusCode, nil
+}
+
+func TestConcurrentSubmitAndDeleteKeepRatingsConsistent(t *testing.T) {
+	setupTestDB(t)
+	services.SetRatingSystem(services.EloSystem{})
+
+	admin := models.Admin{Username: "root", Email: "root@example.com", PasswordHash: "x", Role: models.RoleSuperAdmin}
+	if err := config.DB.Create(&admin).Error; err != nil {
+		t.Fatal(err)
+	}
+	players := make([]models.Player, 4)
+	for i := range players {
+		players[i] = models.Player{Name: fmt.Sprintf("P%d", i+1), Elo: 1000}
+		if err := config.DB.Create(&players[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	app := matchTestApp(&admin)
+
+	// Every pair of players plays the others, with wins, losses and draws
+	const seeded = 12
+	for i := 0; i < seeded; i++ {
+		p1, p2 := players[i%4], players[(i+1+i/4)%4]
+		if status, err := submitTestMatch(app, p1.ID, p2.ID, i%3, 1); err != nil || status != fiber.StatusCreated {
+			t.Fatalf("seed match %d: status %d, %v", i, status, err)
+		}
+	}
+	var seededIDs []uint
+	if err := config.DB.Model(&models.Match{}).Order("id").Pluck("id", &seededIDs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Submit new matches and delete every other seeded one at the same time
+	var wg sync.WaitGroup
+	for i := 0; i < seeded; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p1, p2 := players[(i+2)%4], players[(i+3)%4]
+			status, err := submitTestMatch(app, p1.ID, p2.ID, 2, i%3)
+			if err != nil || status != fiber.StatusCreated {
+				t.Errorf("submit %d: status %d, %v", i, status, err)
+			}
+		}(i)
+		if i%2 == 0 {
+			wg.Add(1)
+			go func(id uint) {
+				defer wg.Done()
+				status, err := deleteTestMatch(app, id)
+				if err != nil || status != fiber.StatusOK {
+					t.Errorf("delete %d: status %d, %v", id, status, err)
+				}
+			}(seededIDs[i])
+		}
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+
+	var matches []models.Match
+	if err := config.DB.Find(&matches).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := seeded + seeded/2; len(matches) != want {
+		t.Fatalf("got %d matches, want %d", len(matches), want)
+	}
+
+	// The counters agree with the matches left
+	type counts struct{ total, won, lost, drawn int }
+	expected := make(map[uint]*counts)
+	for _, player := range players {
+		expected[player.ID] = &counts{}
+	}
+	for _, match := range matches {
+		p1, p2 := expected[match.Player1ID], expected[match.Player2ID]
+		p1.total++
+		p2.total++
+		switch {
+		case match.WinnerID == nil:
+			p1.drawn++
+			p2.drawn++
+		case *match.WinnerID == match.Player1ID:
+			p1.won++
+			p2.lost++
+		default:
+			p2.won++
+			p1.lost++
+		}
+	}
+	for _, player := range players {
+		var stored models.Player
+		if err := config.DB.First(&stored, player.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		want := expected[player.ID]
+		got := counts{stored.TotalMatches, stored.MatchesWon, stored.MatchesLost, stored.MatchesDrawn}
+		if got != *want {
+			t.Errorf("%s: got total/won/lost/drawn %+v, want %+v", stored.Name, got, *want)
+		}
+	}
+
+	// And the ratings are those of a full replay of the match log
+	report, err := services.ReplayRatings(config.DB, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.PlayersChanged) > 0 || len(report.MatchesChanged) > 0 {
+		t.Errorf("replay differs from the stored ratings: %+v %+v", report.PlayersChanged, report.MatchesChanged)
+	}
+}
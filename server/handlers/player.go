@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
+
 	"stone-paper-scissors/config"
 	"stone-paper-scissors/models"
+	"stone-paper-scissors/services"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -238,11 +241,10 @@ func GetPlayerMatches(c *fiber.Ctx) error {
 	var matches []models.Match
 	var total int64
 
-	query := config.DB.Model(&models.Match{}).
+	query := services.PlayerMatchesQuery(playerID).
 		Preload("Player1").
 		Preload("Player2").
-		Where("player1_id = ? OR player2_id = ?", playerID, playerID).
-		Order("created_at DESC")
+		Order("played_at DESC, id DESC")
 
 	query.Count(&total)
 
@@ -262,6 +264,7 @@ func GetPlayerMatches(c *fiber.Ctx) error {
 		EloChange     float64 `json:"elo_change"`
 		EloBefore     float64 `json:"elo_before"`
 		EloAfter      float64 `json:"elo_after"`
+		PlayedAt      string  `json:"played_at"`
 		CreatedAt     string  `json:"created_at"`
 	}
 
@@ -269,6 +272,7 @@ func GetPlayerMatches(c *fiber.Ctx) error {
 	for _, match := range matches {
 		var matchResp PlayerMatchResponse
 		matchResp.ID = match.ID
+		matchResp.PlayedAt = match.PlayedAt.Format("2006-01-02 15:04:05")
 		matchResp.CreatedAt = match.CreatedAt.Format("2006-01-02 15:04:05")
 
 		if match.Player1ID == player.ID {
@@ -316,3 +320,82 @@ func GetPlayerMatches(c *fiber.Ctx) error {
 		"offset":  offset,
 	})
 }
+
+// GetPlayerTendencies returns throw tendencies of a player for scouting
+func GetPlayerTendencies(c *fiber.Ctx) error {
+	playerID := c.Params("id")
+	limit := c.QueryInt("limit", 5)
+
+	var player models.Player
+	if result := config.DB.First(&player, playerID); result.Error != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Player not found",
+		})
+	}
+
+	tendencies, err := services.GetPlayerTendencies(&player, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to analyse player tendencies",
+		})
+	}
+
+	return c.JSON(tendencies)
+}
+
+// GetPlayerRatingHistory returns a player's rating over time (?interval=match|daily|weekly)
+func GetPlayerRatingHistory(c *fiber.Ctx) error {
+	playerID := c.Params("id")
+
+	var player models.Player
+	if result := config.DB.First(&player, playerID); result.Error != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Player not found",
+		})
+	}
+
+	history, err := services.GetPlayerRatingHistory(&player, c.Query("interval"))
+	if err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": validationErr.Message,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch rating history",
+		})
+	}
+
+	return c.JSON(history)
+}
+
+// GetHeadToHead returns a player's record against one opponent (?last=N meetings, default 5)
+func GetHeadToHead(c *fiber.Ctx) error {
+	var player, opponent models.Player
+	if result := config.DB.First(&player, c.Params("id")); result.Error != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Player not found",
+		})
+	}
+	if result := config.DB.First(&opponent, c.Params("opponentId")); result.Error != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Opponent not found",
+		})
+	}
+
+	h2h, err := services.GetHeadToHead(&player, &opponent, c.QueryInt("last", 5))
+	if err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": validationErr.Message,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch head-to-head record",
+		})
+	}
+
+	return c.JSON(h2h)
+}
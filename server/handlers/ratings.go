@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"stone-paper-scissors/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ReplayRatings recomputes all ratings from the match log (requires ratings:manage).
+// Pass ?dry_run=true to get the diff report without writing anything.
+func ReplayRatings(c *fiber.Ctx) error {
+	dryRun := c.QueryBool("dry_run", false)
+
+	report, err := services.RecomputeRatings(dryRun)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to replay ratings",
+		})
+	}
+
+	message := "Ratings recomputed successfully"
+	if dryRun {
+		message = "Dry run complete, no changes were written"
+	}
+
+	return c.JSON(fiber.Map{
+		"message": message,
+		"report":  report,
+	})
+}
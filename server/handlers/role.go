@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"errors"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+	"stone-paper-scissors/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// roleError maps a role service error to an HTTP response
+func roleError(c *fiber.Ctx, err error, fallback string) error {
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": validationErr.Message,
+		})
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Role not found",
+		})
+	}
+	if errors.Is(err, services.ErrRoleInUse) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Role is still assigned to admins",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": fallback,
+	})
+}
+
+// checkGrantable responds with 403 unless admin holds every one of
+// permissions, so that admins cannot hand out more than they hold. Unknown
+// permissions are left for the role validation to reject. It reports whether
+// the request may go on.
+func checkGrantable(c *fiber.Ctx, permissions []models.Permission) (bool, error) {
+	known := make([]models.Permission, 0, len(permissions))
+	for _, p := range permissions {
+		if models.ValidPermission(p) {
+			known = append(known, p)
+		}
+	}
+	ok, err := hasPermissions(c, known...)
+	if err != nil {
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check permissions",
+		})
+	}
+	if !ok {
+		return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You cannot grant permissions you do not hold",
+		})
+	}
+	return true, nil
+}
+
+// checkManageable responds with 403 unless the current request may act on
+// the account of target, e.g. reset their password or two-factor: only super
+// admins act on super admins, and others only on admins whose permissions
+// they all hold, so that nobody can take over a more privileged account. It
+// reports whether the request may go on.
+func checkManageable(c *fiber.Ctx, target *models.Admin) (bool, error) {
+	currentAdmin := c.Locals("admin").(*models.Admin)
+	if target.Role == models.RoleSuperAdmin && currentAdmin.Role != models.RoleSuperAdmin {
+		return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Only super admin can manage super admins",
+		})
+	}
+
+	permissions, err := services.EffectivePermissions(target)
+	if err != nil {
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check permissions",
+		})
+	}
+	ok, err := hasPermissions(c, permissions...)
+	if err != nil {
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check permissions",
+		})
+	}
+	if !ok {
+		return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You cannot manage an admin with permissions you do not hold",
+		})
+	}
+	return true, nil
+}
+
+// GetPermissions lists every permission that can be put in a role
+func GetPermissions(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"permissions":         models.Permissions,
+		"default_permissions": models.DefaultAdminPermissions,
+	})
+}
+
+// GetRoles lists the custom roles (requires admins:manage)
+func GetRoles(c *fiber.Ctx) error {
+	roles, err := services.ListRoles()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch roles",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"roles": roles,
+		"total": len(roles),
+	})
+}
+
+// CreateRole creates a custom role (requires admins:manage)
+func CreateRole(c *fiber.Ctx) error {
+	var req models.RoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if ok, err := checkGrantable(c, req.Permissions); !ok {
+		return err
+	}
+
+	role, err := services.CreateRole(req)
+	if err != nil {
+		return roleError(c, err, "Failed to create role")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(role)
+}
+
+// UpdateRole changes the name, description and permissions of a custom role
+// (requires admins:manage). Admins must hold the permissions the role has
+// now as well as the new ones, or they could strip a more privileged admin's
+// role until checkManageable lets them take over the account.
+func UpdateRole(c *fiber.Ctx) error {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid role ID",
+		})
+	}
+
+	var req models.RoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	var existing models.Role
+	if err := config.DB.First(&existing, id).Error; err != nil {
+		return roleError(c, err, "Failed to update role")
+	}
+	if ok, err := checkGrantable(c, existing.Permissions); !ok {
+		return err
+	}
+	if ok, err := checkGrantable(c, req.Permissions); !ok {
+		return err
+	}
+
+	role, err := services.UpdateRole(id, req)
+	if err != nil {
+		return roleError(c, err, "Failed to update role")
+	}
+
+	return c.JSON(role)
+}
+
+// DeleteRole deletes a custom role no admin holds (requires admins:manage)
+func DeleteRole(c *fiber.Ctx) error {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid role ID",
+		})
+	}
+
+	if err := services.DeleteRole(id); err != nil {
+		return roleError(c, err, "Failed to delete role")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Role deleted successfully",
+	})
+}
+
+// AssignAdminRole gives an admin a custom role, or with a null role_id the
+// default admin permissions (requires admins:manage)
+func AssignAdminRole(c *fiber.Ctx) error {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid admin ID",
+		})
+	}
+
+	var req models.AssignRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	var target models.Admin
+	if result := config.DB.First(&target, id); result.Error != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Admin not found",
+		})
+	}
+	if ok, err := checkManageable(c, &target); !ok {
+		return err
+	}
+
+	granted := models.DefaultAdminPermissions
+	if req.RoleID != nil {
+		var role models.Role
+		if result := config.DB.First(&role, *req.RoleID); result.Error != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Role not found",
+			})
+		}
+		granted = role.Permissions
+	}
+	if ok, err := checkGrantable(c, granted); !ok {
+		return err
+	}
+
+	admin, err := services.AssignRole(id, req.RoleID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Admin not found",
+		})
+	}
+	if err != nil {
+		return roleError(c, err, "Failed to assign role")
+	}
+
+	return adminPermissions(c, admin)
+}
+
+// GetAdminPermissions lists the effective permissions of an admin (requires admins:manage)
+func GetAdminPermissions(c *fiber.Ctx) error {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid admin ID",
+		})
+	}
+
+	var admin models.Admin
+	if result := config.DB.Preload("CustomRole").First(&admin, id); result.Error != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Admin not found",
+		})
+	}
+
+	return adminPermissions(c, &admin)
+}
+
+// GetMyPermissions lists the effective permissions of the current admin, or
+// of the API key the request was made with
+func GetMyPermissions(c *fiber.Ctx) error {
+	admin := c.Locals("admin").(*models.Admin)
+	key, ok := c.Locals("api_key").(*models.APIKey)
+	if !ok {
+		return adminPermissions(c, admin)
+	}
+
+	permissions, err := requestPermissions(c)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch permissions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"admin_id":    admin.ID,
+		"api_key_id":  key.ID,
+		"scopes":      key.Scopes,
+		"permissions": permissions,
+	})
+}
+
+// adminPermissions responds with the role and effective permissions of admin
+func adminPermissions(c *fiber.Ctx, admin *models.Admin) error {
+	permissions, err := services.EffectivePermissions(admin)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch permissions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"admin_id":    admin.ID,
+		"role":        admin.Role,
+		"custom_role": admin.CustomRole,
+		"permissions": permissions,
+	})
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// roleTestApp serves the role and admin handlers as admin, without authentication
+func roleTestApp(admin *models.Admin) *fiber.App {
+	app := fiber.New()
+	asAdmin := func(c *fiber.Ctx) error {
+		c.Locals("admin", admin)
+		return c.Next()
+	}
+	app.Post("/roles", asAdmin, CreateRole)
+	app.Put("/roles/:id", asAdmin, UpdateRole)
+	app.Post("/admins", asAdmin, CreateAdmin)
+	app.Put("/admins/:id/role", asAdmin, AssignAdminRole)
+	app.Delete("/admins/:id/sessions", asAdmin, RevokeAdminSessions)
+	return app
+}
+
+func roleTestRequest(t *testing.T, app *fiber.App, method, path, body string) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+// createTestAdmin stores an admin with a custom role of permissions
+func createTestAdmin(t *testing.T, name string, permissions ...models.Permission) *models.Admin {
+	t.Helper()
+	role := models.Role{Name: name + " role", Permissions: permissions}
+	if err := config.DB.Create(&role).Error; err != nil {
+		t.Fatal(err)
+	}
+	admin := models.Admin{
+		Username:     name,
+		Email:        name + "@example.com",
+		PasswordHash: "x",
+		Role:         models.RoleAdmin,
+		RoleID:       &role.ID,
+	}
+	if err := config.DB.Create(&admin).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &admin
+}
+
+func TestUpdateRoleRefusesDowngradingMorePrivilegedRole(t *testing.T) {
+	setupTestDB(t)
+
+	manager := createTestAdmin(t, "manager", models.PermAdminsManage, models.PermPlayersWrite)
+	owner := createTestAdmin(t, "owner", models.PermAdminsManage, models.PermPlayersWrite, models.PermSettingsManage)
+	app := roleTestApp(manager)
+
+	// Cutting the owner's role down to what the manager holds is refused
+	body := `{"name":"owner role","permissions":["players:write"]}`
+	if status := roleTestRequest(t, app, http.MethodPut, fmt.Sprintf("/roles/%d", *owner.RoleID), body); status != fiber.StatusForbidden {
+		t.Fatalf("downgrade: got status %d, want 403", status)
+	}
+	var role models.Role
+	if err := config.DB.First(&role, *owner.RoleID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(role.Permissions) != 3 {
+		t.Errorf("role was changed to %v", role.Permissions)
+	}
+	if status := roleTestRequest(t, app, http.MethodDelete, fmt.Sprintf("/admins/%d/sessions", owner.ID), ""); status != fiber.StatusForbidden {
+		t.Errorf("manage owner: got status %d, want 403", status)
+	}
+
+	// A role within the manager's own permissions can still be changed
+	body = `{"name":"manager role","permissions":["admins:manage","players:write"],"description":"Managers"}`
+	if status := roleTestRequest(t, app, http.MethodPut, fmt.Sprintf("/roles/%d", *manager.RoleID), body); status != fiber.StatusOK {
+		t.Errorf("update own role: got status %d, want 200", status)
+	}
+
+	if status := roleTestRequest(t, app, http.MethodPut, "/roles/999", body); status != fiber.StatusNotFound {
+		t.Errorf("missing role: got status %d, want 404", status)
+	}
+}
+
+func TestAdminsOnlyGrantAndManageWhatTheyHold(t *testing.T) {
+	setupTestDB(t)
+
+	manager := createTestAdmin(t, "manager", models.PermAdminsManage, models.PermPlayersWrite)
+	junior := createTestAdmin(t, "junior", models.PermPlayersWrite)
+	owner := createTestAdmin(t, "owner", models.PermAdminsManage, models.PermSettingsManage)
+	plain := models.Admin{Username: "plain", Email: "plain@example.com", PasswordHash: "x", Role: models.RoleAdmin}
+	super := models.Admin{Username: "super", Email: "super@example.com", PasswordHash: "x", Role: models.RoleSuperAdmin}
+	for _, admin := range []*models.Admin{&plain, &super} {
+		if err := config.DB.Create(admin).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	app := roleTestApp(manager)
+	request := func(method, path, body string, want int) {
+		t.Helper()
+		if status := roleTestRequest(t, app, method, path, body); status != want {
+			t.Errorf("%s %s %s: got status %d, want %d", method, path, body, status, want)
+		}
+	}
+
+	// Roles can only hold permissions the manager holds
+	request(http.MethodPost, "/roles", `{"name":"settings","permissions":["settings:manage"]}`, fiber.StatusForbidden)
+	request(http.MethodPost, "/roles", `{"name":"players","permissions":["players:write"]}`, fiber.StatusCreated)
+
+	// New admins get a role the manager holds; the default permissions are
+	// more than this manager has
+	newAdmin := func(name string, roleID *uint) string {
+		body := fmt.Sprintf(`{"username":%q,"email":"%s@example.com","password":"secret1"`, name, name)
+		if roleID != nil {
+			body += fmt.Sprintf(`,"role_id":%d`, *roleID)
+		}
+		return body + "}"
+	}
+	request(http.MethodPost, "/admins", newAdmin("a", owner.RoleID), fiber.StatusForbidden)
+	request(http.MethodPost, "/admins", newAdmin("b", nil), fiber.StatusForbidden)
+	request(http.MethodPost, "/admins", newAdmin("c", junior.RoleID), fiber.StatusCreated)
+
+	// Roles are only assigned to admins the manager can manage, and only
+	// when the manager holds them
+	assign := func(admin *models.Admin, roleID *uint, want int) {
+		t.Helper()
+		body := `{"role_id":null}`
+		if roleID != nil {
+			body = fmt.Sprintf(`{"role_id":%d}`, *roleID)
+		}
+		request(http.MethodPut, fmt.Sprintf("/admins/%d/role", admin.ID), body, want)
+	}
+	assign(junior, owner.RoleID, fiber.StatusForbidden)
+	assign(junior, nil, fiber.StatusForbidden)
+	assign(&plain, junior.RoleID, fiber.StatusForbidden)
+	assign(&super, junior.RoleID, fiber.StatusForbidden)
+	assign(owner, junior.RoleID, fiber.StatusForbidden)
+	assign(junior, manager.RoleID, fiber.StatusOK)
+
+	sessions := func(admin *models.Admin) string { return fmt.Sprintf("/admins/%d/sessions", admin.ID) }
+	request(http.MethodDelete, sessions(junior), "", fiber.StatusOK)
+	request(http.MethodDelete, sessions(&plain), "", fiber.StatusForbidden)
+	request(http.MethodDelete, sessions(&super), "", fiber.StatusForbidden)
+
+	// Super admins hold every permission and manage everyone
+	app = roleTestApp(&super)
+	request(http.MethodDelete, sessions(&super), "", fiber.StatusOK)
+	request(http.MethodDelete, sessions(owner), "", fiber.StatusOK)
+	request(http.MethodPost, "/admins", newAdmin("d", owner.RoleID), fiber.StatusCreated)
+}
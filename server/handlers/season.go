@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+
+	"stone-paper-scissors/models"
+	"stone-paper-scissors/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// seasonError maps a season service error to an HTTP response
+func seasonError(c *fiber.Ctx, err error, fallback string) error {
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": validationErr.Message,
+		})
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Season not found",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": fallback,
+	})
+}
+
+// seasonFromQuery resolves the optional ?season=<id|current> query parameter,
+// returning nil for the all-time scope
+func seasonFromQuery(c *fiber.Ctx) (*models.Season, error) {
+	ref := c.Query("season")
+	if ref == "" {
+		return nil, nil
+	}
+	return services.ResolveSeason(ref)
+}
+
+// seasonScope returns the season ID selected by ?season=, or nil for all-time
+func seasonScope(c *fiber.Ctx) (*uint, error) {
+	season, err := seasonFromQuery(c)
+	if err != nil || season == nil {
+		return nil, err
+	}
+	return &season.ID, nil
+}
+
+// CreateSeason creates a pending season
+func CreateSeason(c *fiber.Ctx) error {
+	var req models.CreateSeasonRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	admin := c.Locals("admin").(*models.Admin)
+
+	season, err := services.CreateSeason(req, &admin.ID)
+	if err != nil {
+		return seasonError(c, err, "Failed to create season")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Season created successfully",
+		"season":  season,
+	})
+}
+
+// GetSeasons lists all seasons
+func GetSeasons(c *fiber.Ctx) error {
+	seasons, err := services.ListSeasons()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch seasons",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"seasons": seasons,
+		"total":   len(seasons),
+	})
+}
+
+// GetSeason returns a season by ID, or the active season for /seasons/current
+func GetSeason(c *fiber.Ctx) error {
+	season, err := services.ResolveSeason(c.Params("id"))
+	if err != nil {
+		return seasonError(c, err, "Failed to fetch season")
+	}
+
+	return c.JSON(season)
+}
+
+// StartSeason starts a season and soft resets the season ratings
+func StartSeason(c *fiber.Ctx) error {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid season ID",
+		})
+	}
+
+	season, err := services.StartSeason(id)
+	if err != nil {
+		return seasonError(c, err, "Failed to start season")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Season started successfully",
+		"season":  season,
+	})
+}
+
+// EndSeason ends the active season and crowns its champion
+func EndSeason(c *fiber.Ctx) error {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid season ID",
+		})
+	}
+
+	season, err := services.EndSeason(id)
+	if err != nil {
+		return seasonError(c, err, "Failed to end season")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Season ended successfully",
+		"season":  season,
+	})
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"strconv"
+
+	"stone-paper-scissors/models"
+	"stone-paper-scissors/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetSettings returns the system-wide settings (requires settings:manage)
+func GetSettings(c *fiber.Ctx) error {
+	required, err := services.TwoFactorRequired()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch settings",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"require_2fa": required,
+	})
+}
+
+// UpdateSettings changes system-wide settings (requires settings:manage)
+func UpdateSettings(c *fiber.Ctx) error {
+	var req models.UpdateSettingsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.RequireTwoFactor != nil {
+		if err := services.SetSetting(models.SettingRequireTwoFactor, strconv.FormatBool(*req.RequireTwoFactor)); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to update settings",
+			})
+		}
+	}
+
+	return GetSettings(c)
+}
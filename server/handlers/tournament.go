@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"stone-paper-scissors/models"
+	"stone-paper-scissors/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// tournamentError maps a tournament service error to an HTTP response
+func tournamentError(c *fiber.Ctx, err error, fallback string) error {
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": validationErr.Message,
+		})
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Tournament or tournament match not found",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": fallback,
+	})
+}
+
+// parseID parses a numeric route parameter
+func parseID(c *fiber.Ctx, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Params(name), 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// CreateTournament creates a tournament with a field of players
+func CreateTournament(c *fiber.Ctx) error {
+	var req models.CreateTournamentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	admin := c.Locals("admin").(*models.Admin)
+
+	tournament, err := services.CreateTournament(req, &admin.ID)
+	if err != nil {
+		return tournamentError(c, err, "Failed to create tournament")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":    "Tournament created successfully",
+		"tournament": tournament,
+	})
+}
+
+// GetTournaments lists all tournaments
+func GetTournaments(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	offset := c.QueryInt("offset", 0)
+
+	tournaments, total, err := services.ListTournaments(limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch tournaments",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"tournaments": tournaments,
+		"total":       total,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
+// GetTournament returns a tournament with its participants and bracket
+func GetTournament(c *fiber.Ctx) error {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid tournament ID",
+		})
+	}
+
+	tournament, err := services.GetTournament(id)
+	if err != nil {
+		return tournamentError(c, err, "Failed to fetch tournament")
+	}
+
+	return c.JSON(tournament)
+}
+
+// StartTournament seeds the field and generates the bracket
+func StartTournament(c *fiber.Ctx) error {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid tournament ID",
+		})
+	}
+
+	tournament, err := services.StartTournament(id)
+	if err != nil {
+		return tournamentError(c, err, "Failed to start tournament")
+	}
+
+	return c.JSON(fiber.Map{
+		"message":    "Tournament started successfully",
+		"tournament": tournament,
+	})
+}
+
+// SubmitTournamentResult records the result of a tournament match and advances the winner
+func SubmitTournamentResult(c *fiber.Ctx) error {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid tournament ID",
+		})
+	}
+	matchID, ok := parseID(c, "matchId")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid tournament match ID",
+		})
+	}
+
+	var req models.TournamentResultRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	admin := c.Locals("admin").(*models.Admin)
+
+	slot, err := services.RecordTournamentResult(id, matchID, req, &admin.ID, apiKeyID(c))
+	if err != nil {
+		return tournamentError(c, err, "Failed to record tournament result")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":          "Tournament result recorded successfully",
+		"tournament_match": slot,
+	})
+}
+
+// GetTournamentStandings returns the standings table of a tournament
+func GetTournamentStandings(c *fiber.Ctx) error {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid tournament ID",
+		})
+	}
+
+	standings, err := services.GetTournamentStandings(id)
+	if err != nil {
+		return tournamentError(c, err, "Failed to fetch standings")
+	}
+
+	return c.JSON(fiber.Map{
+		"standings": standings,
+		"total":     len(standings),
+	})
+}
+
+// GetTournamentFixtures returns the fixture list of a tournament (?round=N&status=played|pending)
+func GetTournamentFixtures(c *fiber.Ctx) error {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid tournament ID",
+		})
+	}
+
+	fixtures, err := services.GetTournamentFixtures(id, c.QueryInt("round", 0), c.Query("status"))
+	if err != nil {
+		return tournamentError(c, err, "Failed to fetch fixtures")
+	}
+
+	played := 0
+	for _, fixture := range fixtures {
+		if fixture.CompletedAt != nil {
+			played++
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"fixtures": fixtures,
+		"total":    len(fixtures),
+		"played":   played,
+		"pending":  len(fixtures) - played,
+	})
+}
+
+// GetTournamentBracket returns the bracket tree of an elimination tournament
+func GetTournamentBracket(c *fiber.Ctx) error {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid tournament ID",
+		})
+	}
+
+	bracket, err := services.GetTournamentBracket(id)
+	if err != nil {
+		return tournamentError(c, err, "Failed to fetch bracket")
+	}
+
+	return c.JSON(bracket)
+}
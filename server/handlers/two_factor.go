@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"errors"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+	"stone-paper-scissors/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupTwoFactor starts TOTP enrollment for the current admin and returns the
+// secret with its otpauth:// URI, to be shown as a QR code
+func SetupTwoFactor(c *fiber.Ctx) error {
+	admin := c.Locals("admin").(*models.Admin)
+
+	secret, err := services.BeginTOTPSetup(admin)
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to start two-factor setup",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":     "Scan the QR code with an authenticator app, then confirm with a code",
+		"secret":      secret,
+		"otpauth_uri": services.TOTPProvisioningURI(admin.Username, secret),
+	})
+}
+
+// EnableTwoFactor confirms TOTP enrollment with a code and returns the
+// recovery codes, which are only shown once
+func EnableTwoFactor(c *fiber.Ctx) error {
+	admin := c.Locals("admin").(*models.Admin)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	codes, err := services.EnableTOTP(admin, req.Code)
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to enable two-factor authentication",
+		})
+	}
+
+	services.RecordAudit(config.DB, models.AuditEvent{
+		Type:     models.AuditTwoFactorEnabled,
+		AdminID:  &admin.ID,
+		ActorID:  &admin.ID,
+		Username: admin.Username,
+		IP:       c.IP(),
+	})
+
+	return c.JSON(fiber.Map{
+		"message":        "Two-factor authentication enabled. Store the recovery codes somewhere safe.",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor turns two-factor authentication off for the current admin,
+// given their password and a TOTP or recovery code
+func DisableTwoFactor(c *fiber.Ctx) error {
+	admin := c.Locals("admin").(*models.Admin)
+
+	var req models.DisableTwoFactorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Password == "" || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Password and code are required",
+		})
+	}
+
+	if !admin.TOTPEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Two-factor authentication is not enabled",
+		})
+	}
+
+	required, err := services.TwoFactorRequired()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch settings",
+		})
+	}
+	if required {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Two-factor authentication is required for all admins",
+		})
+	}
+
+	if !CheckPasswordHash(req.Password, admin.PasswordHash) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Password is incorrect",
+		})
+	}
+	if _, err := services.VerifySecondFactor(admin, req.Code); err != nil {
+		if errors.Is(err, services.ErrInvalidTwoFactorCode) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid code",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to verify code",
+		})
+	}
+
+	if err := services.DisableTOTP(config.DB, admin.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to disable two-factor authentication",
+		})
+	}
+
+	services.RecordAudit(config.DB, models.AuditEvent{
+		Type:     models.AuditTwoFactorDisabled,
+		AdminID:  &admin.ID,
+		ActorID:  &admin.ID,
+		Username: admin.Username,
+		IP:       c.IP(),
+	})
+
+	return c.JSON(fiber.Map{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the current admin,
+// given a TOTP or recovery code
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	admin := c.Locals("admin").(*models.Admin)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	codes, err := services.RegenerateRecoveryCodes(admin, req.Code)
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if errors.Is(err, services.ErrInvalidTwoFactorCode) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid code",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate recovery codes",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "New recovery codes generated, the old ones no longer work",
+		"recovery_codes": codes,
+	})
+}
+
+// GetTwoFactorStatus returns whether the current admin uses two-factor
+// authentication and how many recovery codes are left
+func GetTwoFactorStatus(c *fiber.Ctx) error {
+	admin := c.Locals("admin").(*models.Admin)
+
+	required, err := services.TwoFactorRequired()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch settings",
+		})
+	}
+	remaining, err := services.RemainingRecoveryCodes(admin.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to count recovery codes",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"enabled":                  admin.TOTPEnabled,
+		"required":                 required,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// LoginTwoFactor completes a login with the challenge token returned by
+// Login and a TOTP or recovery code
+func LoginTwoFactor(c *fiber.Ctx) error {
+	var req models.TwoFactorLoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.ChallengeToken == "" || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Challenge token and code are required",
+		})
+	}
+
+	claims, err := ParseToken(req.ChallengeToken)
+	if err != nil || (*claims)["purpose"] != "2fa" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid or expired challenge, log in again",
+		})
+	}
+
+	adminID, _ := (*claims)["id"].(float64)
+	var admin models.Admin
+	if result := config.DB.First(&admin, uint(adminID)); result.Error != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Admin not found",
+		})
+	}
+
+	// A challenge completes one login, and none once the password changed
+	tokenID, _ := (*claims)["jti"].(string)
+	stamp, _ := (*claims)["pwd"].(string)
+	challenge, err := services.PendingLoginChallenge(tokenID, admin.ID)
+	if errors.Is(err, services.ErrInvalidLoginChallenge) || (err == nil && stamp != passwordStamp(&admin)) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid or expired challenge, log in again",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check challenge",
+		})
+	}
+
+	// Wrong codes count as failed logins
+	ip := c.IP()
+	if refused, err := checkLoginThrottle(c, admin.Username, ip); refused {
+		return err
+	}
+	if _, err := services.VerifySecondFactor(&admin, req.Code); err != nil {
+		if errors.Is(err, services.ErrInvalidTwoFactorCode) {
+			if err := services.RecordLoginFailure(admin.Username, ip, &admin.ID, "wrong two-factor code"); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to record login attempt",
+				})
+			}
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid two-factor code",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to verify code",
+		})
+	}
+
+	err = services.UseLoginChallenge(challenge)
+	if errors.Is(err, services.ErrInvalidLoginChallenge) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid or expired challenge, log in again",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check challenge",
+		})
+	}
+
+	return completeLogin(c, &admin)
+}
+
+// ResetAdminTwoFactor turns two-factor authentication off for an admin who
+// lost their authenticator and recovery codes (requires admins:manage)
+func ResetAdminTwoFactor(c *fiber.Ctx) error {
+	currentAdmin := c.Locals("admin").(*models.Admin)
+
+	id, ok := parseID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid admin ID",
+		})
+	}
+
+	var admin models.Admin
+	if result := config.DB.First(&admin, id); result.Error != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Admin not found",
+		})
+	}
+	if ok, err := checkManageable(c, &admin); !ok {
+		return err
+	}
+
+	if err := services.DisableTOTP(config.DB, admin.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to reset two-factor authentication",
+		})
+	}
+
+	services.RecordAudit(config.DB, models.AuditEvent{
+		Type:     models.AuditTwoFactorDisabled,
+		AdminID:  &admin.ID,
+		ActorID:  &currentAdmin.ID,
+		Username: admin.Username,
+		IP:       c.IP(),
+		Detail:   "reset by " + currentAdmin.Username,
+	})
+
+	return c.JSON(fiber.Map{
+		"message": "Two-factor authentication reset, the admin can set it up again",
+	})
+}
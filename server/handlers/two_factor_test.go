@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+	"stone-paper-scissors/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// loginTwoFactor completes a login with a challenge token and code
+func loginTwoFactor(t *testing.T, app *fiber.App, challenge, code string) int {
+	t.Helper()
+	body := fmt.Sprintf(`{"challenge_token":%q,"code":%q}`, challenge, code)
+	req := httptest.NewRequest(http.MethodPost, "/auth/login/2fa", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestLoginChallengeIsSingleUseAndVoidedByPasswordChange(t *testing.T) {
+	setupTestDB(t)
+
+	admin := models.Admin{Username: "root", Email: "root@example.com", PasswordHash: "x", Role: models.RoleSuperAdmin, TOTPEnabled: true}
+	if err := config.DB.Create(&admin).Error; err != nil {
+		t.Fatal(err)
+	}
+	codes := []string{"aaaaa-aaaaa", "bbbbb-bbbbb", "ccccc-ccccc"}
+	for _, code := range codes {
+		row := models.RecoveryCode{AdminID: admin.ID, CodeHash: services.HashToken(strings.ReplaceAll(code, "-", ""))}
+		if err := config.DB.Create(&row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	app := fiber.New()
+	app.Post("/auth/login/2fa", LoginTwoFactor)
+
+	challenge, err := GenerateChallengeToken(&admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := loginTwoFactor(t, app, challenge, codes[0]); status != fiber.StatusOK {
+		t.Fatalf("first login: got status %d, want 200", status)
+	}
+
+	// Replaying the challenge fails without using up the code
+	if status := loginTwoFactor(t, app, challenge, codes[1]); status != fiber.StatusUnauthorized {
+		t.Errorf("replayed challenge: got status %d, want 401", status)
+	}
+	if remaining, err := services.RemainingRecoveryCodes(admin.ID); err != nil || remaining != 2 {
+		t.Errorf("got %d recovery codes left (%v), want 2", remaining, err)
+	}
+
+	// A challenge issued before the password changed no longer works
+	challenge, err = GenerateChallengeToken(&admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := services.ChangePassword(admin.ID, "y"); err != nil {
+		t.Fatal(err)
+	}
+	if status := loginTwoFactor(t, app, challenge, codes[1]); status != fiber.StatusUnauthorized {
+		t.Errorf("challenge from before the password change: got status %d, want 401", status)
+	}
+
+	if err := config.DB.First(&admin, admin.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	challenge, err = GenerateChallengeToken(&admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := loginTwoFactor(t, app, challenge, codes[1]); status != fiber.StatusOK {
+		t.Errorf("challenge after the password change: got status %d, want 200", status)
+	}
+}
@@ -3,10 +3,12 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"stone-paper-scissors/config"
-	"stone-paper-scissors/models"
+	"stone-paper-scissors/migrations"
 	"stone-paper-scissors/routes"
+	"stone-paper-scissors/services"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -18,12 +20,22 @@ func main() {
 	// Connect to database
 	config.ConnectDatabase()
 
-	// Auto migrate models
-	err := config.DB.AutoMigrate(&models.Player{}, &models.Match{}, &models.Admin{}, &models.ChampionshipReign{})
-	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+	// Refuse to serve a database that is not at this build's schema version,
+	// unless pending migrations are to be applied on start
+	if os.Getenv("AUTO_MIGRATE") == "true" {
+		if _, err := migrations.Up(config.DB); err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
 	}
-	log.Println("Database migrated successfully!")
+	if err := migrations.Check(config.DB); err != nil {
+		log.Fatalf("%v (run `go run ./cmd/migrate up` or start with AUTO_MIGRATE=true)", err)
+	}
+	log.Printf("Database schema at version %d", migrations.Latest())
+	log.Printf("Rating system: %s", services.Rating().Name())
+
+	// Expired idempotency keys and refresh tokens are cleaned up hourly
+	services.StartIdempotencyCleanup(time.Hour)
+	services.StartRefreshTokenCleanup(time.Hour)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -35,7 +47,7 @@ func main() {
 	app.Use(recover.New())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, Idempotency-Key, X-API-Key",
 		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
 	}))
 
@@ -49,11 +61,13 @@ func main() {
 				"players":     "GET, POST /api/v1/players",
 				"player":      "GET, PUT, DELETE /api/v1/players/:id",
 				"matches":     "GET, POST /api/v1/matches",
-				"match":       "GET /api/v1/matches/:id",
+				"match":       "GET, PUT, DELETE /api/v1/matches/:id",
 				"leaderboard": "GET /api/v1/leaderboard",
 				"top_players": "GET /api/v1/leaderboard/top?n=10",
 				"player_rank": "GET /api/v1/leaderboard/rank/:id",
 				"predict":     "GET /api/v1/leaderboard/predict?player1_id=1&player2_id=2",
+				"tournaments": "GET, POST /api/v1/tournaments",
+				"seasons":     "GET, POST /api/v1/seasons",
 			},
 		})
 	})
@@ -0,0 +1,267 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Snapshots of the tables as AutoMigrate created them before migrations
+// were introduced. Databases created by AutoMigrate already have these
+// tables, so the baseline leaves them as they are.
+
+type baselineAdmin struct {
+	ID           uint   `gorm:"primaryKey"`
+	Username     string `gorm:"uniqueIndex;not null"`
+	Email        string `gorm:"uniqueIndex;not null"`
+	PasswordHash string `gorm:"not null"`
+	Role         string `gorm:"not null;default:'admin'"`
+	CreatedByID  *uint
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
+
+	CreatedBy *baselineAdmin `gorm:"foreignKey:CreatedByID"`
+}
+
+func (baselineAdmin) TableName() string { return "admins" }
+
+type baselinePlayer struct {
+	ID               uint    `gorm:"primaryKey"`
+	Name             string  `gorm:"uniqueIndex;not null"`
+	Elo              float64 `gorm:"default:1000"`
+	RatingDeviation  float64 `gorm:"default:350"`
+	RatingVolatility float64 `gorm:"default:0.06"`
+	MatchesWon       int     `gorm:"default:0"`
+	MatchesLost      int     `gorm:"default:0"`
+	MatchesDrawn     int     `gorm:"default:0"`
+	TotalMatches     int     `gorm:"default:0"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	DeletedAt        gorm.DeletedAt `gorm:"index"`
+}
+
+func (baselinePlayer) TableName() string { return "players" }
+
+type baselineMatch struct {
+	ID               uint `gorm:"primaryKey"`
+	Player1ID        uint `gorm:"not null"`
+	Player2ID        uint `gorm:"not null"`
+	Player1Score     int  `gorm:"not null"`
+	Player2Score     int  `gorm:"not null"`
+	WinnerID         *uint
+	Player1EloChange float64
+	Player2EloChange float64
+	Player1EloBefore float64
+	Player2EloBefore float64
+	Player1EloAfter  float64
+	Player2EloAfter  float64
+	CreatedByAdminID *uint
+	SeasonID         *uint `gorm:"index"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	DeletedAt        gorm.DeletedAt `gorm:"index"`
+
+	Player1        baselinePlayer       `gorm:"foreignKey:Player1ID"`
+	Player2        baselinePlayer       `gorm:"foreignKey:Player2ID"`
+	CreatedByAdmin *baselineAdmin       `gorm:"foreignKey:CreatedByAdminID"`
+	Rounds         []baselineMatchRound `gorm:"foreignKey:MatchID"`
+}
+
+func (baselineMatch) TableName() string { return "matches" }
+
+type baselineMatchRound struct {
+	ID           uint   `gorm:"primaryKey"`
+	MatchID      uint   `gorm:"not null;index"`
+	RoundNumber  int    `gorm:"not null"`
+	Player1Throw string `gorm:"not null"`
+	Player2Throw string `gorm:"not null"`
+	Outcome      string `gorm:"not null"`
+	CreatedAt    time.Time
+}
+
+func (baselineMatchRound) TableName() string { return "match_rounds" }
+
+type baselineChampionshipReign struct {
+	ID        uint      `gorm:"primaryKey"`
+	PlayerID  uint      `gorm:"not null;index"`
+	SeasonID  *uint     `gorm:"index"`
+	StartedAt time.Time `gorm:"not null"`
+	EndedAt   *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	Player baselinePlayer `gorm:"foreignKey:PlayerID"`
+}
+
+func (baselineChampionshipReign) TableName() string { return "championship_reigns" }
+
+type baselineTournament struct {
+	ID               uint   `gorm:"primaryKey"`
+	Name             string `gorm:"not null"`
+	Format           string `gorm:"not null"`
+	Status           string `gorm:"not null;default:'pending'"`
+	TotalRounds      int    `gorm:"default:0"`
+	DoubleRoundRobin bool   `gorm:"default:false"`
+	BracketReset     bool   `gorm:"default:false"`
+	WinnerID         *uint
+	CreatedByAdminID *uint
+	StartedAt        *time.Time
+	CompletedAt      *time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	DeletedAt        gorm.DeletedAt `gorm:"index"`
+
+	Winner       *baselinePlayer                 `gorm:"foreignKey:WinnerID"`
+	Participants []baselineTournamentParticipant `gorm:"foreignKey:TournamentID"`
+	Matches      []baselineTournamentMatch       `gorm:"foreignKey:TournamentID"`
+}
+
+func (baselineTournament) TableName() string { return "tournaments" }
+
+type baselineTournamentParticipant struct {
+	ID           uint `gorm:"primaryKey"`
+	TournamentID uint `gorm:"not null;index"`
+	PlayerID     uint `gorm:"not null;index"`
+	Seed         int
+	SeedElo      float64
+	CreatedAt    time.Time
+
+	Player baselinePlayer `gorm:"foreignKey:PlayerID"`
+}
+
+func (baselineTournamentParticipant) TableName() string { return "tournament_participants" }
+
+type baselineTournamentMatch struct {
+	ID               uint `gorm:"primaryKey"`
+	TournamentID     uint `gorm:"not null;index"`
+	Bracket          string
+	Round            int `gorm:"not null"`
+	Position         int `gorm:"not null"`
+	Player1ID        *uint
+	Player2ID        *uint
+	WinnerID         *uint
+	MatchID          *uint
+	NextMatchID      *uint
+	NextSlot         int
+	LoserNextMatchID *uint
+	LoserNextSlot    int
+	IsBye            bool `gorm:"default:false"`
+	CompletedAt      *time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+
+	Player1 *baselinePlayer `gorm:"foreignKey:Player1ID"`
+	Player2 *baselinePlayer `gorm:"foreignKey:Player2ID"`
+	Match   *baselineMatch  `gorm:"foreignKey:MatchID"`
+}
+
+func (baselineTournamentMatch) TableName() string { return "tournament_matches" }
+
+type baselineSeason struct {
+	ID               uint    `gorm:"primaryKey"`
+	Name             string  `gorm:"uniqueIndex;not null"`
+	Status           string  `gorm:"not null;default:'pending'"`
+	ResetPercent     float64 `gorm:"not null"`
+	ChampionID       *uint
+	CreatedByAdminID *uint
+	StartedAt        *time.Time
+	EndedAt          *time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	DeletedAt        gorm.DeletedAt `gorm:"index"`
+
+	Champion *baselinePlayer `gorm:"foreignKey:ChampionID"`
+}
+
+func (baselineSeason) TableName() string { return "seasons" }
+
+type baselineSeasonRating struct {
+	ID               uint `gorm:"primaryKey"`
+	SeasonID         uint `gorm:"not null;uniqueIndex:idx_season_player"`
+	PlayerID         uint `gorm:"not null;uniqueIndex:idx_season_player;index"`
+	StartingElo      float64
+	Elo              float64
+	RatingDeviation  float64
+	RatingVolatility float64
+	MatchesWon       int `gorm:"default:0"`
+	MatchesLost      int `gorm:"default:0"`
+	MatchesDrawn     int `gorm:"default:0"`
+	TotalMatches     int `gorm:"default:0"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+
+	Player baselinePlayer `gorm:"foreignKey:PlayerID"`
+}
+
+func (baselineSeasonRating) TableName() string { return "season_ratings" }
+
+type baselineIdempotencyKey struct {
+	ID           uint      `gorm:"primaryKey"`
+	AdminID      uint      `gorm:"not null;uniqueIndex:idx_idempotency_admin_key"`
+	Key          string    `gorm:"column:idempotency_key;not null;size:255;uniqueIndex:idx_idempotency_admin_key"`
+	RequestHash  string    `gorm:"not null;size:64"`
+	StatusCode   int       `gorm:"default:0"`
+	ResponseBody string    `gorm:"type:text"`
+	ExpiresAt    time.Time `gorm:"not null;index"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (baselineIdempotencyKey) TableName() string { return "idempotency_keys" }
+
+type baselineMatchEdit struct {
+	ID                   uint `gorm:"primaryKey"`
+	MatchID              uint `gorm:"not null;index"`
+	EditedByAdminID      uint `gorm:"not null"`
+	Reason               string
+	PreviousPlayer1Score int
+	PreviousPlayer2Score int
+	PreviousWinnerID     *uint
+	PreviousRounds       string `gorm:"type:text"`
+	Player1Score         int
+	Player2Score         int
+	WinnerID             *uint
+	Rounds               string `gorm:"type:text"`
+	PlayersUpdated       int
+	MatchesUpdated       int
+	CreatedAt            time.Time
+
+	EditedByAdmin *baselineAdmin `gorm:"foreignKey:EditedByAdminID"`
+}
+
+func (baselineMatchEdit) TableName() string { return "match_edits" }
+
+// baselineTables are created in this order and dropped in reverse
+var baselineTables = []interface{}{
+	&baselineAdmin{},
+	&baselinePlayer{},
+	&baselineMatch{},
+	&baselineMatchRound{},
+	&baselineChampionshipReign{},
+	&baselineTournament{},
+	&baselineTournamentParticipant{},
+	&baselineTournamentMatch{},
+	&baselineSeason{},
+	&baselineSeasonRating{},
+	&baselineIdempotencyKey{},
+	&baselineMatchEdit{},
+}
+
+// baseline creates the schema as it was before versioned migrations
+var baseline = Migration{
+	Version: 1,
+	Name:    "baseline",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(baselineTables...)
+	},
+	Down: func(tx *gorm.DB) error {
+		for i := len(baselineTables) - 1; i >= 0; i-- {
+			if err := tx.Migrator().DropTable(baselineTables[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type playedAtMatch struct {
+	PlayedAt time.Time `gorm:"index"`
+}
+
+func (playedAtMatch) TableName() string { return "matches" }
+
+// matchPlayedAt separates when a match was played from when it was entered,
+// dating the existing matches by the time they were entered
+var matchPlayedAt = Migration{
+	Version: 2,
+	Name:    "match_played_at",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&playedAtMatch{}); err != nil {
+			return err
+		}
+		return tx.Table("matches").
+			Where("played_at IS NULL").
+			Update("played_at", gorm.Expr("created_at")).Error
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropIndex(&playedAtMatch{}, "PlayedAt"); err != nil {
+			return err
+		}
+		return dropColumn(tx, &playedAtMatch{}, "PlayedAt")
+	},
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type refreshToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	AdminID   uint      `gorm:"not null;index"`
+	FamilyID  string    `gorm:"not null;size:64;index"`
+	TokenHash string    `gorm:"not null;size:64;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	RotatedAt *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+func (refreshToken) TableName() string { return "refresh_tokens" }
+
+// refreshTokens stores the hashed refresh tokens of admin sessions
+var refreshTokens = Migration{
+	Version: 3,
+	Name:    "refresh_tokens",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&refreshToken{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&refreshToken{})
+	},
+}
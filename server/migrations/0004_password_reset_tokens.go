@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type passwordResetToken struct {
+	ID               uint      `gorm:"primaryKey"`
+	AdminID          uint      `gorm:"not null;index"`
+	TokenHash        string    `gorm:"not null;size:64;uniqueIndex"`
+	ExpiresAt        time.Time `gorm:"not null"`
+	UsedAt           *time.Time
+	CreatedByAdminID *uint
+	CreatedAt        time.Time
+}
+
+func (passwordResetToken) TableName() string { return "password_reset_tokens" }
+
+// passwordResetTokens stores the hashed one-time tokens of password resets
+var passwordResetTokens = Migration{
+	Version: 4,
+	Name:    "password_reset_tokens",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&passwordResetToken{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&passwordResetToken{})
+	},
+}
@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type loginThrottle struct {
+	ID            uint   `gorm:"primaryKey"`
+	Kind          string `gorm:"not null;size:16;uniqueIndex:idx_login_throttle_key"`
+	Key           string `gorm:"column:throttle_key;not null;size:255;uniqueIndex:idx_login_throttle_key"`
+	Failures      int    `gorm:"not null;default:0"`
+	LastFailureAt time.Time
+	LockedUntil   *time.Time `gorm:"index"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (loginThrottle) TableName() string { return "login_throttles" }
+
+type auditEvent struct {
+	ID        uint   `gorm:"primaryKey"`
+	Type      string `gorm:"not null;size:64;index"`
+	AdminID   *uint  `gorm:"index"`
+	ActorID   *uint
+	Username  string `gorm:"size:255"`
+	IP        string `gorm:"size:64"`
+	Detail    string
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (auditEvent) TableName() string { return "audit_events" }
+
+// loginThrottling counts failed logins per username and IP address and
+// keeps an audit log of them
+var loginThrottling = Migration{
+	Version: 5,
+	Name:    "login_throttling",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&loginThrottle{}, &auditEvent{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&auditEvent{}, &loginThrottle{})
+	},
+}
@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type twoFactorAdmin struct {
+	TOTPSecret   string `gorm:"size:64"`
+	TOTPEnabled  bool   `gorm:"default:false"`
+	TOTPLastStep int64  `gorm:"default:0"`
+}
+
+func (twoFactorAdmin) TableName() string { return "admins" }
+
+type recoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	AdminID   uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null;size:64;uniqueIndex"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (recoveryCode) TableName() string { return "recovery_codes" }
+
+type setting struct {
+	Key       string `gorm:"primaryKey;size:64"`
+	Value     string `gorm:"not null"`
+	UpdatedAt time.Time
+}
+
+func (setting) TableName() string { return "settings" }
+
+// twoFactor adds TOTP secrets to admins, their recovery codes and the
+// settings table holding whether two-factor authentication is required
+var twoFactor = Migration{
+	Version: 6,
+	Name:    "two_factor",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&twoFactorAdmin{}, &recoveryCode{}, &setting{})
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropTable(&setting{}, &recoveryCode{}); err != nil {
+			return err
+		}
+		for _, column := range []string{"TOTPLastStep", "TOTPEnabled", "TOTPSecret"} {
+			if err := dropColumn(tx, &twoFactorAdmin{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
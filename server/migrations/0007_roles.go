@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type rolesAdmin struct {
+	RoleID *uint `gorm:"index"`
+}
+
+func (rolesAdmin) TableName() string { return "admins" }
+
+type role struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        string `gorm:"uniqueIndex;not null;size:64"`
+	Description string
+	Permissions string `gorm:"type:text;not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (role) TableName() string { return "roles" }
+
+// roles adds custom roles with fine-grained permissions and the role of each admin
+var roles = Migration{
+	Version: 7,
+	Name:    "roles",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&role{}, &rolesAdmin{})
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropIndex(&rolesAdmin{}, "RoleID"); err != nil {
+			return err
+		}
+		if err := dropColumn(tx, &rolesAdmin{}, "RoleID"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable(&role{})
+	},
+}
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type apiKey struct {
+	ID         uint   `gorm:"primaryKey"`
+	AdminID    uint   `gorm:"not null;index"`
+	Name       string `gorm:"not null;size:100"`
+	Prefix     string `gorm:"not null;size:16"`
+	KeyHash    string `gorm:"not null;size:64;uniqueIndex"`
+	Scopes     string `gorm:"type:text;not null"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	LastUsedIP string `gorm:"size:64"`
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+func (apiKey) TableName() string { return "api_keys" }
+
+type apiKeyMatch struct {
+	CreatedByAPIKeyID *uint `gorm:"index"`
+}
+
+func (apiKeyMatch) TableName() string { return "matches" }
+
+// apiKeys adds API keys and records the key each match was submitted with
+var apiKeys = Migration{
+	Version: 8,
+	Name:    "api_keys",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&apiKey{}, &apiKeyMatch{})
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropIndex(&apiKeyMatch{}, "CreatedByAPIKeyID"); err != nil {
+			return err
+		}
+		if err := dropColumn(tx, &apiKeyMatch{}, "CreatedByAPIKeyID"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable(&apiKey{})
+	},
+}
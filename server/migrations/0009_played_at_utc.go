@@ -0,0 +1,101 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type utcMatch struct {
+	ID       uint
+	PlayedAt time.Time
+}
+
+func (utcMatch) TableName() string { return "matches" }
+
+type utcSeason struct {
+	ID        uint
+	StartedAt *time.Time
+	EndedAt   *time.Time
+}
+
+func (utcSeason) TableName() string { return "seasons" }
+
+type utcReign struct {
+	ID        uint
+	StartedAt time.Time
+	EndedAt   *time.Time
+}
+
+func (utcReign) TableName() string { return "championship_reigns" }
+
+// playedAtUTC stores the times matches were played, and the times seasons
+// and championship reigns started and ended, in UTC. SQLite compares them as
+// text, so the times in local time (e.g. matches dated by their created_at in
+// match_played_at) were ordered wrongly against those in UTC.
+var playedAtUTC = Migration{
+	Version: 9,
+	Name:    "played_at_utc",
+	Up: func(tx *gorm.DB) error {
+		if tx.Dialector.Name() != "sqlite" {
+			return nil
+		}
+
+		var matches []utcMatch
+		if err := tx.Find(&matches).Error; err != nil {
+			return err
+		}
+		for _, match := range matches {
+			if err := updateUTC(tx, &utcMatch{}, match.ID, map[string]*time.Time{
+				"played_at": &match.PlayedAt,
+			}); err != nil {
+				return err
+			}
+		}
+
+		var seasons []utcSeason
+		if err := tx.Find(&seasons).Error; err != nil {
+			return err
+		}
+		for _, season := range seasons {
+			if err := updateUTC(tx, &utcSeason{}, season.ID, map[string]*time.Time{
+				"started_at": season.StartedAt,
+				"ended_at":   season.EndedAt,
+			}); err != nil {
+				return err
+			}
+		}
+
+		var reigns []utcReign
+		if err := tx.Find(&reigns).Error; err != nil {
+			return err
+		}
+		for _, reign := range reigns {
+			if err := updateUTC(tx, &utcReign{}, reign.ID, map[string]*time.Time{
+				"started_at": &reign.StartedAt,
+				"ended_at":   reign.EndedAt,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		// The times are unchanged, only their offset
+		return nil
+	},
+}
+
+// updateUTC rewrites the columns of a row whose times are not in UTC
+func updateUTC(tx *gorm.DB, model interface{}, id uint, columns map[string]*time.Time) error {
+	updates := make(map[string]interface{})
+	for column, t := range columns {
+		if t != nil && t.Location() != time.UTC {
+			updates[column] = t.UTC()
+		}
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return tx.Model(model).Where("id = ?", id).Updates(updates).Error
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type passwordChangedAdmin struct {
+	PasswordChangedAt *time.Time
+}
+
+func (passwordChangedAdmin) TableName() string { return "admins" }
+
+type loginChallenge struct {
+	ID        uint      `gorm:"primaryKey"`
+	AdminID   uint      `gorm:"not null;index"`
+	TokenID   string    `gorm:"not null;size:64;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (loginChallenge) TableName() string { return "login_challenges" }
+
+// loginChallenges records the second login steps pending for admins with
+// two-factor authentication, so that each challenge token is used once, and
+// when each admin's password last changed, which voids their challenges
+var loginChallenges = Migration{
+	Version: 10,
+	Name:    "login_challenges",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&passwordChangedAdmin{}, &loginChallenge{})
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropTable(&loginChallenge{}); err != nil {
+			return err
+		}
+		return dropColumn(tx, &passwordChangedAdmin{}, "PasswordChangedAt")
+	},
+}
@@ -0,0 +1,273 @@
+// Package migrations versions the database schema. Every change to the
+// schema is a numbered migration with an up and a down step; the versions
+// applied to a database are recorded in the schema_migrations table.
+//
+// Migrations must not use the structs in models, which keep changing: each
+// migration declares snapshots of the tables as they were at that version.
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is a numbered schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// all lists every migration in version order. New migrations are appended.
+var all = []Migration{
+	baseline,
+	matchPlayedAt,
+	refreshTokens,
+	passwordResetTokens,
+	loginThrottling,
+	twoFactor,
+	roles,
+	apiKeys,
+	playedAtUTC,
+	loginChallenges,
+}
+
+// SchemaMigration records a migration applied to the database
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName keeps the table name stable whatever the naming strategy
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status is a migration and whether it has been applied
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// ErrSchemaMismatch is returned by Check when the database is not at the
+// schema version this build expects
+var ErrSchemaMismatch = errors.New("database schema does not match this version")
+
+// Latest returns the schema version this build expects
+func Latest() int {
+	return all[len(all)-1].Version
+}
+
+// find returns the migration with the given version
+func find(version int) *Migration {
+	for i := range all {
+		if all[i].Version == version {
+			return &all[i]
+		}
+	}
+	return nil
+}
+
+// applied returns the migrations recorded in the database, oldest first
+func applied(db *gorm.DB) ([]SchemaMigration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, err
+	}
+	var rows []SchemaMigration
+	err := db.Order("version ASC").Find(&rows).Error
+	return rows, err
+}
+
+// Current returns the version of the latest migration applied to db, 0 for
+// an empty database
+func Current(db *gorm.DB) (int, error) {
+	rows, err := applied(db)
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+	return rows[len(rows)-1].Version, nil
+}
+
+// Statuses lists every known migration and whether it has been applied
+func Statuses(db *gorm.DB) ([]Status, error) {
+	rows, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[int]SchemaMigration, len(rows))
+	for _, row := range rows {
+		done[row.Version] = row
+	}
+
+	statuses := make([]Status, 0, len(all))
+	for _, migration := range all {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := done[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			delete(done, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	// Versions applied by a newer build
+	for _, row := range rows {
+		if _, unknown := done[row.Version]; unknown {
+			appliedAt := row.AppliedAt
+			statuses = append(statuses, Status{Version: row.Version, Name: row.Name, Applied: true, AppliedAt: &appliedAt})
+		}
+	}
+	return statuses, nil
+}
+
+// Check returns ErrSchemaMismatch unless every migration of this build, and
+// no other, has been applied to db
+func Check(db *gorm.DB) error {
+	statuses, err := Statuses(db)
+	if err != nil {
+		return err
+	}
+	current, err := Current(db)
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			return fmt.Errorf("%w: at version %d, migration %d (%s) is pending", ErrSchemaMismatch, current, status.Version, status.Name)
+		}
+		if find(status.Version) == nil {
+			return fmt.Errorf("%w: at version %d, this build only knows up to %d", ErrSchemaMismatch, current, Latest())
+		}
+	}
+	return nil
+}
+
+// Up applies every pending migration and returns the ones it applied
+func Up(db *gorm.DB) ([]Migration, error) {
+	return To(db, Latest())
+}
+
+// Down rolls back the latest applied migration and returns it, or nil when
+// there is nothing to roll back
+func Down(db *gorm.DB) (*Migration, error) {
+	current, err := Current(db)
+	if err != nil || current == 0 {
+		return nil, err
+	}
+	rolledBack, err := To(db, previous(current))
+	if err != nil || len(rolledBack) == 0 {
+		return nil, err
+	}
+	return &rolledBack[0], nil
+}
+
+// previous returns the version before version, 0 for the first one
+func previous(version int) int {
+	prev := 0
+	for _, migration := range all {
+		if migration.Version >= version {
+			break
+		}
+		prev = migration.Version
+	}
+	return prev
+}
+
+// To migrates db up or down to version and returns the migrations it applied
+// or rolled back, in the order they ran
+func To(db *gorm.DB, version int) ([]Migration, error) {
+	if version != 0 && find(version) == nil {
+		return nil, fmt.Errorf("unknown migration version %d", version)
+	}
+	rows, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[int]bool, len(rows))
+	for _, row := range rows {
+		if find(row.Version) == nil {
+			return nil, fmt.Errorf("%w: version %d was applied by a newer build", ErrSchemaMismatch, row.Version)
+		}
+		done[row.Version] = true
+	}
+
+	var ran []Migration
+	// Up, oldest first
+	for _, migration := range all {
+		if migration.Version > version || done[migration.Version] {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Name, err)
+		}
+		ran = append(ran, migration)
+	}
+	// Down, newest first
+	for i := len(all) - 1; i >= 0; i-- {
+		migration := all[i]
+		if migration.Version <= version || !done[migration.Version] {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("rolling back migration %d (%s): %w", migration.Version, migration.Name, err)
+		}
+		ran = append(ran, migration)
+	}
+	return ran, nil
+}
+
+// dropColumn drops the column of field from the table of model. SQLite
+// drops a column by rebuilding the table, which loses its indexes, so those
+// are recreated afterwards; indexes on the column must be dropped first.
+func dropColumn(tx *gorm.DB, model interface{}, field string) error {
+	if tx.Dialector.Name() != "sqlite" {
+		return tx.Migrator().DropColumn(model, field)
+	}
+
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	var indexes []struct {
+		Name string
+		SQL  string
+	}
+	err := tx.Raw("SELECT name, sql FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", stmt.Table).
+		Scan(&indexes).Error
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Migrator().DropColumn(model, field); err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if tx.Migrator().HasIndex(stmt.Table, index.Name) {
+			continue
+		}
+		if err := tx.Exec(index.SQL).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
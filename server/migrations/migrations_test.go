@@ -0,0 +1,215 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB opens an empty SQLite database for the test
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=5000"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// schema describes the tables, columns and indexes of a SQLite database, one
+// line each in a stable order
+func schema(t *testing.T, db *gorm.DB) string {
+	t.Helper()
+	var objects []struct {
+		Type string
+		Name string
+		SQL  string
+	}
+	err := db.Raw(`SELECT type, name, COALESCE(sql, '') AS sql FROM sqlite_master
+		WHERE name NOT IN ('schema_migrations', 'sqlite_sequence') AND name NOT LIKE 'sqlite_autoindex%'`).
+		Scan(&objects).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for _, object := range objects {
+		if object.Type == "index" {
+			lines = append(lines, "index "+object.SQL)
+			continue
+		}
+		var columns []struct {
+			CID       int
+			Name      string
+			Type      string
+			NotNull   bool
+			DfltValue *string
+			PK        int
+		}
+		if err := db.Raw("SELECT * FROM pragma_table_info(?)", object.Name).Scan(&columns).Error; err != nil {
+			t.Fatal(err)
+		}
+		for _, column := range columns {
+			def := "NULL"
+			if column.DfltValue != nil {
+				def = *column.DfltValue
+			}
+			lines = append(lines, fmt.Sprintf("column %s.%s %s not null %v default %s pk %d",
+				object.Name, column.Name, column.Type, column.NotNull, def, column.PK))
+		}
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+func TestUpDownRestoresEverySchema(t *testing.T) {
+	db := openTestDB(t)
+	if err := Check(db); !errors.Is(err, ErrSchemaMismatch) {
+		t.Errorf("empty database: got %v, want a schema mismatch", err)
+	}
+
+	// The schema at every version, migrating up one step at a time
+	schemas := map[int]string{0: schema(t, db)}
+	for _, migration := range all {
+		ran, err := To(db, migration.Version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ran) != 1 || ran[0].Version != migration.Version {
+			t.Fatalf("migrating to %d ran %d migrations", migration.Version, len(ran))
+		}
+		schemas[migration.Version] = schema(t, db)
+	}
+	if err := Check(db); err != nil {
+		t.Errorf("migrated database: %v", err)
+	}
+	if current, err := Current(db); err != nil || current != Latest() {
+		t.Errorf("got version %d, %v, want %d", current, err, Latest())
+	}
+
+	// Rolling back every migration gives back the schema it was applied on
+	for i := len(all) - 1; i >= 0; i-- {
+		rolledBack, err := Down(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rolledBack == nil || rolledBack.Version != all[i].Version {
+			t.Fatalf("got %v rolled back, want %d", rolledBack, all[i].Version)
+		}
+		want := schemas[previous(all[i].Version)]
+		if got := schema(t, db); got != want {
+			t.Errorf("after rolling back %d (%s):\ngot\n%s\nwant\n%s", all[i].Version, all[i].Name, got, want)
+		}
+	}
+	if rolledBack, err := Down(db); rolledBack != nil || err != nil {
+		t.Errorf("empty database: got %v, %v rolled back, want nothing", rolledBack, err)
+	}
+
+	// And migrating up again gives the same schema
+	ran, err := Up(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != len(all) {
+		t.Errorf("got %d migrations applied, want %d", len(ran), len(all))
+	}
+	if got := schema(t, db); got != schemas[Latest()] {
+		t.Errorf("after migrating up again:\ngot\n%s\nwant\n%s", got, schemas[Latest()])
+	}
+	if ran, err := Up(db); len(ran) != 0 || err != nil {
+		t.Errorf("up to date: got %d migrations, %v, want none", len(ran), err)
+	}
+}
+
+func TestCheckRefusesUnknownVersions(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := Up(db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := To(db, Latest()+100); err == nil {
+		t.Error("migrated to an unknown version")
+	}
+
+	// A version applied by a newer build
+	if err := db.Create(&SchemaMigration{Version: Latest() + 1, Name: "future", AppliedAt: time.Now()}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(db); !errors.Is(err, ErrSchemaMismatch) {
+		t.Errorf("newer schema: got %v, want a schema mismatch", err)
+	}
+	if _, err := Down(db); !errors.Is(err, ErrSchemaMismatch) {
+		t.Errorf("rolling back a newer schema: got %v, want a schema mismatch", err)
+	}
+	statuses, err := Statuses(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := statuses[len(statuses)-1]; len(statuses) != len(all)+1 || last.Name != "future" || !last.Applied {
+		t.Errorf("got statuses %+v, want the unknown version listed last", statuses)
+	}
+}
+
+func TestPlayedAtUTC(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := To(db, playedAtUTC.Version-1); err != nil {
+		t.Fatal(err)
+	}
+
+	tokyo := time.FixedZone("JST", 9*60*60)
+	local := time.Date(2024, 3, 1, 21, 0, 0, 0, tokyo)
+	if err := db.Exec("INSERT INTO players (name, elo) VALUES ('Alice', 1000), ('Bob', 1000)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec(`INSERT INTO matches (player1_id, player2_id, player1_score, player2_score, played_at)
+		VALUES (1, 2, 2, 1, ?)`, local).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec(`INSERT INTO seasons (name, status, reset_percent, started_at, ended_at)
+		VALUES ('Spring', 'ended', 50, ?, ?)`, local, local.Add(time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO championship_reigns (player_id, started_at, ended_at) VALUES (1, ?, NULL)", local).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Up(db); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range []string{
+		"SELECT CAST(played_at AS TEXT) FROM matches",
+		"SELECT CAST(started_at AS TEXT) FROM seasons",
+		"SELECT CAST(ended_at AS TEXT) FROM seasons",
+		"SELECT CAST(started_at AS TEXT) FROM championship_reigns",
+	} {
+		var stored string
+		if err := db.Raw(query).Row().Scan(&stored); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if !strings.HasPrefix(stored, "2024-03-01 1") || !strings.HasSuffix(stored, "+00:00") {
+			t.Errorf("%s: got %q, want the time in UTC", query, stored)
+		}
+	}
+
+	var ended *string
+	if err := db.Raw("SELECT ended_at FROM championship_reigns").Row().Scan(&ended); err != nil {
+		t.Fatal(err)
+	}
+	if ended != nil {
+		t.Errorf("open reign: got ended_at %q, want NULL", *ended)
+	}
+}
@@ -16,18 +16,25 @@ const (
 
 // Admin represents an admin user in the system
 type Admin struct {
-	ID           uint           `gorm:"primaryKey" json:"id"`
-	Username     string         `gorm:"uniqueIndex;not null" json:"username"`
-	Email        string         `gorm:"uniqueIndex;not null" json:"email"`
-	PasswordHash string         `gorm:"not null" json:"-"`
-	Role         AdminRole      `gorm:"not null;default:'admin'" json:"role"`
-	CreatedByID  *uint          `json:"created_by_id,omitempty"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	Username          string         `gorm:"uniqueIndex;not null" json:"username"`
+	Email             string         `gorm:"uniqueIndex;not null" json:"email"`
+	PasswordHash      string         `gorm:"not null" json:"-"`
+	Role              AdminRole      `gorm:"not null;default:'admin'" json:"role"`
+	RoleID            *uint          `gorm:"index" json:"role_id,omitempty"` // custom role replacing the default permissions
+	CreatedByID       *uint          `json:"created_by_id,omitempty"`
+	TOTPSecret        string         `gorm:"size:64" json:"-"`                        // base32, set once setup has started
+	TOTPEnabled       bool           `gorm:"default:false" json:"two_factor_enabled"` // set once a code has been verified
+	TOTPLastStep      int64          `gorm:"default:0" json:"-"`                      // time step of the last code accepted
+	PasswordChangedAt *time.Time     `json:"-"`                                       // voids the login challenges issued before
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Relationship - Admin who created this admin
 	CreatedBy *Admin `gorm:"foreignKey:CreatedByID" json:"created_by,omitempty"`
+	// Relationship - Custom role of this admin
+	CustomRole *Role `gorm:"foreignKey:RoleID" json:"custom_role,omitempty"`
 }
 
 // AdminLoginRequest for admin login
@@ -48,19 +55,36 @@ type CreateAdminRequest struct {
 	Username string `json:"username" validate:"required"`
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=6"`
+	RoleID   *uint  `json:"role_id"` // optional custom role
 }
 
 // AdminResponse for API responses
 type AdminResponse struct {
-	ID        uint      `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Role      AdminRole `json:"role"`
-	CreatedAt time.Time `json:"created_at"`
+	ID               uint      `json:"id"`
+	Username         string    `json:"username"`
+	Email            string    `json:"email"`
+	Role             AdminRole `json:"role"`
+	RoleID           *uint     `json:"role_id,omitempty"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// AdminSummary is the public view of an admin, e.g. the editor of a match
+type AdminSummary struct {
+	ID        uint           `json:"id"`
+	Username  string         `json:"username"`
+	DeletedAt gorm.DeletedAt `json:"-"`
+}
+
+// TableName loads admin summaries from the admins table
+func (AdminSummary) TableName() string {
+	return "admins"
 }
 
 // AuthResponse for login/register responses
 type AuthResponse struct {
-	Token string        `json:"token"`
-	Admin AdminResponse `json:"admin"`
+	Token        string        `json:"token"`
+	RefreshToken string        `json:"refresh_token"`
+	ExpiresIn    int           `json:"expires_in"` // lifetime of the access token in seconds
+	Admin        AdminResponse `json:"admin"`
 }
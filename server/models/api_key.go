@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// APIKey lets a device or bot call the API on behalf of the admin who
+// created it, without logging in. A key can do what its scopes allow and
+// its admin may still do; only the hash of the key is stored.
+type APIKey struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	AdminID    uint           `gorm:"not null;index" json:"admin_id"`
+	Name       string         `gorm:"not null;size:100" json:"name"`
+	Prefix     string         `gorm:"not null;size:16" json:"prefix"`        // start of the key, to recognise it
+	KeyHash    string         `gorm:"not null;size:64;uniqueIndex" json:"-"` // SHA-256 of the key
+	Scopes     PermissionList `gorm:"type:text;not null" json:"scopes"`      // permissions the key may use
+	ExpiresAt  *time.Time     `json:"expires_at,omitempty"`                  // nil for a key that does not expire
+	LastUsedAt *time.Time     `json:"last_used_at,omitempty"`                // updated at most once a minute
+	LastUsedIP string         `gorm:"size:64" json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time     `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+
+	Admin *Admin `gorm:"foreignKey:AdminID" json:"admin,omitempty"`
+}
+
+// Active reports whether the key can still be used at now
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(now))
+}
+
+// CreateAPIKeyRequest for creating an API key
+type CreateAPIKeyRequest struct {
+	Name      string       `json:"name" validate:"required"`
+	Scopes    []Permission `json:"scopes" validate:"required"`
+	ExpiresAt *time.Time   `json:"expires_at"` // optional
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// AuditEventType names what an audit event records
+type AuditEventType string
+
+const (
+	AuditLoginFailed   AuditEventType = "login_failed"
+	AuditLoginLocked   AuditEventType = "login_locked"
+	AuditLoginUnlocked AuditEventType = "login_unlocked"
+
+	AuditTwoFactorEnabled  AuditEventType = "two_factor_enabled"
+	AuditTwoFactorDisabled AuditEventType = "two_factor_disabled"
+)
+
+// AuditEvent records a security relevant event, such as a failed login
+type AuditEvent struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Type      AuditEventType `gorm:"not null;size:64;index" json:"type"`
+	AdminID   *uint          `gorm:"index" json:"admin_id,omitempty"` // the admin the event is about, when known
+	ActorID   *uint          `json:"actor_id,omitempty"`              // the admin who acted, for admin actions
+	Username  string         `gorm:"size:255" json:"username,omitempty"`
+	IP        string         `gorm:"size:64" json:"ip,omitempty"`
+	Detail    string         `json:"detail,omitempty"`
+	CreatedAt time.Time      `gorm:"index" json:"created_at"`
+}
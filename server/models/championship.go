@@ -1,6 +1,7 @@
 package models
 
 import (
+	"math"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,9 +12,11 @@ type ChampionshipReign struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
 	PlayerID  uint           `gorm:"not null;index" json:"player_id"`
 	Player    Player         `gorm:"foreignKey:PlayerID" json:"player"`
+	SeasonID  *uint          `gorm:"index" json:"season_id,omitempty"` // null for the all-time championship
 	StartedAt time.Time      `gorm:"not null" json:"started_at"`
 	EndedAt   *time.Time     `json:"ended_at"` // null if currently champion
 	Days      int            `gorm:"-" json:"days"` // calculated field
+	Hours     float64        `gorm:"-" json:"hours"` // calculated field
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
@@ -29,6 +32,16 @@ func (c *ChampionshipReign) CalculateDays() int {
 	return int(duration.Hours() / 24)
 }
 
+// CalculateHours calculates the length of this reign in hours, to two decimals
+func (c *ChampionshipReign) CalculateHours() float64 {
+	endTime := time.Now()
+	if c.EndedAt != nil {
+		endTime = *c.EndedAt
+	}
+	duration := endTime.Sub(c.StartedAt)
+	return math.Round(duration.Hours()*100) / 100
+}
+
 // ChampionStats represents aggregated championship statistics for a player
 type ChampionStats struct {
 	PlayerID        uint       `json:"player_id"`
@@ -36,6 +49,9 @@ type ChampionStats struct {
 	TotalReigns     int        `json:"total_reigns"`
 	TotalDays       int        `json:"total_days"`
 	LongestReignDays int       `json:"longest_reign_days"`
+	TotalHours      float64    `json:"total_hours"`
+	LongestReignHours float64  `json:"longest_reign_hours"`
+	TitleDefenses   int        `json:"title_defenses"` // matches won or drawn while holding the title
 	CurrentChamp    bool       `json:"current_champion"`
 	FirstCrowned    time.Time  `json:"first_crowned"`
 	LastCrowned     *time.Time `json:"last_crowned,omitempty"`
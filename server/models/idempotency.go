@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// IdempotencyKey remembers the response to a request sent with an
+// Idempotency-Key header so that a retried request is answered with the
+// original response instead of being processed again
+type IdempotencyKey struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	AdminID      uint      `gorm:"not null;uniqueIndex:idx_idempotency_admin_key" json:"admin_id"`
+	Key          string    `gorm:"column:idempotency_key;not null;size:255;uniqueIndex:idx_idempotency_admin_key" json:"key"`
+	RequestHash  string    `gorm:"not null;size:64" json:"request_hash"` // SHA-256 of method, path and body
+	StatusCode   int       `gorm:"default:0" json:"status_code"`         // 0 while the request is still being processed
+	ResponseBody string    `gorm:"type:text" json:"response_body"`
+	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
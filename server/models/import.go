@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// ImportStatus is the outcome of a single row of a match import
+type ImportStatus string
+
+const (
+	ImportValid    ImportStatus = "valid"    // passed validation (dry run)
+	ImportImported ImportStatus = "imported" // stored as a match
+	ImportInvalid  ImportStatus = "invalid"  // rejected, see the row error
+)
+
+// ImportMatchRow is a historical match result in an import file. Each player
+// is given either by ID or by name; unknown names are created.
+type ImportMatchRow struct {
+	Player1ID    uint   `json:"player1_id"`
+	Player2ID    uint   `json:"player2_id"`
+	Player1Name  string `json:"player1_name"`
+	Player2Name  string `json:"player2_name"`
+	Player1Score int    `json:"player1_score"`
+	Player2Score int    `json:"player2_score"`
+	PlayedAt     string `json:"played_at"` // RFC 3339, "2006-01-02 15:04:05" or "2006-01-02"
+
+	// ParseError is set when a CSV cell could not be read
+	ParseError string `json:"-"`
+}
+
+// ImportRowResult reports what happened to one row of an import
+type ImportRowResult struct {
+	Row         int          `json:"row"` // 1-based position in the file, not counting a CSV header
+	Status      ImportStatus `json:"status"`
+	Error       string       `json:"error,omitempty"`
+	Player1Name string       `json:"player1_name,omitempty"`
+	Player2Name string       `json:"player2_name,omitempty"`
+	PlayedAt    *time.Time   `json:"played_at,omitempty"`
+	MatchID     *uint        `json:"match_id,omitempty"`
+}
+
+// ImportReport summarises a match import. Nothing is stored unless every row
+// is valid.
+type ImportReport struct {
+	DryRun         bool              `json:"dry_run"`
+	Total          int               `json:"total"`
+	Valid          int               `json:"valid"`
+	Invalid        int               `json:"invalid"`
+	Imported       int               `json:"imported"`
+	PlayersCreated []string          `json:"players_created"`
+	PlayersUpdated int               `json:"players_updated"` // players whose rating changed in the recompute
+	MatchesUpdated int               `json:"matches_updated"` // existing and imported matches re-rated in the recompute
+	Rows           []ImportRowResult `json:"rows"`
+}
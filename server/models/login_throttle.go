@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// ThrottleKind is what failed logins are counted by
+type ThrottleKind string
+
+const (
+	ThrottleUsername ThrottleKind = "username"
+	ThrottleIP       ThrottleKind = "ip"
+)
+
+// LoginThrottle counts the recent failed logins for a username or an IP
+// address. Logins are refused until LockedUntil.
+type LoginThrottle struct {
+	ID            uint         `gorm:"primaryKey" json:"id"`
+	Kind          ThrottleKind `gorm:"not null;size:16;uniqueIndex:idx_login_throttle_key" json:"kind"`
+	Key           string       `gorm:"column:throttle_key;not null;size:255;uniqueIndex:idx_login_throttle_key" json:"key"`
+	Failures      int          `gorm:"not null;default:0" json:"failures"`
+	LastFailureAt time.Time    `json:"last_failure_at"`
+	LockedUntil   *time.Time   `gorm:"index" json:"locked_until,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
@@ -8,27 +8,32 @@ import (
 
 // Match represents a game between two players
 type Match struct {
-	ID               uint           `gorm:"primaryKey" json:"id"`
-	Player1ID        uint           `gorm:"not null" json:"player1_id"`
-	Player2ID        uint           `gorm:"not null" json:"player2_id"`
-	Player1Score     int            `gorm:"not null" json:"player1_score"`
-	Player2Score     int            `gorm:"not null" json:"player2_score"`
-	WinnerID         *uint          `json:"winner_id"` // nil for draw
-	Player1EloChange float64        `json:"player1_elo_change"`
-	Player2EloChange float64        `json:"player2_elo_change"`
-	Player1EloBefore float64        `json:"player1_elo_before"`
-	Player2EloBefore float64        `json:"player2_elo_before"`
-	Player1EloAfter  float64        `json:"player1_elo_after"`
-	Player2EloAfter  float64        `json:"player2_elo_after"`
-	CreatedByAdminID *uint          `json:"created_by_admin_id,omitempty"`
-	CreatedAt        time.Time      `json:"created_at"`
-	UpdatedAt        time.Time      `json:"updated_at"`
-	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	Player1ID         uint           `gorm:"not null" json:"player1_id"`
+	Player2ID         uint           `gorm:"not null" json:"player2_id"`
+	Player1Score      int            `gorm:"not null" json:"player1_score"`
+	Player2Score      int            `gorm:"not null" json:"player2_score"`
+	WinnerID          *uint          `json:"winner_id"` // nil for draw
+	Player1EloChange  float64        `json:"player1_elo_change"`
+	Player2EloChange  float64        `json:"player2_elo_change"`
+	Player1EloBefore  float64        `json:"player1_elo_before"`
+	Player2EloBefore  float64        `json:"player2_elo_before"`
+	Player1EloAfter   float64        `json:"player1_elo_after"`
+	Player2EloAfter   float64        `json:"player2_elo_after"`
+	CreatedByAdminID  *uint          `json:"created_by_admin_id,omitempty"`
+	CreatedByAPIKeyID *uint          `gorm:"index" json:"created_by_api_key_id,omitempty"` // API key the match was submitted with
+	SeasonID          *uint          `gorm:"index" json:"season_id,omitempty"`             // season active when the match was played
+	PlayedAt          time.Time      `gorm:"index" json:"played_at"`                       // when the match took place, ratings are applied in this order
+	CreatedAt         time.Time      `json:"created_at"`                                   // when the match was entered
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Relationships
-	Player1        Player `gorm:"foreignKey:Player1ID" json:"player1,omitempty"`
-	Player2        Player `gorm:"foreignKey:Player2ID" json:"player2,omitempty"`
-	CreatedByAdmin *Admin `gorm:"foreignKey:CreatedByAdminID" json:"created_by_admin,omitempty"`
+	Player1         Player       `gorm:"foreignKey:Player1ID" json:"player1,omitempty"`
+	Player2         Player       `gorm:"foreignKey:Player2ID" json:"player2,omitempty"`
+	CreatedByAdmin  *Admin       `gorm:"foreignKey:CreatedByAdminID" json:"created_by_admin,omitempty"`
+	CreatedByAPIKey *APIKey      `gorm:"foreignKey:CreatedByAPIKeyID" json:"created_by_api_key,omitempty"`
+	Rounds          []MatchRound `gorm:"foreignKey:MatchID" json:"rounds,omitempty"`
 }
 
 // MatchRequest for submitting match results
@@ -39,24 +44,32 @@ type MatchRequest struct {
 	Player2Name  string `json:"player2_name"`
 	Player1Score int    `json:"player1_score" validate:"min=0"`
 	Player2Score int    `json:"player2_score" validate:"min=0"`
+	// Optional round-by-round throws, must add up to the submitted score
+	Rounds []RoundRequest `json:"rounds"`
+	// Optional time the match was played (defaults to now); an earlier time
+	// re-rates every match played after it
+	PlayedAt string `json:"played_at"`
 }
 
 // MatchResponse for API responses
 type MatchResponse struct {
-	ID               uint    `json:"id"`
-	Player1ID        uint    `json:"player1_id"`
-	Player2ID        uint    `json:"player2_id"`
-	Player1Name      string  `json:"player1_name"`
-	Player2Name      string  `json:"player2_name"`
-	Player1Score     int     `json:"player1_score"`
-	Player2Score     int     `json:"player2_score"`
-	WinnerName       string  `json:"winner_name,omitempty"`
-	Player1EloChange float64 `json:"player1_elo_change"`
-	Player2EloChange float64 `json:"player2_elo_change"`
-	Player1EloBefore float64 `json:"player1_elo_before"`
-	Player2EloBefore float64 `json:"player2_elo_before"`
-	Player1EloAfter  float64 `json:"player1_elo_after"`
-	Player2EloAfter  float64 `json:"player2_elo_after"`
-	CreatedByAdminID *uint   `json:"created_by_admin_id,omitempty"`
-	CreatedAt        string  `json:"created_at"`
+	ID                uint         `json:"id"`
+	Player1ID         uint         `json:"player1_id"`
+	Player2ID         uint         `json:"player2_id"`
+	Player1Name       string       `json:"player1_name"`
+	Player2Name       string       `json:"player2_name"`
+	Player1Score      int          `json:"player1_score"`
+	Player2Score      int          `json:"player2_score"`
+	WinnerName        string       `json:"winner_name,omitempty"`
+	Player1EloChange  float64      `json:"player1_elo_change"`
+	Player2EloChange  float64      `json:"player2_elo_change"`
+	Player1EloBefore  float64      `json:"player1_elo_before"`
+	Player2EloBefore  float64      `json:"player2_elo_before"`
+	Player1EloAfter   float64      `json:"player1_elo_after"`
+	Player2EloAfter   float64      `json:"player2_elo_after"`
+	CreatedByAdminID  *uint        `json:"created_by_admin_id,omitempty"`
+	CreatedByAPIKeyID *uint        `json:"created_by_api_key_id,omitempty"`
+	PlayedAt          string       `json:"played_at"`
+	CreatedAt         string       `json:"created_at"`
+	Rounds            []MatchRound `json:"rounds,omitempty"`
 }
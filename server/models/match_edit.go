@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// MatchEdit is an audit record of a corrected match result, keeping the
+// values the match had before the edit
+type MatchEdit struct {
+	ID                   uint      `gorm:"primaryKey" json:"id"`
+	MatchID              uint      `gorm:"not null;index" json:"match_id"`
+	EditedByAdminID      uint      `gorm:"not null" json:"edited_by_admin_id"`
+	Reason               string    `json:"reason,omitempty"`
+	PreviousPlayer1Score int       `json:"previous_player1_score"`
+	PreviousPlayer2Score int       `json:"previous_player2_score"`
+	PreviousWinnerID     *uint     `json:"previous_winner_id"`
+	PreviousRounds       string    `gorm:"type:text" json:"previous_rounds,omitempty"` // JSON list of throws
+	Player1Score         int       `json:"player1_score"`
+	Player2Score         int       `json:"player2_score"`
+	WinnerID             *uint     `json:"winner_id"`
+	Rounds               string    `gorm:"type:text" json:"rounds,omitempty"` // JSON list of throws
+	PlayersUpdated       int       `json:"players_updated"`                   // players whose rating changed in the recompute
+	MatchesUpdated       int       `json:"matches_updated"`                   // matches re-rated in the recompute
+	CreatedAt            time.Time `json:"created_at"`
+
+	EditedByAdmin *AdminSummary `gorm:"foreignKey:EditedByAdminID" json:"edited_by_admin,omitempty"`
+}
+
+// UpdateMatchRequest for correcting the result of a match. Rounds replace the
+// recorded rounds when given; an empty list removes them.
+type UpdateMatchRequest struct {
+	Player1Score int             `json:"player1_score" validate:"min=0"`
+	Player2Score int             `json:"player2_score" validate:"min=0"`
+	Rounds       *[]RoundRequest `json:"rounds"`
+	Reason       string          `json:"reason"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// PasswordResetToken is a one-time token a super admin issues so that an
+// admin who forgot their password can choose a new one. Only the hash of the
+// token is stored.
+type PasswordResetToken struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	AdminID          uint       `gorm:"not null;index" json:"admin_id"`
+	TokenHash        string     `gorm:"not null;size:64;uniqueIndex" json:"-"` // SHA-256 of the token
+	ExpiresAt        time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt           *time.Time `json:"used_at,omitempty"`
+	CreatedByAdminID *uint      `json:"created_by_admin_id,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// ChangePasswordRequest for an admin changing their own password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6"`
+}
+
+// ResetPasswordRequest for setting a new password with a reset token
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=6"`
+}
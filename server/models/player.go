@@ -7,16 +7,18 @@ import (
 )
 
 type Player struct {
-	ID           uint           `gorm:"primaryKey" json:"id"`
-	Name         string         `gorm:"uniqueIndex;not null" json:"name"`
-	Elo          float64        `gorm:"default:1000" json:"elo"`
-	MatchesWon   int            `gorm:"default:0" json:"matches_won"`
-	MatchesLost  int            `gorm:"default:0" json:"matches_lost"`
-	MatchesDrawn int            `gorm:"default:0" json:"matches_drawn"`
-	TotalMatches int            `gorm:"default:0" json:"total_matches"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	Name             string         `gorm:"uniqueIndex;not null" json:"name"`
+	Elo              float64        `gorm:"default:1000" json:"elo"`
+	RatingDeviation  float64        `gorm:"default:350" json:"rating_deviation"`
+	RatingVolatility float64        `gorm:"default:0.06" json:"rating_volatility"`
+	MatchesWon       int            `gorm:"default:0" json:"matches_won"`
+	MatchesLost      int            `gorm:"default:0" json:"matches_lost"`
+	MatchesDrawn     int            `gorm:"default:0" json:"matches_drawn"`
+	TotalMatches     int            `gorm:"default:0" json:"total_matches"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // PlayerResponse for API responses
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// RefreshToken is a long-lived token an admin exchanges for a new access
+// token. Every exchange rotates it: the old token is marked as rotated and a
+// new one is issued in the same family. A family is one login session; only
+// the hash of a token is stored.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	AdminID   uint       `gorm:"not null;index" json:"admin_id"`
+	FamilyID  string     `gorm:"not null;size:64;index" json:"family_id"`
+	TokenHash string     `gorm:"not null;size:64;uniqueIndex" json:"-"` // SHA-256 of the token
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty"` // set once exchanged for a new token
+	RevokedAt *time.Time `json:"revoked_at,omitempty"` // set on logout, reuse or revocation
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// RefreshRequest for exchanging or revoking a refresh token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Permission names an action an admin may be allowed to take
+type Permission string
+
+const (
+	PermPlayersWrite      Permission = "players:write"
+	PermMatchesSubmit     Permission = "matches:submit"
+	PermMatchesImport     Permission = "matches:import"
+	PermMatchesViewAny    Permission = "matches:view:any"
+	PermMatchesEditAny    Permission = "matches:edit:any"
+	PermMatchesDeleteAny  Permission = "matches:delete:any"
+	PermTournamentsManage Permission = "tournaments:manage"
+	PermSeasonsManage     Permission = "seasons:manage"
+	PermRatingsManage     Permission = "ratings:manage"
+	PermAdminsManage      Permission = "admins:manage"
+	PermSettingsManage    Permission = "settings:manage"
+	PermAuditView         Permission = "audit:view"
+	PermDataExport        Permission = "data:export"
+)
+
+// PermissionInfo describes a permission for API responses
+type PermissionInfo struct {
+	Name        Permission `json:"name"`
+	Description string     `json:"description"`
+}
+
+// Permissions lists every permission there is
+var Permissions = []PermissionInfo{
+	{PermPlayersWrite, "Create, update and delete players"},
+	{PermMatchesSubmit, "Submit matches and correct or delete your own"},
+	{PermMatchesImport, "Import matches in bulk"},
+	{PermMatchesViewAny, "View the matches submitted by any admin"},
+	{PermMatchesEditAny, "Correct matches submitted by any admin"},
+	{PermMatchesDeleteAny, "Delete matches submitted by any admin"},
+	{PermTournamentsManage, "Create and start tournaments and submit their results"},
+	{PermSeasonsManage, "Create, start and end seasons"},
+	{PermRatingsManage, "Replay the ratings from the match log"},
+	{PermAdminsManage, "Manage admins, their roles, sessions, passwords and lockouts"},
+	{PermSettingsManage, "Change the system-wide settings"},
+	{PermAuditView, "View the audit log"},
+	{PermDataExport, "Export the database"},
+}
+
+// DefaultAdminPermissions are held by admins without a custom role. Super
+// admins hold every permission.
+var DefaultAdminPermissions = []Permission{
+	PermPlayersWrite,
+	PermMatchesSubmit,
+	PermMatchesImport,
+	PermTournamentsManage,
+}
+
+// SessionOnlyPermissions administer admin accounts and their security, so
+// they are only held when logged in and cannot be API key scopes
+var SessionOnlyPermissions = []Permission{
+	PermAdminsManage,
+	PermSettingsManage,
+}
+
+// ValidPermission reports whether p is a known permission
+func ValidPermission(p Permission) bool {
+	for _, info := range Permissions {
+		if info.Name == p {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionList is a list of permissions stored as a JSON array
+type PermissionList []Permission
+
+// Value implements driver.Valuer
+func (l PermissionList) Value() (driver.Value, error) {
+	if l == nil {
+		l = PermissionList{}
+	}
+	encoded, err := json.Marshal(l)
+	return string(encoded), err
+}
+
+// Scan implements sql.Scanner
+func (l *PermissionList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	case []byte:
+		return json.Unmarshal(v, l)
+	case nil:
+		*l = nil
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into PermissionList", value)
+}
+
+// Role is a custom set of permissions assignable to admins. It replaces the
+// default admin permissions of the admins it is assigned to.
+type Role struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	Name        string         `gorm:"uniqueIndex;not null;size:64" json:"name"`
+	Description string         `json:"description"`
+	Permissions PermissionList `gorm:"type:text;not null" json:"permissions"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// RoleRequest for creating or updating a role
+type RoleRequest struct {
+	Name        string       `json:"name" validate:"required"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// AssignRoleRequest for assigning a custom role to an admin; a null role_id
+// gives the admin the default permissions again
+type AssignRoleRequest struct {
+	RoleID *uint `json:"role_id"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+)
+
+// Throw is a single hand shape thrown in a round
+type Throw string
+
+const (
+	ThrowStone   Throw = "stone"
+	ThrowPaper   Throw = "paper"
+	ThrowScissor Throw = "scissor"
+)
+
+// Throws lists every valid throw in a fixed order
+var Throws = []Throw{ThrowStone, ThrowPaper, ThrowScissor}
+
+// Valid reports whether t is one of the three known throws
+func (t Throw) Valid() bool {
+	return t == ThrowStone || t == ThrowPaper || t == ThrowScissor
+}
+
+// Beats reports whether t wins against other
+func (t Throw) Beats(other Throw) bool {
+	return (t == ThrowStone && other == ThrowScissor) ||
+		(t == ThrowPaper && other == ThrowStone) ||
+		(t == ThrowScissor && other == ThrowPaper)
+}
+
+// RoundOutcome defines who won a single round
+type RoundOutcome string
+
+const (
+	RoundPlayer1 RoundOutcome = "player1"
+	RoundPlayer2 RoundOutcome = "player2"
+	RoundDraw    RoundOutcome = "draw"
+)
+
+// MatchRound represents a single exchange of throws within a match
+type MatchRound struct {
+	ID           uint         `gorm:"primaryKey" json:"id"`
+	MatchID      uint         `gorm:"not null;index" json:"match_id"`
+	RoundNumber  int          `gorm:"not null" json:"round_number"`
+	Player1Throw Throw        `gorm:"not null" json:"player1_throw"`
+	Player2Throw Throw        `gorm:"not null" json:"player2_throw"`
+	Outcome      RoundOutcome `gorm:"not null" json:"outcome"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+// RoundRequest is a single round in a match submission
+type RoundRequest struct {
+	Player1Throw Throw `json:"player1_throw"`
+	Player2Throw Throw `json:"player2_throw"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SeasonStatus defines the lifecycle state of a season
+type SeasonStatus string
+
+const (
+	SeasonPending SeasonStatus = "pending"
+	SeasonActive  SeasonStatus = "active"
+	SeasonEnded   SeasonStatus = "ended"
+)
+
+// Season is a period with its own rating table. Player.Elo keeps the
+// all-time rating; every match played while a season is active also rates
+// the players' SeasonRating rows.
+type Season struct {
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	Name             string         `gorm:"uniqueIndex;not null" json:"name"`
+	Status           SeasonStatus   `gorm:"not null;default:'pending'" json:"status"`
+	ResetPercent     float64        `gorm:"not null" json:"reset_percent"` // how far ratings regress toward the default at season start
+	ChampionID       *uint          `json:"champion_id"`
+	CreatedByAdminID *uint          `json:"created_by_admin_id,omitempty"`
+	StartedAt        *time.Time     `json:"started_at"`
+	EndedAt          *time.Time     `json:"ended_at"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Relationships
+	Champion *Player `gorm:"foreignKey:ChampionID" json:"champion,omitempty"`
+}
+
+// SeasonRating is a player's rating and record within a single season
+type SeasonRating struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	SeasonID         uint      `gorm:"not null;uniqueIndex:idx_season_player" json:"season_id"`
+	PlayerID         uint      `gorm:"not null;uniqueIndex:idx_season_player;index" json:"player_id"`
+	StartingElo      float64   `json:"starting_elo"` // all-time rating after the soft reset
+	Elo              float64   `json:"elo"`
+	RatingDeviation  float64   `json:"rating_deviation"`
+	RatingVolatility float64   `json:"rating_volatility"`
+	MatchesWon       int       `gorm:"default:0" json:"matches_won"`
+	MatchesLost      int       `gorm:"default:0" json:"matches_lost"`
+	MatchesDrawn     int       `gorm:"default:0" json:"matches_drawn"`
+	TotalMatches     int       `gorm:"default:0" json:"total_matches"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+
+	Player Player `gorm:"foreignKey:PlayerID" json:"player"`
+}
+
+// CreateSeasonRequest for creating a season
+type CreateSeasonRequest struct {
+	Name string `json:"name" validate:"required"`
+	// Percentage (0-100) by which ratings regress toward the default rating
+	// when the season starts, defaults to 50
+	ResetPercent *float64 `json:"reset_percent"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Setting is a system-wide option changed at runtime by a super admin
+type Setting struct {
+	Key       string    `gorm:"primaryKey;size:64" json:"key"`
+	Value     string    `gorm:"not null" json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SettingRequireTwoFactor makes two-factor authentication mandatory for every admin
+const SettingRequireTwoFactor = "require_2fa"
+
+// UpdateSettingsRequest for changing settings; fields left out are unchanged
+type UpdateSettingsRequest struct {
+	RequireTwoFactor *bool `json:"require_2fa"`
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TournamentFormat defines how a tournament is played
+type TournamentFormat string
+
+const (
+	FormatSingleElimination TournamentFormat = "single_elimination"
+	FormatDoubleElimination TournamentFormat = "double_elimination"
+	FormatSwiss             TournamentFormat = "swiss"
+	FormatRoundRobin        TournamentFormat = "round_robin"
+)
+
+// BracketSide identifies which part of an elimination bracket a match belongs to
+type BracketSide string
+
+const (
+	BracketWinners    BracketSide = "winners"
+	BracketLosers     BracketSide = "losers"
+	BracketGrandFinal BracketSide = "grand_final"
+	BracketReset      BracketSide = "grand_final_reset"
+)
+
+// TournamentStatus defines the lifecycle state of a tournament
+type TournamentStatus string
+
+const (
+	TournamentPending    TournamentStatus = "pending"
+	TournamentInProgress TournamentStatus = "in_progress"
+	TournamentCompleted  TournamentStatus = "completed"
+)
+
+// Tournament represents a competition between a fixed field of players
+type Tournament struct {
+	ID               uint             `gorm:"primaryKey" json:"id"`
+	Name             string           `gorm:"not null" json:"name"`
+	Format           TournamentFormat `gorm:"not null" json:"format"`
+	Status           TournamentStatus `gorm:"not null;default:'pending'" json:"status"`
+	TotalRounds      int              `gorm:"default:0" json:"total_rounds"`
+	DoubleRoundRobin bool             `gorm:"default:false" json:"double_round_robin"`
+	BracketReset     bool             `gorm:"default:false" json:"bracket_reset"`
+	WinnerID         *uint            `json:"winner_id"`
+	CreatedByAdminID *uint            `json:"created_by_admin_id,omitempty"`
+	StartedAt        *time.Time       `json:"started_at"`
+	CompletedAt      *time.Time       `json:"completed_at"`
+	CreatedAt        time.Time        `json:"created_at"`
+	UpdatedAt        time.Time        `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt   `gorm:"index" json:"-"`
+
+	// Relationships
+	Winner       *Player                 `gorm:"foreignKey:WinnerID" json:"winner,omitempty"`
+	Participants []TournamentParticipant `gorm:"foreignKey:TournamentID" json:"participants,omitempty"`
+	Matches      []TournamentMatch       `gorm:"foreignKey:TournamentID" json:"matches,omitempty"`
+}
+
+// TournamentParticipant is a player entered in a tournament
+type TournamentParticipant struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	TournamentID uint      `gorm:"not null;index" json:"tournament_id"`
+	PlayerID     uint      `gorm:"not null;index" json:"player_id"`
+	Seed         int       `json:"seed"`
+	SeedElo      float64   `json:"seed_elo"` // rating used for seeding when the tournament started
+	CreatedAt    time.Time `json:"created_at"`
+
+	Player Player `gorm:"foreignKey:PlayerID" json:"player"`
+}
+
+// TournamentMatch is a single slot of a tournament schedule or bracket.
+// It is linked to the rated Match once its result has been submitted.
+type TournamentMatch struct {
+	ID               uint        `gorm:"primaryKey" json:"id"`
+	TournamentID     uint        `gorm:"not null;index" json:"tournament_id"`
+	Bracket          BracketSide `json:"bracket,omitempty"`
+	Round            int         `gorm:"not null" json:"round"`
+	Position         int         `gorm:"not null" json:"position"`
+	Player1ID        *uint       `json:"player1_id"`
+	Player2ID        *uint       `json:"player2_id"`
+	WinnerID         *uint       `json:"winner_id"`
+	MatchID          *uint       `json:"match_id"`
+	NextMatchID      *uint       `json:"next_match_id"`
+	NextSlot         int         `json:"next_slot"`                     // 1 or 2, the side the winner takes in the next match
+	LoserNextMatchID *uint       `json:"loser_next_match_id,omitempty"` // double elimination only
+	LoserNextSlot    int         `json:"loser_next_slot,omitempty"`
+	IsBye            bool        `gorm:"default:false" json:"is_bye"`
+	CompletedAt      *time.Time  `json:"completed_at"`
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+
+	// Relationships
+	Player1 *Player `gorm:"foreignKey:Player1ID" json:"player1,omitempty"`
+	Player2 *Player `gorm:"foreignKey:Player2ID" json:"player2,omitempty"`
+	Match   *Match  `gorm:"foreignKey:MatchID" json:"match,omitempty"`
+}
+
+// CreateTournamentRequest for creating a tournament
+type CreateTournamentRequest struct {
+	Name      string           `json:"name" validate:"required"`
+	Format    TournamentFormat `json:"format"`
+	PlayerIDs []uint           `json:"player_ids"`
+	Rounds    int              `json:"rounds"` // Swiss only, defaults to log2 of the field size
+	// Round robin only, every pairing is played twice with sides swapped
+	DoubleRoundRobin bool `json:"double_round_robin"`
+	// Double elimination only, a grand final won from the losers' bracket forces a deciding rematch
+	BracketReset bool `json:"bracket_reset"`
+}
+
+// TournamentResultRequest for submitting the result of a tournament match
+type TournamentResultRequest struct {
+	Player1Score int            `json:"player1_score" validate:"min=0"`
+	Player2Score int            `json:"player2_score" validate:"min=0"`
+	Rounds       []RoundRequest `json:"rounds"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// RecoveryCode is a one-time code an admin can log in with instead of a
+// TOTP code, for when their authenticator is lost. Only the hash is stored.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	AdminID   uint       `gorm:"not null;index" json:"admin_id"`
+	CodeHash  string     `gorm:"not null;size:64;uniqueIndex" json:"-"` // SHA-256 of the normalized code
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// LoginChallenge is the second step of a login pending for an admin who has
+// given their password. Its token ID is in the challenge token, which can
+// complete one login only.
+type LoginChallenge struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	AdminID   uint       `gorm:"not null;index" json:"admin_id"`
+	TokenID   string     `gorm:"not null;size:64;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TwoFactorCodeRequest carries a TOTP code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// DisableTwoFactorRequest for turning two-factor authentication off
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"` // a TOTP or recovery code
+}
+
+// TwoFactorLoginRequest for the second step of a login
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"` // a TOTP or recovery code
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"stone-paper-scissors/handlers"
+	"stone-paper-scissors/models"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -23,16 +24,66 @@ func SetupRoutes(app *fiber.App) {
 	auth := api.Group("/auth")
 	auth.Post("/register", handlers.RegisterSuperAdmin)
 	auth.Post("/login", handlers.Login)
+	auth.Post("/login/2fa", handlers.LoginTwoFactor)
+	auth.Post("/refresh", handlers.RefreshToken)
+	auth.Post("/logout", handlers.Logout)
+	auth.Post("/password-reset", handlers.ResetPassword)
 	auth.Get("/check-super-admin", handlers.CheckSuperAdminExists)
 
-	// Protected auth routes
-	auth.Get("/me", handlers.AuthMiddleware, handlers.GetMe)
+	// Protected auth routes, the account routes are not open to API keys
+	auth.Get("/me", handlers.AuthMiddleware, handlers.SessionOnly, handlers.GetMe)
+	auth.Put("/password", handlers.AuthMiddleware, handlers.SessionOnly, handlers.ChangePassword)
+	auth.Get("/permissions", handlers.AuthMiddleware, handlers.GetMyPermissions)
 
-	// Admin management routes (super admin only)
-	admins := api.Group("/admins", handlers.AuthMiddleware, handlers.SuperAdminOnly)
+	// Two-factor authentication of the current admin
+	twoFactor := auth.Group("/2fa", handlers.AuthMiddleware, handlers.SessionOnly)
+	twoFactor.Get("/", handlers.GetTwoFactorStatus)
+	twoFactor.Post("/setup", handlers.SetupTwoFactor)
+	twoFactor.Post("/enable", handlers.EnableTwoFactor)
+	twoFactor.Post("/disable", handlers.DisableTwoFactor)
+	twoFactor.Post("/recovery-codes", handlers.RegenerateRecoveryCodes)
+
+	// API keys of the current admin, for scoreboards and bots
+	apiKeys := auth.Group("/api-keys", handlers.AuthMiddleware, handlers.SessionOnly)
+	apiKeys.Get("/", handlers.GetAPIKeys)
+	apiKeys.Post("/", handlers.CreateAPIKey)
+	apiKeys.Delete("/:id", handlers.RevokeAPIKey)
+
+	// Admin management routes
+	admins := api.Group("/admins", handlers.AuthMiddleware, handlers.RequirePermission(models.PermAdminsManage))
 	admins.Post("/", handlers.CreateAdmin)
 	admins.Get("/", handlers.GetAllAdmins)
 	admins.Delete("/:id", handlers.DeleteAdmin)
+	admins.Delete("/:id/sessions", handlers.RevokeAdminSessions)
+	admins.Delete("/:id/api-keys", handlers.RevokeAdminAPIKeys)
+	admins.Post("/:id/password-reset", handlers.ResetAdminPassword)
+	admins.Get("/lockouts", handlers.GetLockouts)
+	admins.Delete("/lockouts/:id", handlers.UnlockLogin)
+	admins.Delete("/:id/2fa", handlers.ResetAdminTwoFactor)
+	admins.Get("/:id/permissions", handlers.GetAdminPermissions)
+	admins.Put("/:id/role", handlers.AssignAdminRole)
+
+	// Custom roles
+	api.Get("/permissions", handlers.AuthMiddleware, handlers.GetPermissions)
+	roles := api.Group("/roles", handlers.AuthMiddleware, handlers.RequirePermission(models.PermAdminsManage))
+	roles.Get("/", handlers.GetRoles)
+	roles.Post("/", handlers.CreateRole)
+	roles.Put("/:id", handlers.UpdateRole)
+	roles.Delete("/:id", handlers.DeleteRole)
+
+	// System settings
+	api.Get("/settings", handlers.AuthMiddleware, handlers.RequirePermission(models.PermSettingsManage), handlers.GetSettings)
+	api.Put("/settings", handlers.AuthMiddleware, handlers.RequirePermission(models.PermSettingsManage), handlers.UpdateSettings)
+
+	// Audit log
+	api.Get("/audit-events", handlers.AuthMiddleware, handlers.RequirePermission(models.PermAuditView), handlers.GetAuditEvents)
+
+	// Database export
+	api.Get("/export", handlers.AuthMiddleware, handlers.RequirePermission(models.PermDataExport), handlers.ExportData)
+
+	// Rating maintenance routes
+	ratings := api.Group("/ratings", handlers.AuthMiddleware, handlers.RequirePermission(models.PermRatingsManage))
+	ratings.Post("/replay", handlers.ReplayRatings)
 
 	// Player routes (public read, admin write)
 	players := api.Group("/players")
@@ -40,20 +91,47 @@ func SetupRoutes(app *fiber.App) {
 	players.Get("/search", handlers.SearchPlayers)
 	players.Get("/:id", handlers.GetPlayer)
 	players.Get("/:id/matches", handlers.GetPlayerMatches)
+	players.Get("/:id/tendencies", handlers.GetPlayerTendencies)
+	players.Get("/:id/rating-history", handlers.GetPlayerRatingHistory)
+	players.Get("/:id/head-to-head/:opponentId", handlers.GetHeadToHead)
 
 	// Protected player routes
-	players.Post("/", handlers.AuthMiddleware, handlers.CreatePlayer)
-	players.Put("/:id", handlers.AuthMiddleware, handlers.UpdatePlayer)
-	players.Delete("/:id", handlers.AuthMiddleware, handlers.DeletePlayer)
+	players.Post("/", handlers.AuthMiddleware, handlers.RequirePermission(models.PermPlayersWrite), handlers.CreatePlayer)
+	players.Put("/:id", handlers.AuthMiddleware, handlers.RequirePermission(models.PermPlayersWrite), handlers.UpdatePlayer)
+	players.Delete("/:id", handlers.AuthMiddleware, handlers.RequirePermission(models.PermPlayersWrite), handlers.DeletePlayer)
 
 	// Match routes
 	matches := api.Group("/matches")
 	matches.Get("/", handlers.GetMatchHistory)
 	matches.Get("/:id", handlers.GetMatch)
 	matches.Get("/admin/:adminId", handlers.AuthMiddleware, handlers.GetMatchesByAdmin)
-	matches.Post("/", handlers.AuthMiddleware, handlers.SubmitMatch)
+	matches.Post("/", handlers.AuthMiddleware, handlers.RequirePermission(models.PermMatchesSubmit), handlers.IdempotencyMiddleware, handlers.SubmitMatch)
+	matches.Post("/import", handlers.AuthMiddleware, handlers.RequirePermission(models.PermMatchesImport), handlers.ImportMatches)
+	matches.Get("/:id/edits", handlers.GetMatchEdits)
+	// Editing and deleting check the match's owner against matches:submit
+	// and matches:edit:any / matches:delete:any in the handlers
+	matches.Put("/:id", handlers.AuthMiddleware, handlers.UpdateMatch)
 	matches.Delete("/:id", handlers.AuthMiddleware, handlers.DeleteMatch)
 
+	// Tournament routes (public read, admin write)
+	tournaments := api.Group("/tournaments")
+	tournaments.Get("/", handlers.GetTournaments)
+	tournaments.Get("/:id", handlers.GetTournament)
+	tournaments.Get("/:id/standings", handlers.GetTournamentStandings)
+	tournaments.Get("/:id/fixtures", handlers.GetTournamentFixtures)
+	tournaments.Get("/:id/bracket", handlers.GetTournamentBracket)
+	tournaments.Post("/", handlers.AuthMiddleware, handlers.RequirePermission(models.PermTournamentsManage), handlers.CreateTournament)
+	tournaments.Post("/:id/start", handlers.AuthMiddleware, handlers.RequirePermission(models.PermTournamentsManage), handlers.StartTournament)
+	tournaments.Post("/:id/matches/:matchId/result", handlers.AuthMiddleware, handlers.RequirePermission(models.PermTournamentsManage), handlers.SubmitTournamentResult)
+
+	// Season routes (public read, admin write)
+	seasons := api.Group("/seasons")
+	seasons.Get("/", handlers.GetSeasons)
+	seasons.Get("/:id", handlers.GetSeason)
+	seasons.Post("/", handlers.AuthMiddleware, handlers.RequirePermission(models.PermSeasonsManage), handlers.CreateSeason)
+	seasons.Post("/:id/start", handlers.AuthMiddleware, handlers.RequirePermission(models.PermSeasonsManage), handlers.StartSeason)
+	seasons.Post("/:id/end", handlers.AuthMiddleware, handlers.RequirePermission(models.PermSeasonsManage), handlers.EndSeason)
+
 	// Leaderboard routes (public)
 	leaderboard := api.Group("/leaderboard")
 	leaderboard.Get("/", handlers.GetLeaderboard)
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// APIKeyPrefix starts every API key so that leaked keys are easy to spot
+	APIKeyPrefix = "sps_"
+	// apiKeyUsageInterval is how often the last use of a key is recorded
+	apiKeyUsageInterval = time.Minute
+)
+
+// ErrInvalidAPIKey is returned for an unknown, expired or revoked API key
+var ErrInvalidAPIKey = errors.New("invalid, expired or revoked API key")
+
+// CreateAPIKey issues a new API key for an admin and returns it; the key
+// itself is only available here
+func CreateAPIKey(adminID uint, req models.CreateAPIKeyRequest) (string, *models.APIKey, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return "", nil, invalid("API key name is required")
+	}
+	if len(name) > 100 {
+		return "", nil, invalid("API key name must be at most 100 characters")
+	}
+	scopes, err := normalizePermissions(req.Scopes)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(scopes) == 0 {
+		return "", nil, invalid("An API key needs at least one scope")
+	}
+	for _, scope := range scopes {
+		if Holds(models.SessionOnlyPermissions, scope) {
+			return "", nil, invalid("API keys cannot have the %q scope", scope)
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return "", nil, invalid("expires_at must be in the future")
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	key := APIKeyPrefix + secret
+	record := models.APIKey{
+		AdminID:   adminID,
+		Name:      name,
+		Prefix:    key[:len(APIKeyPrefix)+8],
+		KeyHash:   HashToken(key),
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := config.DB.Create(&record).Error; err != nil {
+		return "", nil, err
+	}
+	return key, &record, nil
+}
+
+// ListAPIKeys returns the API keys of an admin newest first
+func ListAPIKeys(adminID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := config.DB.Where("admin_id = ?", adminID).Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+// RevokeAPIKey revokes one of an admin's API keys
+func RevokeAPIKey(adminID, id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := config.DB.Where("admin_id = ?", adminID).First(&key, id).Error; err != nil {
+		return nil, err
+	}
+	if key.RevokedAt == nil {
+		now := time.Now()
+		if err := config.DB.Model(&key).Update("revoked_at", now).Error; err != nil {
+			return nil, err
+		}
+		key.RevokedAt = &now
+	}
+	return &key, nil
+}
+
+// RevokeAdminAPIKeys revokes every API key of an admin and returns how many were active
+func RevokeAdminAPIKeys(db *gorm.DB, adminID uint) (int64, error) {
+	result := db.Model(&models.APIKey{}).
+		Where("admin_id = ? AND revoked_at IS NULL", adminID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// AuthenticateAPIKey looks up an active API key and records its use from ip
+func AuthenticateAPIKey(key, ip string) (*models.APIKey, error) {
+	var record models.APIKey
+	err := config.DB.Where("key_hash = ?", HashToken(key)).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !record.Active(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	// Busy devices would otherwise write on every request
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) >= apiKeyUsageInterval || record.LastUsedIP != ip {
+		if err := config.DB.Model(&record).Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ip,
+		}).Error; err != nil {
+			return nil, err
+		}
+		record.LastUsedAt = &now
+		record.LastUsedIP = ip
+	}
+	return &record, nil
+}
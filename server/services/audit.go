@@ -0,0 +1,30 @@
+package services
+
+import (
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+// RecordAudit stores an audit event
+func RecordAudit(db *gorm.DB, event models.AuditEvent) error {
+	return db.Create(&event).Error
+}
+
+// ListAuditEvents returns audit events newest first, optionally of one type
+func ListAuditEvents(eventType string, limit, offset int) ([]models.AuditEvent, int64, error) {
+	query := config.DB.Model(&models.AuditEvent{})
+	if eventType != "" {
+		query = query.Where("type = ?", eventType)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []models.AuditEvent
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&events).Error
+	return events, total, err
+}
@@ -0,0 +1,447 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+// BracketRound is a single round of one side of a bracket
+type BracketRound struct {
+	Round   int                      `json:"round"`
+	Matches []models.TournamentMatch `json:"matches"`
+}
+
+// BracketTree is an elimination bracket grouped for rendering
+type BracketTree struct {
+	TournamentID uint                     `json:"tournament_id"`
+	Format       models.TournamentFormat  `json:"format"`
+	Status       models.TournamentStatus  `json:"status"`
+	WinnerID     *uint                    `json:"winner_id"`
+	Winners      []BracketRound           `json:"winners"`
+	Losers       []BracketRound           `json:"losers,omitempty"`
+	GrandFinal   []models.TournamentMatch `json:"grand_final,omitempty"`
+}
+
+// GetTournamentBracket returns the full bracket tree of an elimination tournament
+func GetTournamentBracket(id uint) (*BracketTree, error) {
+	tournament, err := GetTournament(id)
+	if err != nil {
+		return nil, err
+	}
+	if !isElimination(tournament.Format) {
+		return nil, invalid("Only elimination tournaments have a bracket")
+	}
+
+	tree := &BracketTree{
+		TournamentID: tournament.ID,
+		Format:       tournament.Format,
+		Status:       tournament.Status,
+		WinnerID:     tournament.WinnerID,
+		Winners:      []BracketRound{},
+	}
+
+	addToRound := func(rounds []BracketRound, m models.TournamentMatch) []BracketRound {
+		if len(rounds) == 0 || rounds[len(rounds)-1].Round != m.Round {
+			rounds = append(rounds, BracketRound{Round: m.Round})
+		}
+		last := &rounds[len(rounds)-1]
+		last.Matches = append(last.Matches, m)
+		return rounds
+	}
+
+	// Matches are already ordered by round and position
+	for _, m := range tournament.Matches {
+		switch m.Bracket {
+		case models.BracketLosers:
+			tree.Losers = addToRound(tree.Losers, m)
+		case models.BracketGrandFinal, models.BracketReset:
+			tree.GrandFinal = append(tree.GrandFinal, m)
+		default:
+			tree.Winners = addToRound(tree.Winners, m)
+		}
+	}
+
+	return tree, nil
+}
+
+// bracketSeedOrder returns the seed in every bracket position for a bracket
+// of size players, so that seed 1 and 2 can only meet in the final
+// (size 8 gives 1, 8, 4, 5, 2, 7, 3, 6)
+func bracketSeedOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		next := make([]int, 0, len(order)*2)
+		sum := len(order)*2 + 1
+		for _, seed := range order {
+			next = append(next, seed, sum-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// bracketSize returns the power of two that fits players and its number of rounds
+func bracketSize(players int) (size, rounds int) {
+	size = 1
+	for size < players {
+		size *= 2
+		rounds++
+	}
+	return size, rounds
+}
+
+// buildWinnersBracket creates the knockout tree for the seeded field and
+// returns its matches indexed by round (1-based). The final feeds finalNext,
+// if given. Byes are placed against the top seeds but not resolved yet.
+func buildWinnersBracket(tx *gorm.DB, tournament *models.Tournament, seeded []models.TournamentParticipant, finalNext *uint, finalSlot int) ([][]models.TournamentMatch, error) {
+	size, rounds := bracketSize(len(seeded))
+	order := bracketSeedOrder(size)
+
+	seedPlayer := func(seed int) *uint {
+		if seed > len(seeded) {
+			return nil
+		}
+		id := seeded[seed-1].PlayerID
+		return &id
+	}
+
+	// Create from the final backwards so every match knows where its winner goes
+	bracket := make([][]models.TournamentMatch, rounds+1)
+	for round := rounds; round >= 1; round-- {
+		current := make([]models.TournamentMatch, size>>round)
+		for i := range current {
+			current[i] = models.TournamentMatch{
+				TournamentID: tournament.ID,
+				Bracket:      models.BracketWinners,
+				Round:        round,
+				Position:     i + 1,
+			}
+			if round < rounds {
+				nextID := bracket[round+1][i/2].ID
+				current[i].NextMatchID = &nextID
+				current[i].NextSlot = i%2 + 1
+			} else if finalNext != nil {
+				nextID := *finalNext
+				current[i].NextMatchID = &nextID
+				current[i].NextSlot = finalSlot
+			}
+			if round == 1 {
+				current[i].Player1ID = seedPlayer(order[2*i])
+				current[i].Player2ID = seedPlayer(order[2*i+1])
+			}
+		}
+		if err := tx.Create(&current).Error; err != nil {
+			return nil, err
+		}
+		bracket[round] = current
+	}
+
+	tournament.TotalRounds = rounds
+	if err := tx.Model(tournament).Update("total_rounds", rounds).Error; err != nil {
+		return nil, err
+	}
+
+	return bracket, nil
+}
+
+// completeBye marks a first round match with a single player as a bye won by that player
+func completeBye(tx *gorm.DB, slot *models.TournamentMatch) (bool, error) {
+	if slot.Player1ID != nil && slot.Player2ID != nil {
+		return false, nil
+	}
+	winner := slot.Player1ID
+	if winner == nil {
+		winner = slot.Player2ID
+	}
+	now := time.Now()
+	slot.IsBye = true
+	slot.WinnerID = winner
+	slot.CompletedAt = &now
+	return true, tx.Save(slot).Error
+}
+
+// generateSingleElimination creates a knockout bracket for the seeded field.
+// Fields that are not a power of two are padded with byes, which go to the
+// top seeds and advance them to the second round straight away.
+func generateSingleElimination(tx *gorm.DB, tournament *models.Tournament, seeded []models.TournamentParticipant) error {
+	bracket, err := buildWinnersBracket(tx, tournament, seeded, nil, 0)
+	if err != nil {
+		return err
+	}
+
+	for i := range bracket[1] {
+		bye, err := completeBye(tx, &bracket[1][i])
+		if err != nil {
+			return err
+		}
+		if bye {
+			if err := advanceWinner(tx, tournament, &bracket[1][i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// advanceWinner moves the winner of a bracket match into its next match, or
+// completes the tournament when the final has been decided
+func advanceWinner(tx *gorm.DB, tournament *models.Tournament, slot *models.TournamentMatch) error {
+	if slot.WinnerID == nil {
+		return fmt.Errorf("tournament match %d has no winner", slot.ID)
+	}
+
+	if slot.NextMatchID == nil {
+		return completeTournament(tx, tournament, *slot.WinnerID)
+	}
+
+	column := "player1_id"
+	if slot.NextSlot == 2 {
+		column = "player2_id"
+	}
+	return tx.Model(&models.TournamentMatch{}).
+		Where("id = ?", *slot.NextMatchID).
+		Update(column, *slot.WinnerID).Error
+}
+
+// losersRoundSize is the number of matches in a round of the losers' bracket.
+// Odd rounds pair off survivors, even rounds bring in the players dropping
+// from the winners' bracket.
+func losersRoundSize(size, round int) int {
+	if round%2 == 1 {
+		return size >> ((round-1)/2 + 2)
+	}
+	return size >> (round/2 + 1)
+}
+
+// generateDoubleElimination creates the winners' bracket, the losers'
+// bracket, the grand final and (optionally) the bracket reset match.
+// Losers of winners' round 1 meet in losers' round 1; losers of later
+// winners' rounds drop into the even losers' rounds, in alternating order
+// so that players do not immediately meet the opponent who beat them.
+func generateDoubleElimination(tx *gorm.DB, tournament *models.Tournament, seeded []models.TournamentParticipant) error {
+	size, rounds := bracketSize(len(seeded))
+
+	var resetID *uint
+	if tournament.BracketReset {
+		reset := models.TournamentMatch{
+			TournamentID: tournament.ID,
+			Bracket:      models.BracketReset,
+			Round:        1,
+			Position:     1,
+		}
+		if err := tx.Create(&reset).Error; err != nil {
+			return err
+		}
+		resetID = &reset.ID
+	}
+
+	grandFinal := models.TournamentMatch{
+		TournamentID: tournament.ID,
+		Bracket:      models.BracketGrandFinal,
+		Round:        1,
+		Position:     1,
+		NextMatchID:  resetID,
+		NextSlot:     1,
+	}
+	if err := tx.Create(&grandFinal).Error; err != nil {
+		return err
+	}
+
+	// Losers' bracket from its final backwards; its winner is the grand final's player 2
+	losersRounds := 2 * (rounds - 1)
+	losers := make([][]models.TournamentMatch, losersRounds+1)
+	for round := losersRounds; round >= 1; round-- {
+		current := make([]models.TournamentMatch, losersRoundSize(size, round))
+		for i := range current {
+			current[i] = models.TournamentMatch{
+				TournamentID: tournament.ID,
+				Bracket:      models.BracketLosers,
+				Round:        round,
+				Position:     i + 1,
+			}
+			var nextID uint
+			switch {
+			case round == losersRounds:
+				nextID = grandFinal.ID
+				current[i].NextSlot = 2
+			case round%2 == 1:
+				nextID = losers[round+1][i].ID
+				current[i].NextSlot = 1
+			default:
+				nextID = losers[round+1][i/2].ID
+				current[i].NextSlot = i%2 + 1
+			}
+			current[i].NextMatchID = &nextID
+		}
+		if err := tx.Create(&current).Error; err != nil {
+			return err
+		}
+		losers[round] = current
+	}
+
+	winners, err := buildWinnersBracket(tx, tournament, seeded, &grandFinal.ID, 1)
+	if err != nil {
+		return err
+	}
+
+	// Route the losers of every winners' round into the losers' bracket
+	for round := 1; round <= rounds; round++ {
+		for i := range winners[round] {
+			slot := &winners[round][i]
+			var target uint
+			switch {
+			case rounds == 1:
+				// Two player field, the loser goes straight to the grand final
+				target = grandFinal.ID
+				slot.LoserNextSlot = 2
+			case round == 1:
+				target = losers[1][i/2].ID
+				slot.LoserNextSlot = i%2 + 1
+			default:
+				drop := losers[2*(round-1)]
+				index := i
+				if round%2 == 0 {
+					index = len(drop) - 1 - i
+				}
+				target = drop[index].ID
+				slot.LoserNextSlot = 2
+			}
+			slot.LoserNextMatchID = &target
+			if err := tx.Model(slot).Updates(map[string]interface{}{
+				"loser_next_match_id": target,
+				"loser_next_slot":     slot.LoserNextSlot,
+			}).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	// Resolve byes. A first round bye sends nobody to the losers' bracket, so
+	// losers' round 1 matches missing a feeder become walkovers, and a losers'
+	// round 1 match missing both feeders turns the next match into a walkover.
+	byes := make([]bool, len(winners[1]))
+	for i := range winners[1] {
+		bye, err := completeBye(tx, &winners[1][i])
+		if err != nil {
+			return err
+		}
+		byes[i] = bye
+		if bye {
+			if err := advanceDoubleElimination(tx, tournament, &winners[1][i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if losersRounds == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for i := range losers[1] {
+		live := 0
+		for _, bye := range byes[2*i : 2*i+2] {
+			if !bye {
+				live++
+			}
+		}
+
+		switch live {
+		case 1:
+			if err := tx.Model(&losers[1][i]).Update("is_bye", true).Error; err != nil {
+				return err
+			}
+		case 0:
+			if err := tx.Model(&losers[1][i]).Updates(map[string]interface{}{
+				"is_bye":       true,
+				"completed_at": now,
+			}).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&losers[2][i]).Update("is_bye", true).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// routePlayer places a player in a slot of a bracket match. When that match
+// is a walkover (its other side can never be filled) the player advances
+// straight through it.
+func routePlayer(tx *gorm.DB, tournament *models.Tournament, matchID uint, slotNumber int, playerID uint) error {
+	column := "player1_id"
+	if slotNumber == 2 {
+		column = "player2_id"
+	}
+	if err := tx.Model(&models.TournamentMatch{}).Where("id = ?", matchID).Update(column, playerID).Error; err != nil {
+		return err
+	}
+
+	var slot models.TournamentMatch
+	if err := tx.First(&slot, matchID).Error; err != nil {
+		return err
+	}
+	if !slot.IsBye || slot.CompletedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	slot.WinnerID = &playerID
+	slot.CompletedAt = &now
+	if err := tx.Save(&slot).Error; err != nil {
+		return err
+	}
+	return advanceDoubleElimination(tx, tournament, &slot)
+}
+
+// advanceDoubleElimination routes the winner and loser of a completed double
+// elimination match, and decides the tournament after the grand final
+func advanceDoubleElimination(tx *gorm.DB, tournament *models.Tournament, slot *models.TournamentMatch) error {
+	if slot.WinnerID == nil {
+		return fmt.Errorf("tournament match %d has no winner", slot.ID)
+	}
+	winner := *slot.WinnerID
+
+	var loser uint
+	if slot.Player1ID != nil && slot.Player2ID != nil {
+		loser = *slot.Player1ID
+		if loser == winner {
+			loser = *slot.Player2ID
+		}
+	}
+
+	switch slot.Bracket {
+	case models.BracketGrandFinal:
+		// The winners' bracket champion (player 1) only needs to win once
+		if winner == *slot.Player1ID || slot.NextMatchID == nil {
+			return completeTournament(tx, tournament, winner)
+		}
+		// The losers' bracket champion won: play the reset with the same sides
+		if err := routePlayer(tx, tournament, *slot.NextMatchID, 1, *slot.Player1ID); err != nil {
+			return err
+		}
+		return routePlayer(tx, tournament, *slot.NextMatchID, 2, winner)
+
+	case models.BracketReset:
+		return completeTournament(tx, tournament, winner)
+	}
+
+	if slot.NextMatchID != nil {
+		if err := routePlayer(tx, tournament, *slot.NextMatchID, slot.NextSlot, winner); err != nil {
+			return err
+		}
+	}
+	if loser != 0 && slot.LoserNextMatchID != nil {
+		if err := routePlayer(tx, tournament, *slot.LoserNextMatchID, slot.LoserNextSlot, loser); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"testing"
+
+	"stone-paper-scissors/models"
+)
+
+func TestBracketSeedOrder(t *testing.T) {
+	want := []int{1, 8, 4, 5, 2, 7, 3, 6}
+	got := bracketSeedOrder(8)
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestDoubleEliminationRoutingAndReset(t *testing.T) {
+	setupTestDB(t)
+
+	tournament, seed := startTestTournament(t, models.CreateTournamentRequest{
+		Format:       models.FormatDoubleElimination,
+		BracketReset: true,
+	}, 4)
+	slot := func(bracket models.BracketSide, round int) models.TournamentMatch {
+		return tournamentSlot(t, tournament.ID, bracket, round, 1)
+	}
+	winners := func(round, position int) models.TournamentMatch {
+		return tournamentSlot(t, tournament.ID, models.BracketWinners, round, position)
+	}
+
+	checkSlotPlayers(t, winners(1, 1), seed[0], seed[3])
+	checkSlotPlayers(t, winners(1, 2), seed[1], seed[2])
+	playTournamentMatch(t, winners(1, 1), seed[0])
+	playTournamentMatch(t, winners(1, 2), seed[2])
+
+	// First round losers meet in the losers' bracket
+	checkSlotPlayers(t, slot(models.BracketLosers, 1), seed[3], seed[1])
+	playTournamentMatch(t, slot(models.BracketLosers, 1), seed[1])
+
+	// The winners' final loser drops into the second losers' round
+	checkSlotPlayers(t, winners(2, 1), seed[0], seed[2])
+	playTournamentMatch(t, winners(2, 1), seed[0])
+	checkSlotPlayers(t, slot(models.BracketLosers, 2), seed[1], seed[2])
+	playTournamentMatch(t, slot(models.BracketLosers, 2), seed[1])
+
+	// The losers' bracket champion wins the grand final, forcing the reset
+	checkSlotPlayers(t, slot(models.BracketGrandFinal, 1), seed[0], seed[1])
+	playTournamentMatch(t, slot(models.BracketGrandFinal, 1), seed[1])
+	if current, err := GetTournament(tournament.ID); err != nil {
+		t.Fatal(err)
+	} else if current.Status != models.TournamentInProgress {
+		t.Errorf("after the grand final: got status %s, want in progress until the reset", current.Status)
+	}
+	checkSlotPlayers(t, slot(models.BracketReset, 1), seed[0], seed[1])
+	playTournamentMatch(t, slot(models.BracketReset, 1), seed[1])
+	checkTournamentWinner(t, tournament.ID, seed[1])
+}
+
+func TestDoubleEliminationByes(t *testing.T) {
+	setupTestDB(t)
+
+	tournament, seed := startTestTournament(t, models.CreateTournamentRequest{Format: models.FormatDoubleElimination}, 3)
+	slot := func(bracket models.BracketSide, round, position int) models.TournamentMatch {
+		return tournamentSlot(t, tournament.ID, bracket, round, position)
+	}
+
+	// Seed 1 has a bye, so the losers' round 1 match it would feed is a walkover
+	if bye := slot(models.BracketWinners, 1, 1); !bye.IsBye || bye.CompletedAt == nil {
+		t.Errorf("got bye %v, completed %v, want seed 1's first match to be a bye", bye.IsBye, bye.CompletedAt)
+	}
+	checkSlotPlayers(t, slot(models.BracketWinners, 2, 1), seed[0], nil)
+	if walkover := slot(models.BracketLosers, 1, 1); !walkover.IsBye || walkover.CompletedAt != nil {
+		t.Errorf("got bye %v, completed %v, want a walkover waiting for its player", walkover.IsBye, walkover.CompletedAt)
+	}
+
+	// The first round loser walks over into the second losers' round
+	playTournamentMatch(t, slot(models.BracketWinners, 1, 2), seed[2])
+	if walkover := slot(models.BracketLosers, 1, 1); walkover.WinnerID == nil || *walkover.WinnerID != seed[1].ID {
+		t.Errorf("got walkover winner %v, want Seed 2", walkover.WinnerID)
+	}
+	playTournamentMatch(t, slot(models.BracketWinners, 2, 1), seed[2])
+	checkSlotPlayers(t, slot(models.BracketLosers, 2, 1), seed[1], seed[0])
+	playTournamentMatch(t, slot(models.BracketLosers, 2, 1), seed[0])
+
+	// Without a bracket reset the winners' bracket champion wins the final outright
+	checkSlotPlayers(t, slot(models.BracketGrandFinal, 1, 1), seed[2], seed[0])
+	playTournamentMatch(t, slot(models.BracketGrandFinal, 1, 1), seed[2])
+	checkTournamentWinner(t, tournament.ID, seed[2])
+}
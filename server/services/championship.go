@@ -1,27 +1,38 @@
 package services
 
 import (
+	"errors"
+	"math"
+	"sort"
 	"time"
 
 	"stone-paper-scissors/config"
 	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
 )
 
-// TrackChampionshipChange checks if there's a rank change and updates championship reigns
-func TrackChampionshipChange(newChampionID uint, oldChampionID *uint) error {
-	db := config.DB
+// reignScope limits a reign query to one season, or to the all-time championship when seasonID is nil
+func reignScope(db *gorm.DB, seasonID *uint) *gorm.DB {
+	if seasonID == nil {
+		return db.Where("season_id IS NULL")
+	}
+	return db.Where("season_id = ?", *seasonID)
+}
 
+// TrackChampionshipChange checks if there's a rank change and updates championship reigns
+func TrackChampionshipChange(db *gorm.DB, newChampionID uint, oldChampionID *uint, seasonID *uint) error {
 	// If same champion, do nothing
 	if oldChampionID != nil && *oldChampionID == newChampionID {
 		return nil
 	}
 
-	now := time.Now()
+	now := time.Now().UTC()
 
 	// End the previous champion's reign if exists
 	if oldChampionID != nil && *oldChampionID != newChampionID {
 		var currentReign models.ChampionshipReign
-		err := db.Where("player_id = ? AND ended_at IS NULL", *oldChampionID).
+		err := reignScope(db, seasonID).Where("player_id = ? AND ended_at IS NULL", *oldChampionID).
 			First(&currentReign).Error
 		
 		if err == nil {
@@ -35,16 +46,67 @@ func TrackChampionshipChange(newChampionID uint, oldChampionID *uint) error {
 	// Start new reign for new champion
 	newReign := models.ChampionshipReign{
 		PlayerID:  newChampionID,
+		SeasonID:  seasonID,
 		StartedAt: now,
 	}
 
 	return db.Create(&newReign).Error
 }
 
+// RefreshChampion compares the current #1 player with the reigning champion
+// and starts a new reign when they differ, for the all-time ranking and for
+// the active season. It runs on db so rating writes can track the
+// championship in their own transaction.
+func RefreshChampion(db *gorm.DB) error {
+	var topPlayer models.Player
+	if err := db.Order("elo DESC").First(&topPlayer).Error; err != nil {
+		return err
+	}
+	if err := refreshReign(db, topPlayer.ID, nil); err != nil {
+		return err
+	}
+
+	season, err := activeSeason(db)
+	if err != nil || season == nil {
+		return err
+	}
+
+	var top models.SeasonRating
+	if err := seasonLeaderboardQuery(db, season.ID).First(&top).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Nobody has played in the season yet
+			return nil
+		}
+		return err
+	}
+	return refreshReign(db, top.PlayerID, &season.ID)
+}
+
+// refreshReign starts a reign for championID unless they already hold the title
+func refreshReign(db *gorm.DB, championID uint, seasonID *uint) error {
+	// Get previous champion if exists
+	prevChamp, _ := currentReign(db, seasonID)
+	var oldChampID *uint
+	if prevChamp != nil {
+		oldChampID = &prevChamp.PlayerID
+	}
+
+	// If there's a new champion, track the change
+	if oldChampID == nil || *oldChampID != championID {
+		return TrackChampionshipChange(db, championID, oldChampID, seasonID)
+	}
+	return nil
+}
+
 // GetCurrentChampion returns the current champion's reign
-func GetCurrentChampion() (*models.ChampionshipReign, error) {
+func GetCurrentChampion(seasonID *uint) (*models.ChampionshipReign, error) {
+	return currentReign(config.DB, seasonID)
+}
+
+// currentReign loads the open reign of the all-time or a season championship
+func currentReign(db *gorm.DB, seasonID *uint) (*models.ChampionshipReign, error) {
 	var reign models.ChampionshipReign
-	err := config.DB.
+	err := reignScope(db, seasonID).
 		Preload("Player").
 		Where("ended_at IS NULL").
 		Order("started_at DESC").
@@ -55,13 +117,14 @@ func GetCurrentChampion() (*models.ChampionshipReign, error) {
 	}
 
 	reign.Days = reign.CalculateDays()
+	reign.Hours = reign.CalculateHours()
 	return &reign, nil
 }
 
 // GetChampionshipHistory returns all championship reigns ordered by start date
-func GetChampionshipHistory(limit int) ([]models.ChampionshipReign, error) {
+func GetChampionshipHistory(seasonID *uint, limit int) ([]models.ChampionshipReign, error) {
 	var reigns []models.ChampionshipReign
-	query := config.DB.
+	query := reignScope(config.DB, seasonID).
 		Preload("Player").
 		Order("started_at DESC")
 
@@ -77,55 +140,144 @@ func GetChampionshipHistory(limit int) ([]models.ChampionshipReign, error) {
 	// Calculate days for each reign
 	for i := range reigns {
 		reigns[i].Days = reigns[i].CalculateDays()
+		reigns[i].Hours = reigns[i].CalculateHours()
 	}
 
 	return reigns, nil
 }
 
-// GetChampionStats returns aggregated statistics for all champions
-func GetChampionStats() ([]models.ChampionStats, error) {
-	var stats []models.ChampionStats
-
-	// Use DATE_PART instead of EXTRACT for better PostgreSQL compatibility
-	query := `
-		SELECT 
-			p.id as player_id,
-			p.name as player_name,
-			COUNT(cr.id) as total_reigns,
-			COALESCE(SUM(
-				DATE_PART('day', 
-					CASE 
-						WHEN cr.ended_at IS NULL THEN NOW() - cr.started_at
-						ELSE cr.ended_at - cr.started_at
-					END
-				)::INTEGER
-			), 0) as total_days,
-			COALESCE(MAX(
-				DATE_PART('day', 
-					CASE 
-						WHEN cr.ended_at IS NULL THEN NOW() - cr.started_at
-						ELSE cr.ended_at - cr.started_at
-					END
-				)::INTEGER
-			), 0) as longest_reign_days,
-			MAX(CASE WHEN cr.ended_at IS NULL THEN 1 ELSE 0 END) as current_champion,
-			MIN(cr.started_at) as first_crowned,
-			MAX(CASE WHEN cr.ended_at IS NOT NULL THEN cr.started_at END) as last_crowned
-		FROM players p
-		INNER JOIN championship_reigns cr ON p.id = cr.player_id
-		WHERE cr.deleted_at IS NULL
-		GROUP BY p.id, p.name
-		ORDER BY total_days DESC
-	`
-
-	err := config.DB.Raw(query).Scan(&stats).Error
-	return stats, err
+// GetChampionStats returns aggregated statistics for all champions. The
+// reigns are aggregated in Go rather than with date arithmetic in SQL, which
+// differs between the database drivers.
+func GetChampionStats(seasonID *uint) ([]models.ChampionStats, error) {
+	var reigns []models.ChampionshipReign
+	err := reignScope(config.DB, seasonID).
+		Preload("Player", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
+		Order("started_at ASC, id ASC").
+		Find(&reigns).Error
+	if err != nil {
+		return nil, err
+	}
+
+	defenses, err := loadTitleDefenses(config.DB, reigns)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[uint]int)
+	stats := []models.ChampionStats{}
+	for i := range reigns {
+		reign := &reigns[i]
+		days := reign.CalculateDays()
+		hours := reign.CalculateHours()
+
+		if _, ok := index[reign.PlayerID]; !ok {
+			index[reign.PlayerID] = len(stats)
+			stats = append(stats, models.ChampionStats{
+				PlayerID:     reign.PlayerID,
+				PlayerName:   reign.Player.Name,
+				FirstCrowned: reign.StartedAt,
+			})
+		}
+		stat := &stats[index[reign.PlayerID]]
+
+		stat.TotalReigns++
+		stat.TotalDays += days
+		stat.TotalHours += hours
+		stat.TitleDefenses += countTitleDefenses(defenses[reign.PlayerID], reign)
+		if days > stat.LongestReignDays {
+			stat.LongestReignDays = days
+		}
+		if hours > stat.LongestReignHours {
+			stat.LongestReignHours = hours
+		}
+		if reign.EndedAt == nil {
+			stat.CurrentChamp = true
+		} else {
+			startedAt := reign.StartedAt
+			stat.LastCrowned = &startedAt
+		}
+	}
+
+	for i := range stats {
+		stats[i].TotalHours = math.Round(stats[i].TotalHours*100) / 100
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].TotalHours > stats[j].TotalHours
+	})
+
+	return stats, nil
+}
+
+// titleDefense is a match a player won or drew
+type titleDefense struct {
+	playedAt time.Time
+	seasonID *uint
+}
+
+// loadTitleDefenses loads in a single query the matches the champions of
+// reigns (ordered by start) won or drew since the first reign started, in
+// the order they were played
+func loadTitleDefenses(db *gorm.DB, reigns []models.ChampionshipReign) (map[uint][]titleDefense, error) {
+	defenses := make(map[uint][]titleDefense)
+	if len(reigns) == 0 {
+		return defenses, nil
+	}
+	champions := make([]uint, 0, len(reigns))
+	for _, reign := range reigns {
+		if _, ok := defenses[reign.PlayerID]; !ok {
+			defenses[reign.PlayerID] = nil
+			champions = append(champions, reign.PlayerID)
+		}
+	}
+
+	var matches []models.Match
+	err := db.Select("player1_id", "player2_id", "player1_score", "player2_score", "season_id", "played_at").
+		Where("(player1_id IN ? AND player1_score >= player2_score) OR (player2_id IN ? AND player2_score >= player1_score)",
+			champions, champions).
+		Where("played_at >= ?", reigns[0].StartedAt.UTC()).
+		Find(&matches).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, match := range matches {
+		defense := titleDefense{playedAt: match.PlayedAt, seasonID: match.SeasonID}
+		if _, ok := defenses[match.Player1ID]; ok && match.Player1Score >= match.Player2Score {
+			defenses[match.Player1ID] = append(defenses[match.Player1ID], defense)
+		}
+		if _, ok := defenses[match.Player2ID]; ok && match.Player2Score >= match.Player1Score {
+			defenses[match.Player2ID] = append(defenses[match.Player2ID], defense)
+		}
+	}
+	for _, list := range defenses {
+		sort.Slice(list, func(i, j int) bool { return list[i].playedAt.Before(list[j].playedAt) })
+	}
+	return defenses, nil
+}
+
+// countTitleDefenses counts the defenses of the champion played during a
+// reign; matches of a season reign only count when played in that season
+func countTitleDefenses(defenses []titleDefense, reign *models.ChampionshipReign) int {
+	count := 0
+	i := sort.Search(len(defenses), func(i int) bool { return !defenses[i].playedAt.Before(reign.StartedAt) })
+	for ; i < len(defenses); i++ {
+		if reign.EndedAt != nil && !defenses[i].playedAt.Before(*reign.EndedAt) {
+			break
+		}
+		if reign.SeasonID == nil || (defenses[i].seasonID != nil && *defenses[i].seasonID == *reign.SeasonID) {
+			count++
+		}
+	}
+	return count
 }
 
 // GetPlayerChampionshipHistory returns all reigns for a specific player
-func GetPlayerChampionshipHistory(playerID uint) ([]models.ChampionshipReign, error) {
+func GetPlayerChampionshipHistory(playerID uint, seasonID *uint) ([]models.ChampionshipReign, error) {
 	var reigns []models.ChampionshipReign
-	err := config.DB.
+	err := reignScope(config.DB, seasonID).
 		Where("player_id = ?", playerID).
 		Order("started_at DESC").
 		Find(&reigns).Error
@@ -137,6 +289,7 @@ func GetPlayerChampionshipHistory(playerID uint) ([]models.ChampionshipReign, er
 	// Calculate days for each reign
 	for i := range reigns {
 		reigns[i].Days = reigns[i].CalculateDays()
+		reigns[i].Hours = reigns[i].CalculateHours()
 	}
 
 	return reigns, nil
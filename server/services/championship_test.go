@@ -0,0 +1,164 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/migrations"
+	"stone-paper-scissors/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupTestDB points config.DB at a migrated SQLite database for the test
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=5000"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	previous := config.DB
+	config.DB = db
+	t.Cleanup(func() {
+		config.DB = previous
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+// create stores rows in the test database
+func create(t *testing.T, rows ...interface{}) {
+	t.Helper()
+	for _, row := range rows {
+		if err := config.DB.Create(row).Error; err != nil {
+			t.Fatalf("create %T: %v", row, err)
+		}
+	}
+}
+
+// testMatch is a match between two players played at a time
+func testMatch(player1, player2 *models.Player, score1, score2 int, playedAt time.Time, seasonID *uint) *models.Match {
+	return &models.Match{
+		Player1ID:    player1.ID,
+		Player2ID:    player2.ID,
+		Player1Score: score1,
+		Player2Score: score2,
+		SeasonID:     seasonID,
+		PlayedAt:     playedAt,
+	}
+}
+
+func TestGetChampionStats(t *testing.T) {
+	setupTestDB(t)
+
+	alice := &models.Player{Name: "Alice", Elo: 1000}
+	bob := &models.Player{Name: "Bob", Elo: 1000}
+	create(t, alice, bob)
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(hours float64) time.Time {
+		return start.Add(time.Duration(hours * float64(time.Hour)))
+	}
+	ptr := func(t time.Time) *time.Time { return &t }
+	create(t,
+		&models.ChampionshipReign{PlayerID: alice.ID, StartedAt: at(0), EndedAt: ptr(at(48))},
+		&models.ChampionshipReign{PlayerID: bob.ID, StartedAt: at(48), EndedAt: ptr(at(60))},
+		&models.ChampionshipReign{PlayerID: alice.ID, StartedAt: at(60), EndedAt: ptr(at(61.5))},
+	)
+	create(t,
+		testMatch(alice, bob, 2, 1, at(-1), nil),   // before Alice was champion
+		testMatch(alice, bob, 2, 1, at(1), nil),    // defense
+		testMatch(bob, alice, 1, 1, at(2), nil),    // draw, defense
+		testMatch(bob, alice, 2, 0, at(3), nil),    // loss
+		testMatch(alice, bob, 0, 2, at(50), nil),   // Bob's defense
+		testMatch(alice, bob, 2, 0, at(55), nil),   // Bob lost, not a defense
+		testMatch(alice, bob, 3, 0, at(61), nil),   // defense in the second reign
+		testMatch(alice, bob, 3, 0, at(61.5), nil), // after the reign ended
+	)
+
+	stats, err := GetChampionStats(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("got %d champions, want 2", len(stats))
+	}
+
+	got := stats[0]
+	if got.PlayerID != alice.ID {
+		t.Fatalf("longest champion is %s, want Alice", got.PlayerName)
+	}
+	if got.TotalReigns != 2 || got.TotalHours != 49.5 || got.TotalDays != 2 {
+		t.Errorf("Alice: got %d reigns, %v hours, %d days, want 2, 49.5, 2", got.TotalReigns, got.TotalHours, got.TotalDays)
+	}
+	if got.LongestReignHours != 48 || got.LongestReignDays != 2 {
+		t.Errorf("Alice: got longest reign %v hours, %d days, want 48, 2", got.LongestReignHours, got.LongestReignDays)
+	}
+	if got.TitleDefenses != 3 {
+		t.Errorf("Alice: got %d title defenses, want 3", got.TitleDefenses)
+	}
+	if !got.FirstCrowned.Equal(at(0)) || got.LastCrowned == nil || !got.LastCrowned.Equal(at(60)) {
+		t.Errorf("Alice: got first crowned %v, last crowned %v", got.FirstCrowned, got.LastCrowned)
+	}
+	if got.CurrentChamp {
+		t.Error("Alice: reported as current champion")
+	}
+
+	got = stats[1]
+	if got.TotalReigns != 1 || got.TotalHours != 12 || got.TitleDefenses != 1 {
+		t.Errorf("Bob: got %d reigns, %v hours, %d title defenses, want 1, 12, 1", got.TotalReigns, got.TotalHours, got.TitleDefenses)
+	}
+}
+
+func TestGetChampionStatsOfSeason(t *testing.T) {
+	setupTestDB(t)
+
+	alice := &models.Player{Name: "Alice", Elo: 1000}
+	bob := &models.Player{Name: "Bob", Elo: 1000}
+	create(t, alice, bob)
+
+	start := time.Now().UTC().Add(-10 * time.Hour)
+	season := &models.Season{Name: "Spring", Status: models.SeasonActive, ResetPercent: 50, StartedAt: &start}
+	create(t, season)
+	create(t,
+		&models.ChampionshipReign{PlayerID: alice.ID, SeasonID: &season.ID, StartedAt: start},
+		// The all-time title is not part of the season statistics
+		&models.ChampionshipReign{PlayerID: bob.ID, StartedAt: start},
+	)
+	create(t,
+		testMatch(alice, bob, 2, 0, start.Add(time.Hour), &season.ID),
+		testMatch(alice, bob, 1, 1, start.Add(2*time.Hour), &season.ID),
+		testMatch(alice, bob, 2, 0, start.Add(3*time.Hour), nil), // outside the season
+	)
+
+	stats, err := GetChampionStats(&season.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].PlayerID != alice.ID {
+		t.Fatalf("got %+v, want Alice alone", stats)
+	}
+	if !stats[0].CurrentChamp || stats[0].TitleDefenses != 2 {
+		t.Errorf("got current %v with %d title defenses, want true with 2", stats[0].CurrentChamp, stats[0].TitleDefenses)
+	}
+	if stats[0].TotalHours < 10 || stats[0].TotalHours > 10.1 {
+		t.Errorf("got %v hours, want 10", stats[0].TotalHours)
+	}
+
+	allTime, err := GetChampionStats(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(allTime) != 1 || allTime[0].PlayerID != bob.ID || allTime[0].TitleDefenses != 1 {
+		t.Errorf("got all-time %+v, want Bob with 1 title defense", allTime)
+	}
+}
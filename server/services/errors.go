@@ -0,0 +1,20 @@
+package services
+
+import (
+	"fmt"
+)
+
+// ValidationError is returned when an operation is rejected because of its
+// input rather than a database failure; handlers answer it with 400
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// invalid builds a ValidationError with a formatted message
+func invalid(format string, args ...interface{}) error {
+	return &ValidationError{Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"time"
+
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// ExportFormat identifies archives written by WriteExport
+	ExportFormat = "stone-paper-scissors-export"
+	// ExportVersion is bumped whenever the layout of an archive changes.
+	// Version 2 added roles, API keys, audit events and settings, and leaves
+	// out two-factor secrets.
+	ExportVersion = 2
+	// exportManifestFile is the archive entry describing the other entries
+	exportManifestFile = "manifest.json"
+	// exportBatchSize is how many rows are read or written at a time
+	exportBatchSize = 500
+)
+
+// exportTable is an entity written to its own JSON lines file
+type exportTable struct {
+	name  string
+	model interface{}
+	omit  map[string]bool // columns left out of the archive
+}
+
+// exportTables are exported and restored in this order, so that every row
+// comes after the rows it refers to
+var exportTables = []exportTable{
+	{name: "roles", model: &models.Role{}},
+	{name: "admins", model: &models.Admin{}, omit: map[string]bool{"password_hash": true, "password_changed_at": true, "totp_secret": true, "totp_enabled": true, "totp_last_step": true}},
+	{name: "api_keys", model: &models.APIKey{}, omit: map[string]bool{"key_hash": true}},
+	{name: "players", model: &models.Player{}},
+	{name: "seasons", model: &models.Season{}},
+	{name: "season_ratings", model: &models.SeasonRating{}},
+	{name: "matches", model: &models.Match{}},
+	{name: "match_rounds", model: &models.MatchRound{}},
+	{name: "match_edits", model: &models.MatchEdit{}},
+	{name: "tournaments", model: &models.Tournament{}},
+	{name: "tournament_participants", model: &models.TournamentParticipant{}},
+	{name: "tournament_matches", model: &models.TournamentMatch{}},
+	{name: "championship_reigns", model: &models.ChampionshipReign{}},
+	{name: "audit_events", model: &models.AuditEvent{}},
+	{name: "settings", model: &models.Setting{}},
+}
+
+// ExportEntity describes one file of an export archive
+type ExportEntity struct {
+	Name    string   `json:"name"`
+	File    string   `json:"file"`
+	Count   int      `json:"count"`
+	Columns []string `json:"columns"`
+}
+
+// ExportManifest describes an export archive
+type ExportManifest struct {
+	Format       string         `json:"format"`
+	Version      int            `json:"version"`
+	CreatedAt    time.Time      `json:"created_at"`
+	Driver       string         `json:"driver"`
+	RatingSystem string         `json:"rating_system"`
+	Entities     []ExportEntity `json:"entities"`
+}
+
+// exportColumns returns the parsed schema of a table and its exported columns
+func exportColumns(db *gorm.DB, table exportTable) (*schema.Schema, []*schema.Field, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(table.model); err != nil {
+		return nil, nil, err
+	}
+
+	var fields []*schema.Field
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" || table.omit[field.DBName] {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].DBName < fields[j].DBName })
+	return stmt.Schema, fields, nil
+}
+
+// WriteExport writes a zip archive with one JSON lines file per entity and a
+// manifest describing them. Soft-deleted rows are included; admins are
+// written without their password hashes and two-factor secrets, and API keys
+// without their hashes. The tables are read in a single transaction so the
+// archive is consistent.
+func WriteExport(db *gorm.DB, w io.Writer) (*ExportManifest, error) {
+	manifest := &ExportManifest{
+		Format:       ExportFormat,
+		Version:      ExportVersion,
+		CreatedAt:    time.Now().UTC(),
+		Driver:       db.Dialector.Name(),
+		RatingSystem: Rating().Name(),
+		Entities:     []ExportEntity{},
+	}
+	archive := zip.NewWriter(w)
+
+	var options *sql.TxOptions
+	if !isSQLite(db) {
+		options = &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range exportTables {
+			entity, err := writeExportTable(tx, archive, table, manifest.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("%s: %w", table.name, err)
+			}
+			manifest.Entities = append(manifest.Entities, *entity)
+		}
+		return nil
+	}, options)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := createExportFile(archive, exportManifestFile, manifest.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return nil, err
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// createExportFile adds a compressed entry to an archive
+func createExportFile(archive *zip.Writer, name string, modified time.Time) (io.Writer, error) {
+	return archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+}
+
+// writeExportTable writes every row of a table as a JSON object per line
+func writeExportTable(tx *gorm.DB, archive *zip.Writer, table exportTable, modified time.Time) (*ExportEntity, error) {
+	tableSchema, fields, err := exportColumns(tx, table)
+	if err != nil {
+		return nil, err
+	}
+
+	entity := &ExportEntity{Name: table.name, File: table.name + ".jsonl", Columns: []string{}}
+	for _, field := range fields {
+		entity.Columns = append(entity.Columns, field.DBName)
+	}
+
+	file, err := createExportFile(archive, entity.File, modified)
+	if err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(file)
+
+	ctx := context.Background()
+	batch := reflect.New(reflect.SliceOf(tableSchema.ModelType))
+	result := tx.Unscoped().Model(table.model).
+		Order(tableSchema.PrioritizedPrimaryField.DBName+" ASC").
+		FindInBatches(batch.Interface(), exportBatchSize, func(_ *gorm.DB, _ int) error {
+			rows := batch.Elem()
+			for i := 0; i < rows.Len(); i++ {
+				record := make(map[string]interface{}, len(fields))
+				for _, field := range fields {
+					record[field.DBName], _ = field.ValueOf(ctx, rows.Index(i))
+				}
+				if err := encoder.Encode(record); err != nil {
+					return err
+				}
+				entity.Count++
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+// RestoreExport loads an archive written by WriteExport into db, which must
+// already have the schema and no data. Restored admins get passwordHash, as
+// archives carry no password hashes, and restored API keys are revoked.
+func RestoreExport(db *gorm.DB, archive *zip.Reader, passwordHash string) (*ExportManifest, error) {
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	manifestFile := files[exportManifestFile]
+	if manifestFile == nil {
+		return nil, invalid("The archive has no %s", exportManifestFile)
+	}
+	var manifest ExportManifest
+	if err := readJSONFile(manifestFile, &manifest); err != nil {
+		return nil, invalid("Invalid %s: %v", exportManifestFile, err)
+	}
+	if manifest.Format != ExportFormat {
+		return nil, invalid("Not a %s archive", ExportFormat)
+	}
+	if manifest.Version < 1 || manifest.Version > ExportVersion {
+		return nil, invalid("Unsupported archive version %d (this build reads up to %d)", manifest.Version, ExportVersion)
+	}
+
+	entities := make(map[string]ExportEntity, len(manifest.Entities))
+	for _, entity := range manifest.Entities {
+		entities[entity.Name] = entity
+	}
+	for name := range entities {
+		known := false
+		for _, table := range exportTables {
+			known = known || table.name == name
+		}
+		if !known {
+			return nil, invalid("Unknown entity %q in archive", name)
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range exportTables {
+			entity, ok := entities[table.name]
+			if !ok {
+				continue
+			}
+			file := files[entity.File]
+			if file == nil {
+				return invalid("The archive has no %s", entity.File)
+			}
+			count, err := restoreExportTable(tx, file, table, passwordHash)
+			if err != nil {
+				return fmt.Errorf("%s: %w", table.name, err)
+			}
+			if count != entity.Count {
+				return invalid("%s: the manifest lists %d rows but the archive has %d", table.name, entity.Count, count)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// restoreAPIKey gives a restored API key, whose secret is not in the
+// archive, the hash of a random secret and revokes it
+func restoreAPIKey(ctx context.Context, tableSchema *schema.Schema, keyHash *schema.Field, row reflect.Value) error {
+	secret, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+	if err := keyHash.Set(ctx, row, HashToken(secret)); err != nil {
+		return err
+	}
+	revokedAt := tableSchema.LookUpField("revoked_at")
+	if _, zero := revokedAt.ValueOf(ctx, row); zero {
+		return revokedAt.Set(ctx, row, time.Now())
+	}
+	return nil
+}
+
+// readJSONFile decodes a JSON document from an archive entry
+func readJSONFile(file *zip.File, v interface{}) error {
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return json.NewDecoder(reader).Decode(v)
+}
+
+// restoreExportTable inserts the rows of one JSON lines file and returns how many there were
+func restoreExportTable(tx *gorm.DB, file *zip.File, table exportTable, passwordHash string) (int, error) {
+	tableSchema, fields, err := exportColumns(tx, table)
+	if err != nil {
+		return 0, err
+	}
+	byColumn := make(map[string]*schema.Field, len(fields))
+	for _, field := range fields {
+		byColumn[field.DBName] = field
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	ctx := context.Background()
+	batch := reflect.MakeSlice(reflect.SliceOf(tableSchema.ModelType), 0, exportBatchSize)
+	flush := func() error {
+		if batch.Len() == 0 {
+			return nil
+		}
+		rows := reflect.New(batch.Type())
+		rows.Elem().Set(batch)
+		if err := tx.Omit(clause.Associations).Create(rows.Interface()).Error; err != nil {
+			return err
+		}
+		batch = batch.Slice(0, 0)
+		return nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	count := 0
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		count++
+
+		var record map[string]json.RawMessage
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return 0, invalid("line %d: %v", count, err)
+		}
+		row := reflect.New(tableSchema.ModelType).Elem()
+		for column, raw := range record {
+			field := byColumn[column]
+			if field == nil {
+				return 0, invalid("line %d: unknown column %q", count, column)
+			}
+			if err := json.Unmarshal(raw, field.ReflectValueOf(ctx, row).Addr().Interface()); err != nil {
+				return 0, invalid("line %d: column %s: %v", count, column, err)
+			}
+		}
+		if field := tableSchema.LookUpField("password_hash"); field != nil && table.omit["password_hash"] {
+			if err := field.Set(ctx, row, passwordHash); err != nil {
+				return 0, err
+			}
+		}
+		if field := tableSchema.LookUpField("key_hash"); field != nil && table.omit["key_hash"] {
+			if err := restoreAPIKey(ctx, tableSchema, field, row); err != nil {
+				return 0, err
+			}
+		}
+
+		batch = reflect.Append(batch, row)
+		if batch.Len() == exportBatchSize {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+)
+
+// writeTestExport exports config.DB and opens the archive
+func writeTestExport(t *testing.T) (*ExportManifest, *zip.Reader) {
+	t.Helper()
+	var buf bytes.Buffer
+	manifest, err := WriteExport(config.DB, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return manifest, archive
+}
+
+func TestExportRestoreRoundTrip(t *testing.T) {
+	setupTestDB(t)
+
+	admin := &models.Admin{
+		Username:     "root",
+		Email:        "root@example.com",
+		PasswordHash: "original-hash",
+		Role:         models.RoleSuperAdmin,
+		TOTPSecret:   "JBSWY3DPEHPK3PXP",
+		TOTPEnabled:  true,
+	}
+	create(t, admin)
+	key := &models.APIKey{AdminID: admin.ID, Name: "bot", Prefix: "sps_abcd", KeyHash: HashToken("secret"), Scopes: models.PermissionList{models.PermMatchesSubmit}}
+	alice := &models.Player{Name: "Alice", Elo: 1016.5}
+	bob := &models.Player{Name: "Bob", Elo: 983.5}
+	gone := &models.Player{Name: "Gone", Elo: DefaultRating}
+	create(t, key, alice, bob, gone)
+	if err := config.DB.Delete(gone).Error; err != nil {
+		t.Fatal(err)
+	}
+	match := ratedMatch(alice, bob, time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), 1000, 1016.5, 1000, 983.5)
+	match.Rounds = testRounds(t, []models.Throw{models.ThrowStone}, []models.Throw{models.ThrowScissor})
+	create(t, match, &models.Setting{Key: "rating_system", Value: "elo"})
+
+	manifest, archive := writeTestExport(t)
+	if manifest.Format != ExportFormat || manifest.Version != ExportVersion || len(manifest.Entities) != len(exportTables) {
+		t.Errorf("got manifest %+v", manifest)
+	}
+	counts := make(map[string]int)
+	for _, entity := range manifest.Entities {
+		counts[entity.Name] = entity.Count
+		for _, column := range entity.Columns {
+			if strings.Contains(column, "password") || strings.HasPrefix(column, "totp") || column == "key_hash" {
+				t.Errorf("%s: secret column %s is exported", entity.Name, column)
+			}
+		}
+	}
+	if counts["players"] != 3 || counts["matches"] != 1 || counts["match_rounds"] != 1 || counts["admins"] != 1 || counts["settings"] != 1 {
+		t.Errorf("got counts %v", counts)
+	}
+
+	// Restore into a new, empty database
+	setupTestDB(t)
+	if _, err := RestoreExport(config.DB, archive, "restored-hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	var players []models.Player
+	if err := config.DB.Unscoped().Order("id ASC").Find(&players).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(players) != 3 || players[0].Name != "Alice" || players[0].Elo != 1016.5 || !players[2].DeletedAt.Valid {
+		t.Errorf("got players %+v, want Alice, Bob and the deleted Gone", players)
+	}
+	var restored models.Match
+	if err := config.DB.Preload("Rounds").First(&restored, match.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !restored.PlayedAt.Equal(match.PlayedAt) || restored.Player1EloAfter != 1016.5 || restored.Player2EloChange != -16.5 ||
+		len(restored.Rounds) != 1 || restored.Rounds[0].Outcome != models.RoundPlayer1 {
+		t.Errorf("got match %+v, want the exported one", restored)
+	}
+
+	// Secrets are not carried over: admins get the given password hash and
+	// no second factor, and API keys come back revoked
+	var restoredAdmin models.Admin
+	if err := config.DB.First(&restoredAdmin, admin.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if restoredAdmin.Username != "root" || restoredAdmin.PasswordHash != "restored-hash" || restoredAdmin.TOTPSecret != "" || restoredAdmin.TOTPEnabled {
+		t.Errorf("got admin %+v, want the restored hash and no second factor", restoredAdmin)
+	}
+	var restoredKey models.APIKey
+	if err := config.DB.First(&restoredKey, key.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if restoredKey.RevokedAt == nil || restoredKey.KeyHash == key.KeyHash || restoredKey.Name != "bot" {
+		t.Errorf("got key %+v, want it restored revoked with a new hash", restoredKey)
+	}
+
+	// An archive of the restored database lists the same rows
+	again, _ := writeTestExport(t)
+	for _, entity := range again.Entities {
+		if entity.Count != counts[entity.Name] {
+			t.Errorf("%s: got %d rows after the round trip, want %d", entity.Name, entity.Count, counts[entity.Name])
+		}
+	}
+
+	// The target database must be empty
+	if _, err := RestoreExport(config.DB, archive, "restored-hash"); err == nil {
+		t.Error("restored an archive over existing data")
+	}
+}
+
+func TestRestoreExportRejectsForeignArchives(t *testing.T) {
+	setupTestDB(t)
+
+	archiveOf := func(manifest string) *zip.Reader {
+		t.Helper()
+		var buf bytes.Buffer
+		writer := zip.NewWriter(&buf)
+		if manifest != "" {
+			file, err := writer.Create(exportManifestFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.Write([]byte(manifest))
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return archive
+	}
+
+	for name, manifest := range map[string]string{
+		"no manifest":    "",
+		"other format":   `{"format":"backup","version":1}`,
+		"newer version":  `{"format":"stone-paper-scissors-export","version":99}`,
+		"unknown entity": `{"format":"stone-paper-scissors-export","version":2,"entities":[{"name":"secrets","file":"secrets.jsonl"}]}`,
+		"missing file":   `{"format":"stone-paper-scissors-export","version":2,"entities":[{"name":"players","file":"players.jsonl","count":1}]}`,
+	} {
+		if _, err := RestoreExport(config.DB, archiveOf(manifest), "x"); !isValidationError(err) {
+			t.Errorf("%s: got %v, want a validation error", name, err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"math"
+	"os"
+	"time"
+)
+
+const (
+	// glickoScale converts between the Glicko rating scale and the Glicko-2 internal scale
+	glickoScale = 173.7178
+	// glickoCenter is the rating that maps to 0 on the Glicko-2 internal scale
+	glickoCenter = 1500.0
+	// glickoEpsilon is the convergence tolerance of the volatility iteration
+	glickoEpsilon = 0.000001
+	// MinDeviation keeps very active players from freezing their rating entirely
+	MinDeviation = 30.0
+	// DefaultRatingPeriod is how long a player can go without playing before
+	// their deviation grows, unless GLICKO2_RATING_PERIOD is set
+	DefaultRatingPeriod = 7 * 24 * time.Hour
+)
+
+// Glicko2System implements Mark Glickman's Glicko-2 rating system.
+// Every match is treated as its own rating period, so deviation shrinks with
+// each game played and volatility tracks how erratic a player's results are.
+// Deviation grows again for every Period a player goes without playing.
+type Glicko2System struct {
+	// Tau constrains the change in volatility over time (0.3 - 1.2 is sensible)
+	Tau float64
+	// Period is the length of a rating period without a match
+	Period time.Duration
+}
+
+// NewGlicko2System returns a Glicko-2 system with the recommended tau and
+// the rating period from GLICKO2_RATING_PERIOD
+func NewGlicko2System() Glicko2System {
+	period := DefaultRatingPeriod
+	if d, err := time.ParseDuration(os.Getenv("GLICKO2_RATING_PERIOD")); err == nil && d > 0 {
+		period = d
+	}
+	return Glicko2System{Tau: 0.5, Period: period}
+}
+
+// Name implements RatingSystem
+func (Glicko2System) Name() string {
+	return "glicko2"
+}
+
+// g reduces the impact of a game based on the opponent's deviation
+func glickoG(phi float64) float64 {
+	return 1.0 / math.Sqrt(1.0+3.0*phi*phi/(math.Pi*math.Pi))
+}
+
+// glickoE is the expected score of a player (mu) against an opponent (muJ, phiJ)
+func glickoE(mu, muJ, phiJ float64) float64 {
+	return 1.0 / (1.0 + math.Exp(-glickoG(phiJ)*(mu-muJ)))
+}
+
+func toGlicko2(rating PlayerRating) (mu, phi float64) {
+	return (rating.Rating - glickoCenter) / glickoScale, rating.Deviation / glickoScale
+}
+
+// Update implements RatingSystem
+func (s Glicko2System) Update(player1, player2 PlayerRating, player1Score, player2Score int) RatingResult {
+	var actual1, actual2 float64
+	if player1Score > player2Score {
+		actual1, actual2 = 1.0, 0.0
+	} else if player2Score > player1Score {
+		actual1, actual2 = 0.0, 1.0
+	} else {
+		actual1, actual2 = 0.5, 0.5
+	}
+
+	new1 := s.rate(player1, player2, actual1)
+	new2 := s.rate(player2, player1, actual2)
+
+	return RatingResult{
+		Player1:       new1,
+		Player2:       new2,
+		Player1Change: math.Round((new1.Rating-player1.Rating)*100) / 100,
+		Player2Change: math.Round((new2.Rating-player2.Rating)*100) / 100,
+	}
+}
+
+// rate applies a single game (score 1, 0.5 or 0) against opponent to player
+func (s Glicko2System) rate(player, opponent PlayerRating, score float64) PlayerRating {
+	mu, phi := toGlicko2(player)
+	muJ, phiJ := toGlicko2(opponent)
+	sigma := player.Volatility
+
+	g := glickoG(phiJ)
+	e := glickoE(mu, muJ, phiJ)
+	v := 1.0 / (g * g * e * (1.0 - e))
+	delta := v * g * (score - e)
+
+	newSigma := s.volatility(phi, sigma, v, delta)
+
+	phiStar := math.Sqrt(phi*phi + newSigma*newSigma)
+	newPhi := 1.0 / math.Sqrt(1.0/(phiStar*phiStar)+1.0/v)
+	newMu := mu + newPhi*newPhi*g*(score-e)
+
+	deviation := math.Min(DefaultDeviation, math.Max(MinDeviation, newPhi*glickoScale))
+
+	player.Rating = math.Round((newMu*glickoScale+glickoCenter)*100) / 100
+	player.Deviation = math.Round(deviation*100) / 100
+	player.Volatility = newSigma
+	return player
+}
+
+// Idle implements InactivityRating: the deviation grows as in step 6 of the
+// paper once for every rating period in idle, up to that of a new player
+func (s Glicko2System) Idle(rating PlayerRating, idle time.Duration) PlayerRating {
+	if s.Period <= 0 || idle < s.Period {
+		return rating
+	}
+	periods := float64(idle / s.Period)
+	_, phi := toGlicko2(rating)
+	phi = math.Sqrt(phi*phi + periods*rating.Volatility*rating.Volatility)
+	deviation := math.Min(DefaultDeviation, phi*glickoScale)
+	rating.Deviation = math.Round(deviation*100) / 100
+	return rating
+}
+
+// volatility finds the new volatility using the Illinois algorithm (step 5 of the paper)
+func (s Glicko2System) volatility(phi, sigma, v, delta float64) float64 {
+	tau := s.Tau
+	if tau <= 0 {
+		tau = 0.5
+	}
+
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		num := ex * (delta*delta - phi*phi - v - ex)
+		den := 2.0 * math.Pow(phi*phi+v+ex, 2)
+		return num/den - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > glickoEpsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA = fA / 2
+		}
+		B, fB = C, fC
+	}
+
+	return math.Exp(A / 2)
+}
+
+// ExpectedScore implements RatingSystem
+func (Glicko2System) ExpectedScore(player, opponent PlayerRating) float64 {
+	mu, _ := toGlicko2(player)
+	muJ, phiJ := toGlicko2(opponent)
+	return glickoE(mu, muJ, phiJ)
+}
+
+// WinProbability implements RatingSystem.
+// Both deviations are combined so that two uncertain players give a prediction closer to 50%.
+func (Glicko2System) WinProbability(player1, player2 PlayerRating) float64 {
+	mu1, phi1 := toGlicko2(player1)
+	mu2, phi2 := toGlicko2(player2)
+	combined := math.Sqrt(phi1*phi1 + phi2*phi2)
+	return glickoE(mu1, mu2, combined) * 100
+}
+
+// Uncertainty implements RatingSystem
+func (Glicko2System) Uncertainty(rating PlayerRating) float64 {
+	return rating.Deviation
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+func TestGlicko2Idle(t *testing.T) {
+	system := Glicko2System{Tau: 0.5, Period: 24 * time.Hour}
+	rating := PlayerRating{Rating: 1200, Deviation: 50, Volatility: 0.06}
+
+	if got := system.Idle(rating, 23*time.Hour); got != rating {
+		t.Errorf("less than a period: got %+v, want %+v", got, rating)
+	}
+
+	got := system.Idle(rating, 50*time.Hour)
+	phi := 50 / glickoScale
+	want := math.Round(math.Sqrt(phi*phi+2*0.06*0.06)*glickoScale*100) / 100
+	if got.Deviation != want || got.Rating != rating.Rating || got.Volatility != rating.Volatility {
+		t.Errorf("two periods: got %+v, want deviation %v", got, want)
+	}
+
+	if got := system.Idle(rating, 10*365*24*time.Hour); got.Deviation != DefaultDeviation {
+		t.Errorf("years: got deviation %v, want %v", got.Deviation, DefaultDeviation)
+	}
+}
+
+func TestGlicko2InactivityMatchesReplay(t *testing.T) {
+	setupTestDB(t)
+	previous := Rating()
+	SetRatingSystem(Glicko2System{Tau: 0.5, Period: 24 * time.Hour})
+	t.Cleanup(func() { SetRatingSystem(previous) })
+
+	alice := &models.Player{Name: "Alice", Elo: 1000}
+	bob := &models.Player{Name: "Bob", Elo: 1000}
+	carol := &models.Player{Name: "Carol", Elo: 1000}
+	create(t, alice, bob, carol)
+
+	// Alice and Bob play daily, then Carol meets Bob after a month off
+	system := Rating()
+	start := time.Now().UTC().Add(-60 * 24 * time.Hour)
+	day := func(n int) time.Time { return start.Add(time.Duration(n) * 24 * time.Hour) }
+	record := func(player1, player2 *models.Player, score1, score2 int, playedAt time.Time) {
+		t.Helper()
+		err := WriteTransaction(func(tx *gorm.DB) error {
+			_, err := RecordMatch(tx, MatchInput{
+				Player1ID:    player1.ID,
+				Player2ID:    player2.ID,
+				Player1Score: score1,
+				Player2Score: score2,
+				PlayedAt:     playedAt,
+			})
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	load := func(player *models.Player) PlayerRating {
+		t.Helper()
+		var stored models.Player
+		if err := config.DB.First(&stored, player.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return RatingFromPlayer(&stored)
+	}
+
+	for n := 0; n < 10; n++ {
+		record(alice, bob, 2, n%3, day(n))
+	}
+	record(alice, carol, 2, 1, day(12))
+	carolBefore, bobBefore := load(carol), load(bob)
+	record(carol, bob, 2, 0, day(45))
+
+	want := system.Update(system.(InactivityRating).Idle(carolBefore, 33*24*time.Hour),
+		system.(InactivityRating).Idle(bobBefore, 36*24*time.Hour), 2, 0)
+	if got := load(carol); got.Rating != want.Player1.Rating || got.Deviation != want.Player1.Deviation {
+		t.Errorf("Carol: got %+v, want %+v", got, want.Player1)
+	}
+	if got := load(bob); got.Rating != want.Player2.Rating || got.Deviation != want.Player2.Deviation {
+		t.Errorf("Bob: got %+v, want %+v", got, want.Player2)
+	}
+	if active := system.Update(carolBefore, bobBefore, 2, 0); active.Player1.Deviation >= want.Player1.Deviation {
+		t.Errorf("the month off did not raise the deviation: %v without, %v with", active.Player1.Deviation, want.Player1.Deviation)
+	}
+
+	report, err := ReplayRatings(config.DB, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.PlayersChanged) > 0 || len(report.MatchesChanged) > 0 {
+		t.Errorf("replay differs from the recorded ratings: %+v %+v", report.PlayersChanged, report.MatchesChanged)
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+)
+
+// Results of a meeting from the player's point of view
+const (
+	ResultWin  = "win"
+	ResultLoss = "loss"
+	ResultDraw = "draw"
+)
+
+// HeadToHeadMeeting is a single match between the two players
+type HeadToHeadMeeting struct {
+	MatchID       uint      `json:"match_id"`
+	Date          time.Time `json:"date"`
+	Result        string    `json:"result"`
+	PlayerScore   int       `json:"player_score"`
+	OpponentScore int       `json:"opponent_score"`
+	EloChange     float64   `json:"elo_change"`
+}
+
+// HeadToHeadStreak is a run of consecutive results
+type HeadToHeadStreak struct {
+	Result string `json:"result"`
+	Length int    `json:"length"`
+}
+
+// HeadToHead is the record of a player against one opponent
+type HeadToHead struct {
+	PlayerID          uint                `json:"player_id"`
+	PlayerName        string              `json:"player_name"`
+	OpponentID        uint                `json:"opponent_id"`
+	OpponentName      string              `json:"opponent_name"`
+	Meetings          int                 `json:"meetings"`
+	Wins              int                 `json:"wins"`
+	Losses            int                 `json:"losses"`
+	Draws             int                 `json:"draws"`
+	WinRate           float64             `json:"win_rate"` // percentage of meetings won by the player
+	ScoreFor          int                 `json:"score_for"`
+	ScoreAgainst      int                 `json:"score_against"`
+	EloExchanged      float64             `json:"elo_exchanged"` // net rating the player gained in these meetings
+	LongestWinStreak  int                 `json:"longest_win_streak"`
+	LongestLossStreak int                 `json:"longest_loss_streak"`
+	CurrentStreak     *HeadToHeadStreak   `json:"current_streak"`
+	LastMeetings      []HeadToHeadMeeting `json:"last_meetings"`
+}
+
+// GetHeadToHead returns the record of player against opponent, with the last
+// meetings most recent first
+func GetHeadToHead(player, opponent *models.Player, last int) (*HeadToHead, error) {
+	if player.ID == opponent.ID {
+		return nil, invalid("A player has no head-to-head record against themselves")
+	}
+
+	var matches []models.Match
+	err := config.DB.
+		Where("(player1_id = ? AND player2_id = ?) OR (player1_id = ? AND player2_id = ?)",
+			player.ID, opponent.ID, opponent.ID, player.ID).
+		Order("played_at ASC, id ASC").
+		Find(&matches).Error
+	if err != nil {
+		return nil, err
+	}
+
+	h2h := &HeadToHead{
+		PlayerID:     player.ID,
+		PlayerName:   player.Name,
+		OpponentID:   opponent.ID,
+		OpponentName: opponent.Name,
+		Meetings:     len(matches),
+		LastMeetings: []HeadToHeadMeeting{},
+	}
+
+	meetings := make([]HeadToHeadMeeting, 0, len(matches))
+	var streak *HeadToHeadStreak
+	for _, match := range matches {
+		meeting := HeadToHeadMeeting{MatchID: match.ID, Date: match.PlayedAt}
+		if match.Player1ID == player.ID {
+			meeting.PlayerScore = match.Player1Score
+			meeting.OpponentScore = match.Player2Score
+			meeting.EloChange = match.Player1EloChange
+		} else {
+			meeting.PlayerScore = match.Player2Score
+			meeting.OpponentScore = match.Player1Score
+			meeting.EloChange = match.Player2EloChange
+		}
+
+		switch {
+		case match.WinnerID == nil:
+			meeting.Result = ResultDraw
+			h2h.Draws++
+		case *match.WinnerID == player.ID:
+			meeting.Result = ResultWin
+			h2h.Wins++
+		default:
+			meeting.Result = ResultLoss
+			h2h.Losses++
+		}
+
+		h2h.ScoreFor += meeting.PlayerScore
+		h2h.ScoreAgainst += meeting.OpponentScore
+		h2h.EloExchanged += meeting.EloChange
+
+		if streak == nil || streak.Result != meeting.Result {
+			streak = &HeadToHeadStreak{Result: meeting.Result}
+		}
+		streak.Length++
+		switch {
+		case streak.Result == ResultWin && streak.Length > h2h.LongestWinStreak:
+			h2h.LongestWinStreak = streak.Length
+		case streak.Result == ResultLoss && streak.Length > h2h.LongestLossStreak:
+			h2h.LongestLossStreak = streak.Length
+		}
+
+		meetings = append(meetings, meeting)
+	}
+
+	h2h.CurrentStreak = streak
+	h2h.WinRate = percentage(h2h.Wins, h2h.Meetings)
+	h2h.EloExchanged = math.Round(h2h.EloExchanged*100) / 100
+
+	for i := len(meetings) - 1; i >= 0 && len(h2h.LastMeetings) < last; i-- {
+		h2h.LastMeetings = append(h2h.LastMeetings, meetings[i])
+	}
+
+	return h2h, nil
+}
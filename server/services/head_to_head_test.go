@@ -0,0 +1,88 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"stone-paper-scissors/models"
+)
+
+func TestGetHeadToHead(t *testing.T) {
+	setupTestDB(t)
+
+	alice := &models.Player{Name: "Alice", Elo: 1000}
+	bob := &models.Player{Name: "Bob", Elo: 1000}
+	carol := &models.Player{Name: "Carol", Elo: 1000}
+	create(t, alice, bob, carol)
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	meeting := func(hours int, player1, player2 *models.Player, score1, score2 int, change1 float64) *models.Match {
+		match := testMatch(player1, player2, score1, score2, start.Add(time.Duration(hours)*time.Hour), nil)
+		switch {
+		case score1 > score2:
+			match.WinnerID = &player1.ID
+		case score2 > score1:
+			match.WinnerID = &player2.ID
+		}
+		match.Player1EloChange, match.Player2EloChange = change1, -change1
+		return match
+	}
+	// Alice: win, win, loss, draw, win
+	matches := []*models.Match{
+		meeting(0, alice, bob, 2, 0, 16),
+		meeting(1, bob, alice, 1, 2, -14.5),
+		meeting(2, alice, bob, 0, 2, -18.25),
+		meeting(3, bob, alice, 1, 1, 0.5),
+		meeting(4, alice, bob, 3, 1, 15),
+	}
+	for _, match := range matches {
+		create(t, match)
+	}
+	create(t, meeting(5, alice, carol, 0, 2, -16))
+
+	h2h, err := GetHeadToHead(alice, bob, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h2h.Meetings != 5 || h2h.Wins != 3 || h2h.Losses != 1 || h2h.Draws != 1 || h2h.WinRate != 60 {
+		t.Errorf("got %d meetings %d-%d-%d at %v%%, want 5 meetings 3-1-1 at 60%%", h2h.Meetings, h2h.Wins, h2h.Losses, h2h.Draws, h2h.WinRate)
+	}
+	if h2h.ScoreFor != 8 || h2h.ScoreAgainst != 5 || h2h.EloExchanged != 26.75 {
+		t.Errorf("got score %d-%d and %v rating exchanged, want 8-5 and 26.75", h2h.ScoreFor, h2h.ScoreAgainst, h2h.EloExchanged)
+	}
+	if h2h.LongestWinStreak != 2 || h2h.LongestLossStreak != 1 {
+		t.Errorf("got longest streaks of %d wins and %d losses, want 2 and 1", h2h.LongestWinStreak, h2h.LongestLossStreak)
+	}
+	if h2h.CurrentStreak == nil || *h2h.CurrentStreak != (HeadToHeadStreak{Result: ResultWin, Length: 1}) {
+		t.Errorf("got current streak %+v, want a single win", h2h.CurrentStreak)
+	}
+	if len(h2h.LastMeetings) != 2 || h2h.LastMeetings[0].MatchID != matches[4].ID || h2h.LastMeetings[1].MatchID != matches[3].ID {
+		t.Fatalf("got last meetings %+v, want the last two, most recent first", h2h.LastMeetings)
+	}
+	if got := h2h.LastMeetings[1]; got.Result != ResultDraw || got.PlayerScore != 1 || got.OpponentScore != 1 || got.EloChange != -0.5 {
+		t.Errorf("got %+v, want Alice's side of the draw", got)
+	}
+
+	// The same record from the other side
+	h2h, err = GetHeadToHead(bob, alice, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h2h.Wins != 1 || h2h.Losses != 3 || h2h.ScoreFor != 5 || h2h.EloExchanged != -26.75 || len(h2h.LastMeetings) != 5 {
+		t.Errorf("got %d-%d, score for %d, %v exchanged and %d meetings, want Alice's record reversed",
+			h2h.Wins, h2h.Losses, h2h.ScoreFor, h2h.EloExchanged, len(h2h.LastMeetings))
+	}
+
+	// Players who never met have an empty record
+	h2h, err = GetHeadToHead(bob, carol, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h2h.Meetings != 0 || h2h.CurrentStreak != nil || h2h.LastMeetings == nil || h2h.WinRate != 0 {
+		t.Errorf("got %+v, want an empty record", h2h)
+	}
+
+	if _, err := GetHeadToHead(alice, alice, 10); !isValidationError(err) {
+		t.Errorf("against themselves: got %v, want a validation error", err)
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultIdempotencyTTL is how long a stored response is replayed when
+// IDEMPOTENCY_TTL is not set
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+var (
+	// ErrIdempotencyKeyReused is returned when a key is sent again with a different request
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+	// ErrIdempotencyInProgress is returned when the original request with the key has not finished yet
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still being processed")
+)
+
+// IdempotencyTTL returns the configured retention of idempotency keys
+func IdempotencyTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return DefaultIdempotencyTTL
+}
+
+// HashRequest fingerprints a request so that a reused key can be told apart from a retry
+func HashRequest(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(path))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// BeginIdempotentRequest claims key for a request. It returns the stored
+// record with replay set when the request was already answered, or a fresh
+// record the caller must complete or abandon once the request is handled.
+func BeginIdempotentRequest(adminID uint, key, requestHash string) (record *models.IdempotencyKey, replay bool, err error) {
+	now := time.Now()
+
+	// An expired key is free to be used again
+	if err := config.DB.
+		Where("admin_id = ? AND idempotency_key = ? AND expires_at <= ?", adminID, key, now).
+		Delete(&models.IdempotencyKey{}).Error; err != nil {
+		return nil, false, err
+	}
+
+	record = &models.IdempotencyKey{
+		AdminID:     adminID,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   now.Add(IdempotencyTTL()),
+	}
+	result := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return record, false, nil
+	}
+
+	// The key is taken: this is a retry, a reused key or a concurrent duplicate
+	var existing models.IdempotencyKey
+	if err := config.DB.Where("admin_id = ? AND idempotency_key = ?", adminID, key).First(&existing).Error; err != nil {
+		return nil, false, err
+	}
+	if existing.RequestHash != requestHash {
+		return nil, false, ErrIdempotencyKeyReused
+	}
+	if existing.StatusCode == 0 {
+		return nil, false, ErrIdempotencyInProgress
+	}
+	return &existing, true, nil
+}
+
+// CompleteIdempotentRequest stores the response sent for a claimed key
+func CompleteIdempotentRequest(record *models.IdempotencyKey, statusCode int, body []byte) error {
+	return config.DB.Model(record).Updates(map[string]interface{}{
+		"status_code":   statusCode,
+		"response_body": string(body),
+	}).Error
+}
+
+// AbandonIdempotentRequest releases a claimed key so that the request can be retried
+func AbandonIdempotentRequest(record *models.IdempotencyKey) error {
+	return config.DB.Delete(record).Error
+}
+
+// CleanupIdempotencyKeys deletes every expired idempotency key
+func CleanupIdempotencyKeys(db *gorm.DB) (int64, error) {
+	result := db.Where("expires_at <= ?", time.Now()).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
+
+// StartIdempotencyCleanup deletes expired idempotency keys every interval in the background
+func StartIdempotencyCleanup(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			deleted, err := CleanupIdempotencyKeys(config.DB)
+			if err != nil {
+				log.Printf("Failed to clean up idempotency keys: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("Cleaned up %d expired idempotency keys", deleted)
+			}
+		}
+	}()
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+// playedAtLayouts are the accepted formats of a played-at timestamp; the ones
+// without a zone are read as UTC
+var playedAtLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParsePlayedAt reads the time a match was played
+func ParsePlayedAt(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range playedAtLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, invalid("played_at %q is not a date (use YYYY-MM-DD, YYYY-MM-DD HH:MM:SS or RFC 3339)", value)
+}
+
+// ParseImport reads match rows in the given format, "csv" or "json"
+func ParseImport(format string, r io.Reader) ([]models.ImportMatchRow, error) {
+	switch strings.ToLower(format) {
+	case "csv":
+		return ParseImportCSV(r)
+	case "json":
+		return ParseImportJSON(r)
+	}
+	return nil, invalid("Unknown import format %q, use csv or json", format)
+}
+
+// ParseImportJSON reads a JSON array of match rows
+func ParseImportJSON(r io.Reader) ([]models.ImportMatchRow, error) {
+	var rows []models.ImportMatchRow
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		return nil, invalid("Invalid JSON, expected an array of matches: %v", err)
+	}
+	return rows, nil
+}
+
+// ParseImportCSV reads match rows from a CSV file with a header line. The
+// columns are named like the JSON fields; player1 and player2 columns take
+// either an ID or a name.
+func ParseImportCSV(r io.Reader) ([]models.ImportMatchRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, invalid("The CSV file is empty")
+	}
+	if err != nil {
+		return nil, invalid("Invalid CSV: %v", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "player1", "player2", "player1_id", "player2_id", "player1_name", "player2_name",
+			"player1_score", "player2_score", "played_at":
+			columns[name] = i
+		default:
+			return nil, invalid("Unknown CSV column %q", name)
+		}
+	}
+	for _, required := range []string{"player1_score", "player2_score", "played_at"} {
+		if _, ok := columns[required]; !ok {
+			return nil, invalid("The CSV file has no %s column", required)
+		}
+	}
+
+	var rows []models.ImportMatchRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, invalid("Invalid CSV: %v", err)
+		}
+
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		var row models.ImportMatchRow
+		var problems []string
+		number := func(name string) int {
+			value := cell(name)
+			if value == "" {
+				return 0
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s %q is not a whole number", name, value))
+			}
+			return n
+		}
+		player := func(prefix string, id *uint, name *string) {
+			*name = cell(prefix + "_name")
+			if value := cell(prefix); value != "" {
+				if n, err := strconv.ParseUint(value, 10, 32); err == nil {
+					*id = uint(n)
+				} else {
+					*name = value
+				}
+			}
+			if cell(prefix+"_id") != "" {
+				*id = uint(number(prefix + "_id"))
+			}
+		}
+
+		player("player1", &row.Player1ID, &row.Player1Name)
+		player("player2", &row.Player2ID, &row.Player2Name)
+		row.Player1Score = number("player1_score")
+		row.Player2Score = number("player2_score")
+		row.PlayedAt = cell("played_at")
+		row.ParseError = strings.Join(problems, "; ")
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// importPlayer is one side of an import row, an existing player or a name
+// that will be created
+type importPlayer struct {
+	id   uint
+	name string
+}
+
+// importMatch is a validated import row
+type importMatch struct {
+	index    int
+	playedAt time.Time
+	player1  importPlayer
+	player2  importPlayer
+	score1   int
+	score2   int
+}
+
+// resolveImportPlayer finds the player on one side of an import row
+func resolveImportPlayer(db *gorm.DB, label string, id uint, name string) (importPlayer, error) {
+	name = strings.TrimSpace(name)
+	if id != 0 {
+		var player models.Player
+		if err := forUpdate(db).First(&player, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return importPlayer{}, invalid("%s #%d not found", label, id)
+			}
+			return importPlayer{}, err
+		}
+		return importPlayer{id: player.ID, name: player.Name}, nil
+	}
+	if name == "" {
+		return importPlayer{}, invalid("%s needs an ID or a name", label)
+	}
+
+	var player models.Player
+	err := forUpdate(db).Unscoped().Where("name = ?", name).First(&player).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return importPlayer{name: name}, nil
+	}
+	if err != nil {
+		return importPlayer{}, err
+	}
+	if player.DeletedAt.Valid {
+		return importPlayer{}, invalid("%s %q has been deleted", label, name)
+	}
+	return importPlayer{id: player.ID, name: player.Name}, nil
+}
+
+// validateImportRow checks a single import row against the database
+func validateImportRow(db *gorm.DB, index int, row models.ImportMatchRow, now time.Time) (*importMatch, error) {
+	if row.ParseError != "" {
+		return nil, invalid("%s", row.ParseError)
+	}
+	if strings.TrimSpace(row.PlayedAt) == "" {
+		return nil, invalid("played_at is required")
+	}
+	playedAt, err := ParsePlayedAt(row.PlayedAt)
+	if err != nil {
+		return nil, err
+	}
+	if playedAt.After(now) {
+		return nil, invalid("played_at is in the future")
+	}
+	if row.Player1Score < 0 || row.Player2Score < 0 {
+		return nil, invalid("Scores cannot be negative")
+	}
+
+	player1, err := resolveImportPlayer(db, "Player 1", row.Player1ID, row.Player1Name)
+	if err != nil {
+		return nil, err
+	}
+	player2, err := resolveImportPlayer(db, "Player 2", row.Player2ID, row.Player2Name)
+	if err != nil {
+		return nil, err
+	}
+	if player1.name == player2.name {
+		return nil, invalid("Player cannot play against themselves")
+	}
+
+	return &importMatch{
+		index:    index,
+		playedAt: playedAt,
+		player1:  player1,
+		player2:  player2,
+		score1:   row.Player1Score,
+		score2:   row.Player2Score,
+	}, nil
+}
+
+// errImportInvalid rolls back an import with invalid rows
+var errImportInvalid = errors.New("the import has invalid rows")
+
+// validateImport checks every import row against db and reports on each,
+// and returns the valid rows in the order they are to be stored
+func validateImport(db *gorm.DB, rows []models.ImportMatchRow, dryRun bool) (*models.ImportReport, []*importMatch, error) {
+	report := &models.ImportReport{
+		DryRun:         dryRun,
+		Total:          len(rows),
+		PlayersCreated: []string{},
+		Rows:           make([]models.ImportRowResult, len(rows)),
+	}
+
+	now := time.Now()
+	created := make(map[string]bool)
+	var valid []*importMatch
+	for i, row := range rows {
+		result := &report.Rows[i]
+		result.Row = i + 1
+
+		match, err := validateImportRow(db, i, row, now)
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			result.Status = models.ImportInvalid
+			result.Error = err.Error()
+			report.Invalid++
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		result.Status = models.ImportValid
+		result.Player1Name = match.player1.name
+		result.Player2Name = match.player2.name
+		result.PlayedAt = &match.playedAt
+		report.Valid++
+		valid = append(valid, match)
+
+		for _, player := range []importPlayer{match.player1, match.player2} {
+			if player.id == 0 && !created[player.name] {
+				created[player.name] = true
+				report.PlayersCreated = append(report.PlayersCreated, player.name)
+			}
+		}
+	}
+
+	// Matches are stored oldest first so that equal timestamps keep file order
+	sort.SliceStable(valid, func(i, j int) bool {
+		return valid[i].playedAt.Before(valid[j].playedAt)
+	})
+	return report, valid, nil
+}
+
+// ImportMatches validates historical match results and, when every row is
+// valid and dryRun is not set, stores them with missing players created by
+// name. Imported matches are dated by their played-at time and the whole
+// match log is replayed in chronological order, so that ratings come out as
+// if the matches had been submitted when they were played. A report with
+// invalid rows means nothing was stored.
+func ImportMatches(rows []models.ImportMatchRow, createdByAdminID, createdByAPIKeyID *uint, dryRun bool) (*models.ImportReport, error) {
+	if len(rows) == 0 {
+		return nil, invalid("The import contains no matches")
+	}
+	if dryRun {
+		report, _, err := validateImport(config.DB, rows, true)
+		return report, err
+	}
+
+	// The rows are validated in the write transaction, so that the players
+	// they name cannot be deleted before the matches are stored
+	var report *models.ImportReport
+	err := WriteTransaction(func(tx *gorm.DB) error {
+		var valid []*importMatch
+		var err error
+		report, valid, err = validateImport(tx, rows, false)
+		if err != nil {
+			return err
+		}
+		if report.Invalid > 0 {
+			return errImportInvalid
+		}
+
+		playerIDs := make(map[string]uint, len(report.PlayersCreated))
+		for _, name := range report.PlayersCreated {
+			player := models.Player{Name: name, Elo: DefaultRating}
+			if err := tx.Create(&player).Error; err != nil {
+				return fmt.Errorf("failed to create player %q: %w", name, err)
+			}
+			playerIDs[name] = player.ID
+		}
+		resolve := func(player importPlayer) uint {
+			if player.id != 0 {
+				return player.id
+			}
+			return playerIDs[player.name]
+		}
+
+		for _, row := range valid {
+			match := models.Match{
+				Player1ID:         resolve(row.player1),
+				Player2ID:         resolve(row.player2),
+				Player1Score:      row.score1,
+				Player2Score:      row.score2,
+				CreatedByAdminID:  createdByAdminID,
+				CreatedByAPIKeyID: createdByAPIKeyID,
+				PlayedAt:          row.playedAt.UTC(),
+			}
+			if row.score1 > row.score2 {
+				match.WinnerID = &match.Player1ID
+			} else if row.score2 > row.score1 {
+				match.WinnerID = &match.Player2ID
+			}
+
+			season, err := seasonAt(tx, row.playedAt)
+			if err != nil {
+				return err
+			}
+			if season != nil {
+				match.SeasonID = &season.ID
+			}
+
+			if err := tx.Create(&match).Error; err != nil {
+				return fmt.Errorf("failed to create match for row %d: %w", row.index+1, err)
+			}
+			result := &report.Rows[row.index]
+			result.Status = models.ImportImported
+			result.MatchID = &match.ID
+			report.Imported++
+		}
+
+		// Rate the imported matches in order, together with everything after them
+		replay, err := ReplayRatings(tx, false)
+		if err != nil {
+			return err
+		}
+		report.PlayersUpdated = len(replay.PlayersChanged)
+		report.MatchesUpdated = len(replay.MatchesChanged)
+
+		return RefreshChampion(tx)
+	})
+	if errors.Is(err, errImportInvalid) {
+		return report, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
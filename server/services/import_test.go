@@ -0,0 +1,198 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+)
+
+func TestParseImportCSV(t *testing.T) {
+	rows, err := ParseImport("CSV", strings.NewReader(
+		"Player1, player2_name, player1_score, player2_score, played_at\n"+
+			"3, Bob, 2, 1, 2024-03-01\n"+
+			"Alice, , x, 0, 2024-03-02 10:00\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	want := models.ImportMatchRow{Player1ID: 3, Player2Name: "Bob", Player1Score: 2, Player2Score: 1, PlayedAt: "2024-03-01"}
+	if rows[0] != want {
+		t.Errorf("row 1: got %+v, want %+v", rows[0], want)
+	}
+	if rows[1].Player1Name != "Alice" || rows[1].Player1ID != 0 || !strings.Contains(rows[1].ParseError, "player1_score") {
+		t.Errorf("row 2: got %+v, want Alice by name and a score error", rows[1])
+	}
+
+	for name, file := range map[string]string{
+		"empty file":     "",
+		"unknown column": "player1,player2,winner,player1_score,player2_score,played_at\n",
+		"missing column": "player1,player2,player1_score,player2_score\n",
+	} {
+		if _, err := ParseImportCSV(strings.NewReader(file)); !isValidationError(err) {
+			t.Errorf("%s: got %v, want a validation error", name, err)
+		}
+	}
+}
+
+func TestParseImportJSON(t *testing.T) {
+	rows, err := ParseImport("json", strings.NewReader(
+		`[{"player1_id":1,"player2_name":"Bob","player1_score":2,"player2_score":0,"played_at":"2024-03-01T10:00:00Z"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.ImportMatchRow{Player1ID: 1, Player2Name: "Bob", Player1Score: 2, PlayedAt: "2024-03-01T10:00:00Z"}
+	if len(rows) != 1 || rows[0] != want {
+		t.Errorf("got %+v, want %+v", rows, want)
+	}
+
+	if _, err := ParseImport("json", strings.NewReader(`{"player1_id":1}`)); !isValidationError(err) {
+		t.Errorf("object: got %v, want a validation error", err)
+	}
+	if _, err := ParseImport("xml", strings.NewReader("")); !isValidationError(err) {
+		t.Errorf("unknown format: got %v, want a validation error", err)
+	}
+}
+
+func TestParsePlayedAt(t *testing.T) {
+	for value, want := range map[string]time.Time{
+		"2024-03-01":                time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		"2024-03-01 10:30":          time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+		"2024-03-01 10:30:15":       time.Date(2024, 3, 1, 10, 30, 15, 0, time.UTC),
+		"2024-03-01T10:30:15":       time.Date(2024, 3, 1, 10, 30, 15, 0, time.UTC),
+		"2024-03-01T10:30:15+09:00": time.Date(2024, 3, 1, 1, 30, 15, 0, time.UTC),
+	} {
+		got, err := ParsePlayedAt(value)
+		if err != nil || !got.Equal(want) || got.Location() != time.UTC {
+			t.Errorf("%s: got %v, %v, want %v", value, got, err, want)
+		}
+	}
+	if _, err := ParsePlayedAt("01/03/2024"); !isValidationError(err) {
+		t.Errorf("got %v, want a validation error", err)
+	}
+}
+
+func TestImportMatchesReplaysBackDatedMatches(t *testing.T) {
+	setupTestDB(t)
+	SetRatingSystem(EloSystem{})
+
+	alice := &models.Player{Name: "Alice", Elo: DefaultRating}
+	bob := &models.Player{Name: "Bob", Elo: DefaultRating}
+	create(t, alice, bob)
+	live := recordTestMatch(t, alice, bob, 2, 0)
+
+	rows, err := ParseImportCSV(strings.NewReader(
+		"player1,player2,player1_score,player2_score,played_at\n" +
+			"Alice,Carol,0,2,2024-03-02 09:00\n" +
+			"Bob,Alice,2,1,2024-03-01\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A dry run reports without storing anything
+	report, err := ImportMatches(rows, nil, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Valid != 2 || report.Imported != 0 || len(report.PlayersCreated) != 1 || report.PlayersCreated[0] != "Carol" {
+		t.Errorf("dry run: got %+v, want 2 valid rows creating Carol", report)
+	}
+	var count int64
+	if err := config.DB.Model(&models.Player{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("dry run: got %d players, want 2", count)
+	}
+
+	report, err = ImportMatches(rows, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 2 || report.Rows[0].Status != models.ImportImported || report.Rows[0].MatchID == nil {
+		t.Fatalf("got %+v, want both rows imported", report)
+	}
+
+	// The imported matches are rated in the order they were played, before
+	// the match already recorded
+	var matches []models.Match
+	if err := config.DB.Order("played_at ASC").Find(&matches).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 3 || matches[0].Player1ID != bob.ID || matches[2].ID != live.ID {
+		t.Fatalf("got %d matches, want the two imported before the live one", len(matches))
+	}
+	if matches[0].Player2EloBefore != DefaultRating || matches[1].Player1EloBefore != matches[0].Player2EloAfter ||
+		matches[2].Player1EloBefore != matches[1].Player1EloAfter {
+		t.Errorf("Alice's rating is not carried through the matches in order: %+v", matches)
+	}
+	var player models.Player
+	if err := config.DB.First(&player, alice.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if player.Elo != matches[2].Player1EloAfter || player.MatchesWon != 1 || player.MatchesLost != 2 {
+		t.Errorf("got Alice %d-%d at %v, want 1-2 at %v", player.MatchesWon, player.MatchesLost, player.Elo, matches[2].Player1EloAfter)
+	}
+	replay, err := ReplayRatings(config.DB, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.PlayersChanged) != 0 || len(replay.MatchesChanged) != 0 {
+		t.Errorf("a replay after the import still changes %d players and %d matches", len(replay.PlayersChanged), len(replay.MatchesChanged))
+	}
+}
+
+func TestImportMatchesRejectsInvalidRows(t *testing.T) {
+	setupTestDB(t)
+
+	alice := &models.Player{Name: "Alice", Elo: DefaultRating}
+	bob := &models.Player{Name: "Bob", Elo: DefaultRating}
+	create(t, alice, bob)
+	if err := config.DB.Delete(bob).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format("2006-01-02")
+	rows := []models.ImportMatchRow{
+		{Player1Name: "Alice", Player2Name: "Dave", Player1Score: 2, PlayedAt: "2024-03-01"},
+		{Player1Name: "Alice", Player2Name: "Bob", Player1Score: 2, PlayedAt: "2024-03-01"},
+		{Player1ID: alice.ID, Player2ID: 999, Player1Score: 2, PlayedAt: "2024-03-01"},
+		{Player1ID: alice.ID, Player2Name: "Alice", Player1Score: 2, PlayedAt: "2024-03-01"},
+		{Player1Name: "Alice", Player2Name: "Dave", Player1Score: 2, PlayedAt: tomorrow},
+		{Player1Name: "Alice", Player2Name: "Dave", Player1Score: -1, PlayedAt: "2024-03-01"},
+		{Player1Name: "Alice", Player2Name: "Dave", Player1Score: 2},
+	}
+	report, err := ImportMatches(rows, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Valid != 1 || report.Invalid != 6 || report.Imported != 0 {
+		t.Errorf("got %d valid, %d invalid and %d imported, want 1, 6 and 0", report.Valid, report.Invalid, report.Imported)
+	}
+	for i, want := range []string{"", "has been deleted", "not found", "against themselves", "future", "negative", "required"} {
+		got := report.Rows[i]
+		if (want == "") != (got.Status == models.ImportValid) || !strings.Contains(got.Error, want) {
+			t.Errorf("row %d: got %s %q, want an error containing %q", i+1, got.Status, got.Error, want)
+		}
+	}
+
+	// Nothing is stored when a row is invalid
+	var matches, players int64
+	if err := config.DB.Model(&models.Match{}).Count(&matches).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := config.DB.Unscoped().Model(&models.Player{}).Count(&players).Error; err != nil {
+		t.Fatal(err)
+	}
+	if matches != 0 || players != 2 {
+		t.Errorf("got %d matches and %d players, want nothing stored", matches, players)
+	}
+
+	if _, err := ImportMatches(nil, nil, nil, false); !isValidationError(err) {
+		t.Errorf("no rows: got %v, want a validation error", err)
+	}
+}
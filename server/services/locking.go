@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxWriteAttempts is how often a rating write is tried before giving up on
+// serialization failures and deadlocks
+const maxWriteAttempts = 5
+
+// sqliteWrites serializes rating writes on SQLite, which has no row locks
+var sqliteWrites sync.Mutex
+
+// isSQLite reports whether db is backed by SQLite
+func isSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == "sqlite"
+}
+
+// WriteTransaction runs fn in a transaction that changes ratings. Player rows
+// are locked by the writes themselves (see lockPlayers) on Postgres and
+// MySQL; on SQLite the whole transaction is serialized in-process instead.
+// Transactions that fail on a serialization failure, deadlock or busy
+// database are rolled back and retried with a short backoff.
+func WriteTransaction(fn func(tx *gorm.DB) error) error {
+	db := config.DB
+	if isSQLite(db) {
+		sqliteWrites.Lock()
+		defer sqliteWrites.Unlock()
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxWriteAttempts; attempt++ {
+		err = db.Transaction(fn)
+		if err == nil || !isRetryable(err) {
+			return err
+		}
+		time.Sleep(time.Duration(attempt*attempt) * 10 * time.Millisecond)
+	}
+	return err
+}
+
+// isRetryable reports whether err means the transaction lost a race and can
+// simply be run again
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// serialization_failure, deadlock_detected
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		// ER_LOCK_DEADLOCK, ER_LOCK_WAIT_TIMEOUT
+		return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
+
+// forUpdate adds SELECT ... FOR UPDATE to a query on databases with row locks
+func forUpdate(tx *gorm.DB) *gorm.DB {
+	if isSQLite(tx) {
+		return tx
+	}
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"})
+}
+
+// lockPlayers locks the rows of the given players until tx ends. Rows are
+// locked in ID order so that two transactions never wait on each other.
+func lockPlayers(tx *gorm.DB, ids ...uint) error {
+	if isSQLite(tx) {
+		return nil
+	}
+	var locked []uint
+	return forUpdate(tx).Unscoped().Model(&models.Player{}).
+		Where("id IN ?", ids).
+		Order("id ASC").
+		Pluck("id", &locked).Error
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// MailConfigured reports whether an SMTP server is configured with SMTP_HOST
+func MailConfigured() bool {
+	return os.Getenv("SMTP_HOST") != ""
+}
+
+// SendMail sends a plain text message through the SMTP server in SMTP_HOST
+// and SMTP_PORT (default 25). SMTP_USERNAME and SMTP_PASSWORD are optional,
+// so that a local mail sink can be used without authentication.
+func SendMail(to, subject, body string) error {
+	if !MailConfigured() {
+		return fmt.Errorf("SMTP_HOST is not set")
+	}
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid mail header")
+	}
+
+	host := os.Getenv("SMTP_HOST")
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "25"
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+
+	var auth smtp.Auth
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", to)
+	fmt.Fprintf(&message, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	message.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	return smtp.SendMail(net.JoinHostPort(host, port), auth, from, []string{to}, []byte(message.String()))
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"encoding/json"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+// roundRequests turns recorded rounds back into the throws that produced them
+func roundRequests(rounds []models.MatchRound) []models.RoundRequest {
+	requests := make([]models.RoundRequest, 0, len(rounds))
+	for _, round := range rounds {
+		requests = append(requests, models.RoundRequest{
+			Player1Throw: round.Player1Throw,
+			Player2Throw: round.Player2Throw,
+		})
+	}
+	return requests
+}
+
+// encodeRounds stores a list of throws as JSON for the audit trail
+func encodeRounds(rounds []models.RoundRequest) string {
+	if len(rounds) == 0 {
+		return ""
+	}
+	encoded, _ := json.Marshal(rounds)
+	return string(encoded)
+}
+
+// sameWinner compares two optional winners (nil for a draw)
+func sameWinner(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// EditMatch corrects the score and rounds of a match, re-rates every later
+// match by replaying the match log, and records the edit with the previous
+// values. It runs on tx; callers use a WriteTransaction and refresh the
+// championship on the same tx.
+func EditMatch(tx *gorm.DB, matchID uint, req models.UpdateMatchRequest, editedByAdminID uint) (*models.MatchEdit, error) {
+	if req.Player1Score < 0 || req.Player2Score < 0 {
+		return nil, invalid("Scores cannot be negative")
+	}
+
+	var match models.Match
+	if err := forUpdate(tx).First(&match, matchID).Error; err != nil {
+		return nil, err
+	}
+
+	var existing []models.MatchRound
+	if err := tx.Where("match_id = ?", match.ID).Order("round_number ASC").Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	previousRounds := roundRequests(existing)
+
+	// Without new rounds the recorded ones are kept, so they must still add up
+	requested := previousRounds
+	if req.Rounds != nil {
+		requested = *req.Rounds
+	}
+	rounds, err := BuildRounds(requested, req.Player1Score, req.Player2Score)
+	if err != nil {
+		if req.Rounds == nil {
+			return nil, invalid("The recorded rounds do not match the new score, submit the corrected rounds")
+		}
+		return nil, err
+	}
+
+	var winnerID *uint
+	if req.Player1Score > req.Player2Score {
+		winnerID = &match.Player1ID
+	} else if req.Player2Score > req.Player1Score {
+		winnerID = &match.Player2ID
+	}
+
+	// A tournament has already progressed on the recorded winner
+	if !sameWinner(winnerID, match.WinnerID) {
+		if found, err := inTournament(tx, match.ID); err != nil {
+			return nil, err
+		} else if found {
+			return nil, invalid("The winner of a tournament match cannot be changed")
+		}
+	}
+
+	edit := models.MatchEdit{
+		MatchID:              match.ID,
+		EditedByAdminID:      editedByAdminID,
+		Reason:               req.Reason,
+		PreviousPlayer1Score: match.Player1Score,
+		PreviousPlayer2Score: match.Player2Score,
+		PreviousWinnerID:     match.WinnerID,
+		PreviousRounds:       encodeRounds(previousRounds),
+		Player1Score:         req.Player1Score,
+		Player2Score:         req.Player2Score,
+		WinnerID:             winnerID,
+		Rounds:               encodeRounds(roundRequests(rounds)),
+	}
+
+	if err := tx.Model(&models.Match{}).Where("id = ?", match.ID).Updates(map[string]interface{}{
+		"player1_score": req.Player1Score,
+		"player2_score": req.Player2Score,
+		"winner_id":     winnerID,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("match_id = ?", match.ID).Delete(&models.MatchRound{}).Error; err != nil {
+		return nil, err
+	}
+	if len(rounds) > 0 {
+		for i := range rounds {
+			rounds[i].MatchID = match.ID
+		}
+		if err := tx.Create(&rounds).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// Re-rate this match and everything after it
+	report, err := ReplayRatings(tx, false)
+	if err != nil {
+		return nil, err
+	}
+	edit.PlayersUpdated = len(report.PlayersChanged)
+	edit.MatchesUpdated = len(report.MatchesChanged)
+
+	if err := tx.Create(&edit).Error; err != nil {
+		return nil, err
+	}
+	return &edit, nil
+}
+
+// GetMatchEdits returns the edit history of a match, most recent first
+func GetMatchEdits(matchID uint) ([]models.MatchEdit, error) {
+	var match models.Match
+	if err := config.DB.First(&match, matchID).Error; err != nil {
+		return nil, err
+	}
+
+	var edits []models.MatchEdit
+	err := config.DB.
+		Preload("EditedByAdmin").
+		Where("match_id = ?", match.ID).
+		Order("created_at DESC, id DESC").
+		Find(&edits).Error
+	return edits, err
+}
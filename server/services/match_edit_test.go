@@ -0,0 +1,147 @@
+package services
+
+import (
+	"testing"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+// editTestMatch edits a match in a write transaction
+func editTestMatch(matchID uint, req models.UpdateMatchRequest) (*models.MatchEdit, error) {
+	var edit *models.MatchEdit
+	err := WriteTransaction(func(tx *gorm.DB) error {
+		var err error
+		edit, err = EditMatch(tx, matchID, req, 1)
+		return err
+	})
+	return edit, err
+}
+
+func TestEditMatchAuditsAndReplays(t *testing.T) {
+	setupTestDB(t)
+	SetRatingSystem(EloSystem{})
+
+	editor := models.Admin{Username: "root", Email: "root@example.com", PasswordHash: "x", Role: models.RoleSuperAdmin}
+	alice := &models.Player{Name: "Alice", Elo: DefaultRating}
+	bob := &models.Player{Name: "Bob", Elo: DefaultRating}
+	create(t, &editor, alice, bob)
+
+	var first *models.Match
+	err := WriteTransaction(func(tx *gorm.DB) error {
+		var err error
+		first, err = RecordMatch(tx, MatchInput{
+			Player1ID:    alice.ID,
+			Player2ID:    bob.ID,
+			Player1Score: 2,
+			Player2Score: 0,
+			Rounds: []models.RoundRequest{
+				{Player1Throw: models.ThrowStone, Player2Throw: models.ThrowScissor},
+				{Player1Throw: models.ThrowPaper, Player2Throw: models.ThrowStone},
+			},
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := recordTestMatch(t, alice, bob, 1, 0)
+
+	// The recorded rounds no longer add up to a new score
+	if _, err := editTestMatch(first.ID, models.UpdateMatchRequest{Player1Score: 0, Player2Score: 2}); !isValidationError(err) {
+		t.Errorf("score without rounds: got %v, want a validation error", err)
+	}
+	if _, err := editTestMatch(first.ID, models.UpdateMatchRequest{Player1Score: -1}); !isValidationError(err) {
+		t.Errorf("negative score: got %v, want a validation error", err)
+	}
+
+	rounds := []models.RoundRequest{
+		{Player1Throw: models.ThrowStone, Player2Throw: models.ThrowPaper},
+		{Player1Throw: models.ThrowStone, Player2Throw: models.ThrowStone},
+		{Player1Throw: models.ThrowScissor, Player2Throw: models.ThrowStone},
+	}
+	edit, err := editTestMatch(first.ID, models.UpdateMatchRequest{Player1Score: 0, Player2Score: 2, Rounds: &rounds, Reason: "scores swapped"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edit.PreviousPlayer1Score != 2 || edit.PreviousPlayer2Score != 0 || edit.PreviousWinnerID == nil || *edit.PreviousWinnerID != alice.ID {
+		t.Errorf("got previous %d-%d won by %v, want 2-0 won by Alice", edit.PreviousPlayer1Score, edit.PreviousPlayer2Score, edit.PreviousWinnerID)
+	}
+	if edit.WinnerID == nil || *edit.WinnerID != bob.ID || edit.Reason != "scores swapped" {
+		t.Errorf("got winner %v and reason %q, want Bob and the reason given", edit.WinnerID, edit.Reason)
+	}
+	if edit.PreviousRounds != `[{"player1_throw":"stone","player2_throw":"scissor"},{"player1_throw":"paper","player2_throw":"stone"}]` {
+		t.Errorf("got previous rounds %s", edit.PreviousRounds)
+	}
+	if edit.PlayersUpdated != 2 || edit.MatchesUpdated != 2 {
+		t.Errorf("got %d players and %d matches re-rated, want 2 and 2", edit.PlayersUpdated, edit.MatchesUpdated)
+	}
+
+	var stored []models.MatchRound
+	if err := config.DB.Where("match_id = ?", first.ID).Order("round_number ASC").Find(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 3 || stored[2].Outcome != models.RoundPlayer2 {
+		t.Errorf("got rounds %+v, want the 3 corrected rounds", stored)
+	}
+
+	// Every later match is re-rated from the corrected result
+	var edited, later models.Match
+	if err := config.DB.First(&edited, first.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := config.DB.First(&later, second.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if edited.Player1EloAfter >= DefaultRating || later.Player1EloBefore != edited.Player1EloAfter {
+		t.Errorf("got Alice %v after the edited match and %v before the next, want a loss carried forward",
+			edited.Player1EloAfter, later.Player1EloBefore)
+	}
+	var player models.Player
+	if err := config.DB.First(&player, alice.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if player.MatchesWon != 1 || player.MatchesLost != 1 || player.Elo != later.Player1EloAfter {
+		t.Errorf("got Alice %d-%d at %v, want 1-1 at %v", player.MatchesWon, player.MatchesLost, player.Elo, later.Player1EloAfter)
+	}
+	report, err := ReplayRatings(config.DB, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.PlayersChanged) != 0 || len(report.MatchesChanged) != 0 {
+		t.Errorf("a replay after the edit still changes %d players and %d matches", len(report.PlayersChanged), len(report.MatchesChanged))
+	}
+
+	// A score-only edit keeps rounds that still add up
+	if _, err := editTestMatch(first.ID, models.UpdateMatchRequest{Player1Score: 0, Player2Score: 2, Reason: "recheck"}); err != nil {
+		t.Fatal(err)
+	}
+	edits, err := GetMatchEdits(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 2 || edits[0].Reason != "recheck" || edits[1].Reason != "scores swapped" {
+		t.Errorf("got %d edits, want the two edits most recent first", len(edits))
+	}
+	if edits[0].PreviousRounds != edits[0].Rounds {
+		t.Errorf("got rounds %s changed to %s, want them kept", edits[0].PreviousRounds, edits[0].Rounds)
+	}
+}
+
+func TestEditMatchKeepsTournamentWinner(t *testing.T) {
+	setupTestDB(t)
+
+	tournament, seed := startTestTournament(t, models.CreateTournamentRequest{Format: models.FormatSingleElimination}, 2)
+	final := tournamentSlot(t, tournament.ID, models.BracketWinners, 1, 1)
+	playTournamentMatch(t, final, seed[0])
+	final = tournamentSlot(t, tournament.ID, models.BracketWinners, 1, 1)
+
+	if _, err := editTestMatch(*final.MatchID, models.UpdateMatchRequest{Player1Score: 0, Player2Score: 2}); !isValidationError(err) {
+		t.Errorf("changing the winner: got %v, want a validation error", err)
+	}
+	if _, err := editTestMatch(*final.MatchID, models.UpdateMatchRequest{Player1Score: 3, Player2Score: 1}); err != nil {
+		t.Errorf("keeping the winner: %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+// PlayerMatchesQuery returns a query over every match the player took part in
+func PlayerMatchesQuery(playerID interface{}) *gorm.DB {
+	return config.DB.Model(&models.Match{}).
+		Where("player1_id = ? OR player2_id = ?", playerID, playerID)
+}
+
+// MatchInput is a match result between two existing players
+type MatchInput struct {
+	Player1ID         uint
+	Player2ID         uint
+	Player1Score      int
+	Player2Score      int
+	Rounds            []models.RoundRequest
+	CreatedByAdminID  *uint
+	CreatedByAPIKeyID *uint     // API key the match was submitted with, if any
+	PlayedAt          time.Time // zero means now, stored in UTC
+}
+
+// needsResequence reports whether a match played at playedAt lands before
+// matches or season starts that are already recorded, so that it cannot be
+// rated on top of the current ratings
+func needsResequence(tx *gorm.DB, playedAt time.Time) (bool, error) {
+	var later int64
+	if err := tx.Model(&models.Match{}).Where("played_at > ?", playedAt).Count(&later).Error; err != nil {
+		return false, err
+	}
+	if later > 0 {
+		return true, nil
+	}
+	if err := tx.Model(&models.Season{}).Where("started_at > ?", playedAt).Count(&later).Error; err != nil {
+		return false, err
+	}
+	return later > 0, nil
+}
+
+// createMatch stores a match together with its rounds
+func createMatch(tx *gorm.DB, match *models.Match, rounds []models.MatchRound) error {
+	if err := tx.Create(match).Error; err != nil {
+		return fmt.Errorf("failed to create match record: %w", err)
+	}
+
+	if len(rounds) > 0 {
+		for i := range rounds {
+			rounds[i].MatchID = match.ID
+		}
+		if err := tx.Create(&rounds).Error; err != nil {
+			return fmt.Errorf("failed to record match rounds: %w", err)
+		}
+		match.Rounds = rounds
+	}
+	return nil
+}
+
+// RecordMatch rates a match with the configured rating system and stores it
+// together with its rounds and the updated player stats. A back-dated match
+// is stored first and the match log replayed, so that it and every match
+// played after it are rated in order. It runs on tx so callers can record
+// extra state (e.g. bracket progress) atomically. Callers run it in a
+// WriteTransaction and refresh the championship on the same tx.
+func RecordMatch(tx *gorm.DB, input MatchInput) (*models.Match, error) {
+	if input.Player1ID == input.Player2ID {
+		return nil, invalid("Player cannot play against themselves")
+	}
+	if input.Player1Score < 0 || input.Player2Score < 0 {
+		return nil, invalid("Scores cannot be negative")
+	}
+
+	// Stored in UTC, as SQLite orders played_at as text
+	playedAt := input.PlayedAt.UTC()
+	if playedAt.IsZero() {
+		playedAt = time.Now().UTC()
+	}
+	if playedAt.After(time.Now()) {
+		return nil, invalid("played_at is in the future")
+	}
+
+	rounds, err := BuildRounds(input.Rounds, input.Player1Score, input.Player2Score)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lock both players so concurrent matches cannot rate from a stale rating
+	if err := lockPlayers(tx, input.Player1ID, input.Player2ID); err != nil {
+		return nil, err
+	}
+
+	var player1, player2 models.Player
+	if err := tx.First(&player1, input.Player1ID).Error; err != nil {
+		return nil, fmt.Errorf("player 1: %w", err)
+	}
+	if err := tx.First(&player2, input.Player2ID).Error; err != nil {
+		return nil, fmt.Errorf("player 2: %w", err)
+	}
+
+	// Determine winner
+	var winnerID *uint
+	if input.Player1Score > input.Player2Score {
+		winnerID = &player1.ID
+	} else if input.Player2Score > input.Player1Score {
+		winnerID = &player2.ID
+	}
+
+	match := models.Match{
+		Player1ID:         player1.ID,
+		Player2ID:         player2.ID,
+		Player1Score:      input.Player1Score,
+		Player2Score:      input.Player2Score,
+		WinnerID:          winnerID,
+		CreatedByAdminID:  input.CreatedByAdminID,
+		CreatedByAPIKeyID: input.CreatedByAPIKeyID,
+		PlayedAt:          playedAt,
+	}
+
+	// Matches played during a season also count towards the season ratings
+	season, err := seasonAt(tx, playedAt)
+	if err != nil {
+		return nil, err
+	}
+	if season != nil {
+		match.SeasonID = &season.ID
+	}
+
+	resequence, err := needsResequence(tx, playedAt)
+	if err != nil {
+		return nil, err
+	}
+	if resequence {
+		if err := createMatch(tx, &match, rounds); err != nil {
+			return nil, err
+		}
+		if _, err := ReplayRatings(tx, false); err != nil {
+			return nil, fmt.Errorf("failed to re-rate later matches: %w", err)
+		}
+		if err := tx.Preload("Player1").Preload("Player2").First(&match, match.ID).Error; err != nil {
+			return nil, err
+		}
+		return &match, nil
+	}
+
+	// Calculate new ratings with the configured rating system
+	rating1, err := idleRatingAt(tx, RatingFromPlayer(&player1), player1.ID, nil, playedAt)
+	if err != nil {
+		return nil, err
+	}
+	rating2, err := idleRatingAt(tx, RatingFromPlayer(&player2), player2.ID, nil, playedAt)
+	if err != nil {
+		return nil, err
+	}
+	ratingResult := Rating().Update(rating1, rating2, input.Player1Score, input.Player2Score)
+
+	match.Player1EloBefore = player1.Elo
+	match.Player2EloBefore = player2.Elo
+	match.Player1EloAfter = ratingResult.Player1.Rating
+	match.Player2EloAfter = ratingResult.Player2.Rating
+	match.Player1EloChange = ratingResult.Player1Change
+	match.Player2EloChange = ratingResult.Player2Change
+
+	// Season ratings are seeded from the all-time ratings before this match
+	if season != nil {
+		if err := recordSeasonResult(tx, season, &player1, &player2, input.Player1Score, input.Player2Score, playedAt); err != nil {
+			return nil, fmt.Errorf("failed to update season ratings: %w", err)
+		}
+	}
+
+	// Update player stats
+	switch {
+	case input.Player1Score > input.Player2Score:
+		player1.MatchesWon++
+		player2.MatchesLost++
+	case input.Player2Score > input.Player1Score:
+		player2.MatchesWon++
+		player1.MatchesLost++
+	default:
+		player1.MatchesDrawn++
+		player2.MatchesDrawn++
+	}
+	ApplyRating(&player1, ratingResult.Player1)
+	ApplyRating(&player2, ratingResult.Player2)
+	player1.TotalMatches++
+	player2.TotalMatches++
+
+	if err := tx.Save(&player1).Error; err != nil {
+		return nil, fmt.Errorf("failed to update player 1: %w", err)
+	}
+	if err := tx.Save(&player2).Error; err != nil {
+		return nil, fmt.Errorf("failed to update player 2: %w", err)
+	}
+	if err := createMatch(tx, &match, rounds); err != nil {
+		return nil, err
+	}
+
+	match.Player1 = player1
+	match.Player2 = player2
+	return &match, nil
+}
+
+// idleRatingAt returns the rating of a player before a match played at
+// playedAt, after the time since their last match (in the season, when
+// seasonID is set) with rating systems that account for inactivity
+func idleRatingAt(tx *gorm.DB, rating PlayerRating, playerID uint, seasonID *uint, playedAt time.Time) (PlayerRating, error) {
+	system := Rating()
+	if _, ok := system.(InactivityRating); !ok {
+		return rating, nil
+	}
+
+	query := tx.Model(&models.Match{}).
+		Where("(player1_id = ? OR player2_id = ?)", playerID, playerID).
+		Where("played_at <= ?", playedAt)
+	if seasonID != nil {
+		query = query.Where("season_id = ?", *seasonID)
+	}
+	var last []models.Match
+	if err := query.Select("played_at").Order("played_at DESC").Limit(1).Find(&last).Error; err != nil {
+		return rating, err
+	}
+	if len(last) == 0 {
+		return rating, nil
+	}
+	return idleRating(system, rating, &last[0].PlayedAt, playedAt), nil
+}
+
+// inTournament reports whether a match is the result of a tournament match
+func inTournament(tx *gorm.DB, matchID uint) (bool, error) {
+	var count int64
+	err := tx.Model(&models.TournamentMatch{}).Where("match_id = ?", matchID).Count(&count).Error
+	return count > 0, err
+}
+
+// DeleteMatch deletes a match on tx. Tournament results cannot be deleted,
+// as the tournament has progressed on them. Callers replay the ratings and
+// refresh the championship on the same tx.
+func DeleteMatch(tx *gorm.DB, matchID uint) error {
+	if found, err := inTournament(tx, matchID); err != nil {
+		return err
+	} else if found {
+		return invalid("Tournament matches cannot be deleted")
+	}
+
+	result := tx.Delete(&models.Match{}, matchID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Deleted by a concurrent request
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+// DefaultPasswordResetTTL is how long a reset token can be used when
+// PASSWORD_RESET_TTL is not set
+const DefaultPasswordResetTTL = time.Hour
+
+// ErrInvalidResetToken is returned for an unknown, expired or used reset token
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// PasswordResetTTL returns the configured lifetime of reset tokens
+func PasswordResetTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("PASSWORD_RESET_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return DefaultPasswordResetTTL
+}
+
+// PasswordResetURL returns the link to the page where a reset token is used,
+// based on PASSWORD_RESET_URL
+func PasswordResetURL(token string) string {
+	base := os.Getenv("PASSWORD_RESET_URL")
+	if base == "" {
+		base = "http://localhost:3000/auth/reset-password"
+	}
+	return base + "?token=" + url.QueryEscape(token)
+}
+
+// CreatePasswordReset issues a one-time reset token for an admin. Earlier
+// unused tokens of the admin stop working.
+func CreatePasswordReset(adminID uint, createdByAdminID *uint) (string, *models.PasswordResetToken, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	record := models.PasswordResetToken{
+		AdminID:          adminID,
+		TokenHash:        HashToken(token),
+		ExpiresAt:        time.Now().Add(PasswordResetTTL()),
+		CreatedByAdminID: createdByAdminID,
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("admin_id = ? AND used_at IS NULL", adminID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&record).Error
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return token, &record, nil
+}
+
+// SendPasswordResetEmail mails an admin the link to use a reset token
+func SendPasswordResetEmail(admin *models.Admin, token string, expiresAt time.Time) error {
+	body := fmt.Sprintf("Hello %s,\n\n"+
+		"A password reset was requested for your Stone-Paper-Scissors admin account. "+
+		"Open the link below to choose a new password:\n\n%s\n\n"+
+		"The link can be used once and expires at %s.\n",
+		admin.Username, PasswordResetURL(token), expiresAt.UTC().Format("2006-01-02 15:04 MST"))
+	return SendMail(admin.Email, "Reset your password", body)
+}
+
+// setPassword stores a new password hash for an admin, ends every session of
+// the admin, revokes their API keys and voids their pending reset tokens and
+// login challenges
+func setPassword(tx *gorm.DB, adminID uint, passwordHash string) error {
+	result := tx.Model(&models.Admin{}).Where("id = ?", adminID).Updates(map[string]interface{}{
+		"password_hash":       passwordHash,
+		"password_changed_at": time.Now().UTC(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	if _, err := RevokeAdminSessions(tx, adminID); err != nil {
+		return err
+	}
+	if _, err := RevokeAdminAPIKeys(tx, adminID); err != nil {
+		return err
+	}
+	return tx.Where("admin_id = ? AND used_at IS NULL", adminID).Delete(&models.PasswordResetToken{}).Error
+}
+
+// ChangePassword sets a new password hash for an admin, ends every session
+// of the admin and revokes their API keys
+func ChangePassword(adminID uint, passwordHash string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		return setPassword(tx, adminID, passwordHash)
+	})
+}
+
+// ResetPassword uses up a reset token to set a new password hash for its
+// admin, ending every session and revoking the API keys of the admin
+func ResetPassword(token, passwordHash string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var record models.PasswordResetToken
+		err := tx.Where("token_hash = ?", HashToken(token)).First(&record).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if record.UsedAt != nil || !record.ExpiresAt.After(now) {
+			return ErrInvalidResetToken
+		}
+
+		// A token can only be used once, even by concurrent requests
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", record.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidResetToken
+		}
+
+		err = setPassword(tx, record.AdminID, passwordHash)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// The admin has been deleted
+			return ErrInvalidResetToken
+		}
+		return err
+	})
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+)
+
+func TestResetPassword(t *testing.T) {
+	setupTestDB(t)
+
+	admin := &models.Admin{Username: "root", Email: "root@example.com", PasswordHash: "old", Role: models.RoleSuperAdmin}
+	create(t, admin)
+	family, _, err := StartSession(admin.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Issuing a new token voids the earlier one
+	earlier, _, err := CreatePasswordReset(admin.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, record, err := CreatePasswordReset(admin.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.TokenHash != HashToken(token) || record.TokenHash == token {
+		t.Errorf("got stored hash %q, want the hash of the token", record.TokenHash)
+	}
+	if err := ResetPassword(earlier, "earlier"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("earlier token: got %v, want ErrInvalidResetToken", err)
+	}
+
+	if err := ResetPassword(token, "new"); err != nil {
+		t.Fatal(err)
+	}
+	var stored models.Admin
+	if err := config.DB.First(&stored, admin.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.PasswordHash != "new" || stored.PasswordChangedAt == nil {
+		t.Errorf("got hash %q changed at %v, want the new hash", stored.PasswordHash, stored.PasswordChangedAt)
+	}
+	if active, err := SessionActive(family); err != nil || active {
+		t.Errorf("got session active %v, %v, want it ended by the reset", active, err)
+	}
+
+	// A token can only be used once
+	if err := ResetPassword(token, "again"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("used token: got %v, want ErrInvalidResetToken", err)
+	}
+	if err := config.DB.First(&stored, admin.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.PasswordHash != "new" {
+		t.Errorf("got hash %q after reusing the token, want it unchanged", stored.PasswordHash)
+	}
+}
+
+func TestResetPasswordExpiry(t *testing.T) {
+	setupTestDB(t)
+
+	admin := &models.Admin{Username: "root", Email: "root@example.com", PasswordHash: "old", Role: models.RoleSuperAdmin}
+	create(t, admin)
+
+	t.Setenv("PASSWORD_RESET_TTL", "10m")
+	token, record, err := CreatePasswordReset(admin.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := time.Until(record.ExpiresAt); ttl > 10*time.Minute || ttl < 9*time.Minute {
+		t.Errorf("got a token valid for %v, want 10m", ttl)
+	}
+	if err := config.DB.Model(record).Update("expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := ResetPassword(token, "new"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("expired token: got %v, want ErrInvalidResetToken", err)
+	}
+
+	// A token of a deleted admin is refused too
+	token, _, err = CreatePasswordReset(admin.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.DB.Delete(admin).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := ResetPassword(token, "new"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("deleted admin: got %v, want ErrInvalidResetToken", err)
+	}
+	if err := ResetPassword("unknown", "new"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("unknown token: got %v, want ErrInvalidResetToken", err)
+	}
+}
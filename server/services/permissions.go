@@ -0,0 +1,216 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrRoleInUse is returned when deleting a role still assigned to admins
+var ErrRoleInUse = errors.New("role is assigned to admins")
+
+// EffectivePermissions returns the permissions an admin holds: every
+// permission for a super admin, those of their custom role, or else the
+// default admin permissions
+func EffectivePermissions(admin *models.Admin) ([]models.Permission, error) {
+	if admin.Role == models.RoleSuperAdmin {
+		all := make([]models.Permission, 0, len(models.Permissions))
+		for _, info := range models.Permissions {
+			all = append(all, info.Name)
+		}
+		return all, nil
+	}
+	if admin.RoleID == nil {
+		return models.DefaultAdminPermissions, nil
+	}
+	if admin.CustomRole == nil || admin.CustomRole.ID != *admin.RoleID {
+		var role models.Role
+		if err := config.DB.First(&role, *admin.RoleID).Error; err != nil {
+			return nil, err
+		}
+		admin.CustomRole = &role
+	}
+	return admin.CustomRole.Permissions, nil
+}
+
+// APIKeyPermissions returns the permissions a request made with an API key
+// holds: the key's scopes that its admin still holds, except those only held
+// when logged in
+func APIKeyPermissions(admin *models.Admin, key *models.APIKey) ([]models.Permission, error) {
+	held, err := EffectivePermissions(admin)
+	if err != nil {
+		return nil, err
+	}
+	permissions := make([]models.Permission, 0, len(key.Scopes))
+	for _, p := range key.Scopes {
+		if Holds(held, p) && !Holds(models.SessionOnlyPermissions, p) {
+			permissions = append(permissions, p)
+		}
+	}
+	return permissions, nil
+}
+
+// Holds reports whether held contains every one of permissions
+func Holds(held []models.Permission, permissions ...models.Permission) bool {
+	for _, p := range permissions {
+		found := false
+		for _, h := range held {
+			if h == p {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizePermissions validates a list of permissions and sorts and dedupes it
+func normalizePermissions(list []models.Permission) ([]models.Permission, error) {
+	seen := make(map[models.Permission]bool)
+	permissions := make([]models.Permission, 0, len(list))
+	for _, p := range list {
+		if !models.ValidPermission(p) {
+			return nil, invalid("Unknown permission %q", p)
+		}
+		if !seen[p] {
+			seen[p] = true
+			permissions = append(permissions, p)
+		}
+	}
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i] < permissions[j] })
+	return permissions, nil
+}
+
+// normalizeRole validates a role request and sorts and dedupes its permissions
+func normalizeRole(req models.RoleRequest) (models.RoleRequest, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return req, invalid("Role name is required")
+	}
+	if len(req.Name) > 64 {
+		return req, invalid("Role name must be at most 64 characters")
+	}
+	permissions, err := normalizePermissions(req.Permissions)
+	if err != nil {
+		return req, err
+	}
+	req.Permissions = permissions
+	return req, nil
+}
+
+// roleNameTaken reports whether another role already has a name
+func roleNameTaken(name string, exceptID uint) (bool, error) {
+	var count int64
+	err := config.DB.Model(&models.Role{}).
+		Where("name = ? AND id <> ?", name, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListRoles returns every custom role by name
+func ListRoles() ([]models.Role, error) {
+	var roles []models.Role
+	err := config.DB.Order("name").Find(&roles).Error
+	return roles, err
+}
+
+// CreateRole stores a new custom role
+func CreateRole(req models.RoleRequest) (*models.Role, error) {
+	req, err := normalizeRole(req)
+	if err != nil {
+		return nil, err
+	}
+	if taken, err := roleNameTaken(req.Name, 0); err != nil {
+		return nil, err
+	} else if taken {
+		return nil, invalid("A role named %q already exists", req.Name)
+	}
+	role := models.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+	}
+	if err := config.DB.Create(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// UpdateRole replaces the name, description and permissions of a role. The
+// change applies to its admins on their next request.
+func UpdateRole(id uint, req models.RoleRequest) (*models.Role, error) {
+	req, err := normalizeRole(req)
+	if err != nil {
+		return nil, err
+	}
+	var role models.Role
+	if err := config.DB.First(&role, id).Error; err != nil {
+		return nil, err
+	}
+	if taken, err := roleNameTaken(req.Name, id); err != nil {
+		return nil, err
+	} else if taken {
+		return nil, invalid("A role named %q already exists", req.Name)
+	}
+	role.Name = req.Name
+	role.Description = req.Description
+	role.Permissions = req.Permissions
+	if err := config.DB.Save(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// DeleteRole deletes a custom role that no admin holds
+func DeleteRole(id uint) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var role models.Role
+		if err := tx.First(&role, id).Error; err != nil {
+			return err
+		}
+		var assigned int64
+		if err := tx.Model(&models.Admin{}).Where("role_id = ?", id).Count(&assigned).Error; err != nil {
+			return err
+		}
+		if assigned > 0 {
+			return ErrRoleInUse
+		}
+		return tx.Delete(&role).Error
+	})
+}
+
+// AssignRole gives an admin a custom role, or the default permissions again
+// when roleID is nil. Super admins always hold every permission.
+func AssignRole(adminID uint, roleID *uint) (*models.Admin, error) {
+	var admin models.Admin
+	if err := config.DB.First(&admin, adminID).Error; err != nil {
+		return nil, err
+	}
+	if admin.Role == models.RoleSuperAdmin {
+		return nil, invalid("Super admins hold every permission and cannot be given a role")
+	}
+	if roleID != nil {
+		var role models.Role
+		err := config.DB.First(&role, *roleID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, invalid("Role %d does not exist", *roleID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		admin.CustomRole = &role
+	}
+	if err := config.DB.Model(&admin).Update("role_id", roleID).Error; err != nil {
+		return nil, err
+	}
+	admin.RoleID = roleID
+	return &admin, nil
+}
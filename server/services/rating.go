@@ -0,0 +1,183 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"stone-paper-scissors/models"
+)
+
+const (
+	// DefaultRating is the rating every new player starts with
+	DefaultRating = 1000.0
+	// DefaultDeviation is the rating deviation of a player with no rated matches
+	DefaultDeviation = 350.0
+	// DefaultVolatility is the starting Glicko-2 volatility
+	DefaultVolatility = 0.06
+)
+
+// PlayerRating is the rating state of a single player as seen by a RatingSystem
+type PlayerRating struct {
+	Rating       float64
+	Deviation    float64
+	Volatility   float64
+	TotalMatches int
+}
+
+// RatingResult contains the updated rating state of both players after a match
+type RatingResult struct {
+	Player1       PlayerRating
+	Player2       PlayerRating
+	Player1Change float64
+	Player2Change float64
+}
+
+// RatingSystem is a rating engine used to rate matches and predict outcomes
+type RatingSystem interface {
+	// Name returns the identifier used to select the system (RATING_SYSTEM env)
+	Name() string
+	// Update rates a single match and returns the new state of both players
+	Update(player1, player2 PlayerRating, player1Score, player2Score int) RatingResult
+	// ExpectedScore returns the expected score (0..1) of player against opponent
+	ExpectedScore(player, opponent PlayerRating) float64
+	// WinProbability returns the probability (in percent) of player1 beating player2
+	WinProbability(player1, player2 PlayerRating) float64
+	// Uncertainty returns the deviation to show next to a rating, or 0 if the system has none
+	Uncertainty(rating PlayerRating) float64
+}
+
+// InactivityRating is implemented by rating systems whose ratings grow less
+// certain while a player does not play
+type InactivityRating interface {
+	// Idle returns rating after the player has not played for idle
+	Idle(rating PlayerRating, idle time.Duration) PlayerRating
+}
+
+// idleRating returns the rating before a match played at playedAt of a player
+// who last played at lastPlayed (nil before their first match)
+func idleRating(system RatingSystem, rating PlayerRating, lastPlayed *time.Time, playedAt time.Time) PlayerRating {
+	inactivity, ok := system.(InactivityRating)
+	if !ok || lastPlayed == nil || !playedAt.After(*lastPlayed) {
+		return rating
+	}
+	return inactivity.Idle(rating, playedAt.Sub(*lastPlayed))
+}
+
+var (
+	ratingSystem     RatingSystem
+	ratingSystemOnce sync.Once
+)
+
+// NewRatingSystem returns the rating system registered under name
+func NewRatingSystem(name string) (RatingSystem, error) {
+	switch strings.ToLower(name) {
+	case "", "elo":
+		return EloSystem{}, nil
+	case "glicko2", "glicko-2":
+		return NewGlicko2System(), nil
+	default:
+		return nil, fmt.Errorf("unknown rating system %q", name)
+	}
+}
+
+// Rating returns the rating system selected for this deployment via RATING_SYSTEM
+func Rating() RatingSystem {
+	ratingSystemOnce.Do(func() {
+		system, err := NewRatingSystem(os.Getenv("RATING_SYSTEM"))
+		if err != nil {
+			log.Printf("%v, falling back to elo", err)
+			system = EloSystem{}
+		}
+		ratingSystem = system
+	})
+	return ratingSystem
+}
+
+// SetRatingSystem overrides the deployment rating system (used by tools and tests)
+func SetRatingSystem(system RatingSystem) {
+	ratingSystemOnce.Do(func() {})
+	ratingSystem = system
+}
+
+// NewPlayerRating returns the rating state of a brand new player
+func NewPlayerRating() PlayerRating {
+	return PlayerRating{
+		Rating:     DefaultRating,
+		Deviation:  DefaultDeviation,
+		Volatility: DefaultVolatility,
+	}
+}
+
+// RatingFromPlayer extracts the rating state of a player
+func RatingFromPlayer(player *models.Player) PlayerRating {
+	rating := PlayerRating{
+		Rating:       player.Elo,
+		Deviation:    player.RatingDeviation,
+		Volatility:   player.RatingVolatility,
+		TotalMatches: player.TotalMatches,
+	}
+	// Rows created before deviation tracking (or without DB defaults loaded)
+	if rating.Deviation <= 0 {
+		rating.Deviation = DefaultDeviation
+	}
+	if rating.Volatility <= 0 {
+		rating.Volatility = DefaultVolatility
+	}
+	return rating
+}
+
+// ApplyRating stores a rating state on a player (match counters are left untouched)
+func ApplyRating(player *models.Player, rating PlayerRating) {
+	player.Elo = rating.Rating
+	player.RatingDeviation = rating.Deviation
+	player.RatingVolatility = rating.Volatility
+}
+
+// EloSystem is the chess-style Elo with score-based adjustment implemented by CalculateElo
+type EloSystem struct{}
+
+// Name implements RatingSystem
+func (EloSystem) Name() string {
+	return "elo"
+}
+
+// Update implements RatingSystem
+func (EloSystem) Update(player1, player2 PlayerRating, player1Score, player2Score int) RatingResult {
+	result := CalculateElo(
+		player1.Rating,
+		player2.Rating,
+		player1Score,
+		player2Score,
+		player1.TotalMatches,
+		player2.TotalMatches,
+	)
+
+	player1.Rating = result.Player1NewElo
+	player2.Rating = result.Player2NewElo
+
+	return RatingResult{
+		Player1:       player1,
+		Player2:       player2,
+		Player1Change: result.Player1EloChange,
+		Player2Change: result.Player2EloChange,
+	}
+}
+
+// ExpectedScore implements RatingSystem
+func (EloSystem) ExpectedScore(player, opponent PlayerRating) float64 {
+	return CalculateExpectedScore(player.Rating, opponent.Rating)
+}
+
+// WinProbability implements RatingSystem
+func (EloSystem) WinProbability(player1, player2 PlayerRating) float64 {
+	return CalculateWinProbability(player1.Rating, player2.Rating)
+}
+
+// Uncertainty implements RatingSystem
+func (EloSystem) Uncertainty(rating PlayerRating) float64 {
+	return 0
+}
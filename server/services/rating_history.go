@@ -0,0 +1,170 @@
+package services
+
+import (
+	"time"
+
+	"stone-paper-scissors/models"
+)
+
+// Rating history bucket intervals
+const (
+	IntervalMatch  = "match"
+	IntervalDaily  = "daily"
+	IntervalWeekly = "weekly"
+)
+
+// RatingPoint is a player's rating right after a single match
+type RatingPoint struct {
+	Date       time.Time `json:"date"`
+	MatchID    uint      `json:"match_id"`
+	OpponentID uint      `json:"opponent_id"`
+	Rating     float64   `json:"rating"`
+	Change     float64   `json:"change"`
+}
+
+// RatingBucket summarises a player's rating over a day or a week
+type RatingBucket struct {
+	Start   time.Time `json:"start"`
+	Open    float64   `json:"open"`
+	Min     float64   `json:"min"`
+	Max     float64   `json:"max"`
+	Close   float64   `json:"close"`
+	Matches int       `json:"matches"`
+}
+
+// RatingExtreme is the highest or lowest rating a player has held
+type RatingExtreme struct {
+	Rating  float64   `json:"rating"`
+	Date    time.Time `json:"date"`
+	MatchID *uint     `json:"match_id"` // nil for the starting rating
+}
+
+// RatingHistory is a player's rating over time
+type RatingHistory struct {
+	PlayerID       uint           `json:"player_id"`
+	PlayerName     string         `json:"player_name"`
+	Interval       string         `json:"interval"`
+	StartingRating float64        `json:"starting_rating"`
+	CurrentRating  float64        `json:"current_rating"`
+	TotalMatches   int            `json:"total_matches"`
+	Peak           RatingExtreme  `json:"peak"`
+	Lowest         RatingExtreme  `json:"lowest"`
+	Points         []RatingPoint  `json:"points,omitempty"`
+	Buckets        []RatingBucket `json:"buckets,omitempty"`
+}
+
+// GetPlayerRatingHistory builds a player's rating time series from the
+// before/after ratings stored on their matches. With interval daily or weekly
+// the series is bucketed (UTC days, weeks starting on Monday) with the
+// opening, lowest, highest and closing rating of every bucket.
+func GetPlayerRatingHistory(player *models.Player, interval string) (*RatingHistory, error) {
+	if interval == "" {
+		interval = IntervalMatch
+	}
+	if interval != IntervalMatch && interval != IntervalDaily && interval != IntervalWeekly {
+		return nil, invalid("Interval must be match, daily or weekly")
+	}
+
+	var matches []models.Match
+	if err := PlayerMatchesQuery(player.ID).Order("played_at ASC, id ASC").Find(&matches).Error; err != nil {
+		return nil, err
+	}
+
+	history := &RatingHistory{
+		PlayerID:       player.ID,
+		PlayerName:     player.Name,
+		Interval:       interval,
+		StartingRating: DefaultRating,
+		CurrentRating:  player.Elo,
+		TotalMatches:   len(matches),
+	}
+
+	points := make([]RatingPoint, 0, len(matches))
+	for _, match := range matches {
+		point := RatingPoint{Date: match.PlayedAt, MatchID: match.ID}
+		if match.Player1ID == player.ID {
+			point.OpponentID = match.Player2ID
+			point.Rating = match.Player1EloAfter
+			point.Change = match.Player1EloChange
+		} else {
+			point.OpponentID = match.Player1ID
+			point.Rating = match.Player2EloAfter
+			point.Change = match.Player2EloChange
+		}
+		points = append(points, point)
+	}
+
+	started := player.CreatedAt
+	if len(matches) > 0 {
+		first := matches[0]
+		history.StartingRating = first.Player1EloBefore
+		if first.Player2ID == player.ID {
+			history.StartingRating = first.Player2EloBefore
+		}
+		// Imported matches can predate the player record
+		if first.PlayedAt.Before(started) {
+			started = first.PlayedAt
+		}
+	}
+
+	// The starting rating counts towards the extremes, so a player who has
+	// only lost peaks at the rating they started with
+	history.Peak = RatingExtreme{Rating: history.StartingRating, Date: started}
+	history.Lowest = history.Peak
+	for i := range points {
+		if points[i].Rating > history.Peak.Rating {
+			history.Peak = RatingExtreme{Rating: points[i].Rating, Date: points[i].Date, MatchID: &points[i].MatchID}
+		}
+		if points[i].Rating < history.Lowest.Rating {
+			history.Lowest = RatingExtreme{Rating: points[i].Rating, Date: points[i].Date, MatchID: &points[i].MatchID}
+		}
+	}
+
+	if interval == IntervalMatch {
+		history.Points = points
+		return history, nil
+	}
+
+	history.Buckets = bucketRatings(points, history.StartingRating, interval)
+	return history, nil
+}
+
+// bucketRatings groups rating points into daily or weekly buckets
+func bucketRatings(points []RatingPoint, starting float64, interval string) []RatingBucket {
+	buckets := make([]RatingBucket, 0)
+	previous := starting
+	for _, point := range points {
+		start := bucketStart(point.Date, interval)
+		if len(buckets) == 0 || !buckets[len(buckets)-1].Start.Equal(start) {
+			buckets = append(buckets, RatingBucket{
+				Start: start,
+				Open:  previous,
+				Min:   previous,
+				Max:   previous,
+			})
+		}
+
+		bucket := &buckets[len(buckets)-1]
+		if point.Rating < bucket.Min {
+			bucket.Min = point.Rating
+		}
+		if point.Rating > bucket.Max {
+			bucket.Max = point.Rating
+		}
+		bucket.Close = point.Rating
+		bucket.Matches++
+		previous = point.Rating
+	}
+	return buckets
+}
+
+// bucketStart truncates a time to the start of its UTC day or Monday-based week
+func bucketStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if interval == IntervalWeekly {
+		offset := (int(day.Weekday()) + 6) % 7
+		day = day.AddDate(0, 0, -offset)
+	}
+	return day
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"stone-paper-scissors/models"
+)
+
+// ratedMatch is a match with the ratings player1 and player2 went from and to
+func ratedMatch(player1, player2 *models.Player, playedAt time.Time, from1, to1, from2, to2 float64) *models.Match {
+	match := testMatch(player1, player2, 1, 0, playedAt, nil)
+	match.Player1EloBefore, match.Player1EloAfter, match.Player1EloChange = from1, to1, to1-from1
+	match.Player2EloBefore, match.Player2EloAfter, match.Player2EloChange = from2, to2, to2-from2
+	return match
+}
+
+func TestGetPlayerRatingHistory(t *testing.T) {
+	setupTestDB(t)
+
+	alice := &models.Player{Name: "Alice", Elo: 1030}
+	bob := &models.Player{Name: "Bob", Elo: 1000}
+	carol := &models.Player{Name: "Carol", Elo: 985}
+	create(t, alice, bob, carol)
+
+	monday := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	at := func(days, hours int) time.Time {
+		return monday.AddDate(0, 0, days).Add(time.Duration(hours) * time.Hour)
+	}
+	matches := []*models.Match{
+		ratedMatch(alice, bob, at(0, 10), 1000, 1016, 1000, 984),
+		ratedMatch(bob, alice, at(0, 18), 984, 1010, 1016, 990),
+		ratedMatch(alice, carol, at(2, 9), 990, 1005, 1000, 985),
+		ratedMatch(bob, alice, at(7, 9), 1010, 985, 1005, 1030),
+	}
+	for _, match := range matches {
+		create(t, match)
+	}
+
+	history, err := GetPlayerRatingHistory(alice, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history.Interval != IntervalMatch || history.StartingRating != 1000 || history.CurrentRating != 1030 || history.TotalMatches != 4 {
+		t.Errorf("got %+v, want 4 matches from 1000 to 1030", history)
+	}
+	wantPoints := []RatingPoint{
+		{MatchID: matches[0].ID, OpponentID: bob.ID, Rating: 1016, Change: 16},
+		{MatchID: matches[1].ID, OpponentID: bob.ID, Rating: 990, Change: -26},
+		{MatchID: matches[2].ID, OpponentID: carol.ID, Rating: 1005, Change: 15},
+		{MatchID: matches[3].ID, OpponentID: bob.ID, Rating: 1030, Change: 25},
+	}
+	if len(history.Points) != len(wantPoints) {
+		t.Fatalf("got %d points, want %d", len(history.Points), len(wantPoints))
+	}
+	for i, want := range wantPoints {
+		got := history.Points[i]
+		want.Date = got.Date
+		if got != want || !got.Date.Equal(matches[i].PlayedAt) {
+			t.Errorf("point %d: got %+v, want %+v at %v", i+1, got, want, matches[i].PlayedAt)
+		}
+	}
+	if history.Peak.Rating != 1030 || history.Peak.MatchID == nil || *history.Peak.MatchID != matches[3].ID {
+		t.Errorf("got peak %+v, want 1030 after the last match", history.Peak)
+	}
+	if history.Lowest.Rating != 990 || history.Lowest.MatchID == nil || *history.Lowest.MatchID != matches[1].ID {
+		t.Errorf("got lowest %+v, want 990 after the second match", history.Lowest)
+	}
+
+	checkBuckets := func(interval string, want []RatingBucket) {
+		t.Helper()
+		history, err := GetPlayerRatingHistory(alice, interval)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if history.Points != nil || len(history.Buckets) != len(want) {
+			t.Fatalf("%s: got %d points and buckets %+v, want %d buckets", interval, len(history.Points), history.Buckets, len(want))
+		}
+		for i := range want {
+			got := history.Buckets[i]
+			if !got.Start.Equal(want[i].Start) || got.Open != want[i].Open || got.Min != want[i].Min ||
+				got.Max != want[i].Max || got.Close != want[i].Close || got.Matches != want[i].Matches {
+				t.Errorf("%s bucket %d: got %+v, want %+v", interval, i+1, got, want[i])
+			}
+		}
+	}
+	checkBuckets(IntervalDaily, []RatingBucket{
+		{Start: at(0, 0), Open: 1000, Min: 990, Max: 1016, Close: 990, Matches: 2},
+		{Start: at(2, 0), Open: 990, Min: 990, Max: 1005, Close: 1005, Matches: 1},
+		{Start: at(7, 0), Open: 1005, Min: 1005, Max: 1030, Close: 1030, Matches: 1},
+	})
+	checkBuckets(IntervalWeekly, []RatingBucket{
+		{Start: at(0, 0), Open: 1000, Min: 990, Max: 1016, Close: 1005, Matches: 3},
+		{Start: at(7, 0), Open: 1005, Min: 1005, Max: 1030, Close: 1030, Matches: 1},
+	})
+
+	// A player who has only lost peaks at the rating they started with
+	history, err = GetPlayerRatingHistory(carol, IntervalMatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history.Peak.Rating != 1000 || history.Peak.MatchID != nil || history.Lowest.Rating != 985 {
+		t.Errorf("got peak %+v and lowest %+v, want the starting 1000 and 985", history.Peak, history.Lowest)
+	}
+
+	if _, err := GetPlayerRatingHistory(alice, "monthly"); !isValidationError(err) {
+		t.Errorf("unknown interval: got %v, want a validation error", err)
+	}
+}
+
+func TestBucketStart(t *testing.T) {
+	// Early on Monday in Tokyo is still Sunday in UTC
+	sunday := time.Date(2024, 3, 11, 1, 30, 0, 0, time.FixedZone("JST", 9*60*60))
+	if got, want := bucketStart(sunday, IntervalDaily), time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("daily: got %v, want %v", got, want)
+	}
+	if got, want := bucketStart(sunday, IntervalWeekly), time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("weekly: got %v, want %v", got, want)
+	}
+}
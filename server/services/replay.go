@@ -0,0 +1,381 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+// PlayerReplayChange describes how a player's stored stats differ from the replayed ones
+type PlayerReplayChange struct {
+	PlayerID           uint    `json:"player_id"`
+	PlayerName         string  `json:"player_name"`
+	EloBefore          float64 `json:"elo_before"`
+	EloAfter           float64 `json:"elo_after"`
+	MatchesWonBefore   int     `json:"matches_won_before"`
+	MatchesWonAfter    int     `json:"matches_won_after"`
+	MatchesLostBefore  int     `json:"matches_lost_before"`
+	MatchesLostAfter   int     `json:"matches_lost_after"`
+	MatchesDrawnBefore int     `json:"matches_drawn_before"`
+	MatchesDrawnAfter  int     `json:"matches_drawn_after"`
+	TotalMatchesBefore int     `json:"total_matches_before"`
+	TotalMatchesAfter  int     `json:"total_matches_after"`
+}
+
+// MatchReplayChange describes how a match's stored rating fields differ from the replayed ones
+type MatchReplayChange struct {
+	MatchID                uint    `json:"match_id"`
+	Player1EloBefore       float64 `json:"player1_elo_before"`
+	Player1EloBeforeReplay float64 `json:"player1_elo_before_replay"`
+	Player1EloAfter        float64 `json:"player1_elo_after"`
+	Player1EloAfterReplay  float64 `json:"player1_elo_after_replay"`
+	Player2EloBefore       float64 `json:"player2_elo_before"`
+	Player2EloBeforeReplay float64 `json:"player2_elo_before_replay"`
+	Player2EloAfter        float64 `json:"player2_elo_after"`
+	Player2EloAfterReplay  float64 `json:"player2_elo_after_replay"`
+}
+
+// ReplayReport summarises a rating replay
+type ReplayReport struct {
+	DryRun          bool                 `json:"dry_run"`
+	RatingSystem    string               `json:"rating_system"`
+	MatchesReplayed int                  `json:"matches_replayed"`
+	PlayersChanged  []PlayerReplayChange `json:"players_changed"`
+	MatchesChanged  []MatchReplayChange  `json:"matches_changed"`
+	// Number of season rating rows that were (or would be) rewritten
+	SeasonRatingsChanged int `json:"season_ratings_changed"`
+}
+
+// replayState is the running state of a single player during a replay
+type replayState struct {
+	rating PlayerRating
+	won    int
+	lost   int
+	drawn  int
+	seed   float64 // season replays only, the rating after the soft reset
+	// when the player last played, for rating systems that account for inactivity
+	lastPlayed *time.Time
+}
+
+// ratingsDiffer compares two ratings at the 2-decimal precision they are stored with
+func ratingsDiffer(a, b float64) bool {
+	return math.Abs(a-b) >= 0.005
+}
+
+// ReplayRatings recomputes every player's rating and W/L/D counters, and every
+// match's before/after/change fields, by replaying the match table in
+// chronological order with the configured rating system. Season ratings are
+// replayed the same way. With dryRun set nothing is written and the report
+// only describes what would change.
+func ReplayRatings(db *gorm.DB, dryRun bool) (*ReplayReport, error) {
+	rating := Rating()
+	report := &ReplayReport{
+		DryRun:         dryRun,
+		RatingSystem:   rating.Name(),
+		PlayersChanged: []PlayerReplayChange{},
+		MatchesChanged: []MatchReplayChange{},
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Soft-deleted players keep their matches, so they are replayed too
+		var players []models.Player
+		if err := forUpdate(tx).Unscoped().Order("id ASC").Find(&players).Error; err != nil {
+			return err
+		}
+
+		var matches []models.Match
+		if err := tx.Order("played_at ASC, id ASC").Find(&matches).Error; err != nil {
+			return err
+		}
+
+		states := make(map[uint]*replayState, len(players))
+		for _, player := range players {
+			states[player.ID] = &replayState{rating: NewPlayerRating()}
+		}
+		stateFor := func(id uint) *replayState {
+			if states[id] == nil {
+				states[id] = &replayState{rating: NewPlayerRating()}
+			}
+			return states[id]
+		}
+
+		// Seasons are replayed alongside the all-time ratings, each seeded
+		// with a soft reset of the all-time ratings at the moment it started
+		var seasons []models.Season
+		if err := tx.Where("started_at IS NOT NULL").Order("started_at ASC, id ASC").Find(&seasons).Error; err != nil {
+			return err
+		}
+		var seasonRows []models.SeasonRating
+		if err := tx.Order("id ASC").Find(&seasonRows).Error; err != nil {
+			return err
+		}
+
+		seasonByID := make(map[uint]*models.Season, len(seasons))
+		for i := range seasons {
+			seasonByID[seasons[i].ID] = &seasons[i]
+		}
+		seasonStates := make(map[uint]map[uint]*replayState, len(seasons))
+		seasonStateFor := func(season *models.Season, playerID uint) *replayState {
+			if seasonStates[season.ID] == nil {
+				seasonStates[season.ID] = make(map[uint]*replayState)
+			}
+			if seasonStates[season.ID][playerID] == nil {
+				seed := softReset(stateFor(playerID).rating, season.ResetPercent)
+				seasonStates[season.ID][playerID] = &replayState{rating: seed, seed: seed.Rating}
+			}
+			return seasonStates[season.ID][playerID]
+		}
+
+		nextSeason := 0
+		startSeasons := func(until *time.Time) {
+			for ; nextSeason < len(seasons); nextSeason++ {
+				season := &seasons[nextSeason]
+				if until != nil && season.StartedAt.After(*until) {
+					return
+				}
+				for _, row := range seasonRows {
+					if row.SeasonID == season.ID {
+						seasonStateFor(season, row.PlayerID)
+					}
+				}
+			}
+		}
+
+		for _, match := range matches {
+			startSeasons(&match.PlayedAt)
+			playedAt := match.PlayedAt
+
+			p1 := stateFor(match.Player1ID)
+			p2 := stateFor(match.Player2ID)
+
+			if match.SeasonID != nil && seasonByID[*match.SeasonID] != nil {
+				season := seasonByID[*match.SeasonID]
+				s1 := seasonStateFor(season, match.Player1ID)
+				s2 := seasonStateFor(season, match.Player2ID)
+				s1.rating = idleRating(rating, s1.rating, s1.lastPlayed, playedAt)
+				s2.rating = idleRating(rating, s2.rating, s2.lastPlayed, playedAt)
+				seasonResult := rating.Update(s1.rating, s2.rating, match.Player1Score, match.Player2Score)
+				s1.rating = seasonResult.Player1
+				s2.rating = seasonResult.Player2
+				s1.lastPlayed = &playedAt
+				s2.lastPlayed = &playedAt
+				s1.rating.TotalMatches++
+				s2.rating.TotalMatches++
+				switch {
+				case match.Player1Score > match.Player2Score:
+					s1.won++
+					s2.lost++
+				case match.Player2Score > match.Player1Score:
+					s2.won++
+					s1.lost++
+				default:
+					s1.drawn++
+					s2.drawn++
+				}
+			}
+
+			p1.rating = idleRating(rating, p1.rating, p1.lastPlayed, playedAt)
+			p2.rating = idleRating(rating, p2.rating, p2.lastPlayed, playedAt)
+			result := rating.Update(p1.rating, p2.rating, match.Player1Score, match.Player2Score)
+			p1.lastPlayed = &playedAt
+			p2.lastPlayed = &playedAt
+
+			var winnerID *uint
+			if match.Player1Score > match.Player2Score {
+				id := match.Player1ID
+				winnerID = &id
+				p1.won++
+				p2.lost++
+			} else if match.Player2Score > match.Player1Score {
+				id := match.Player2ID
+				winnerID = &id
+				p2.won++
+				p1.lost++
+			} else {
+				p1.drawn++
+				p2.drawn++
+			}
+
+			change := MatchReplayChange{
+				MatchID:                match.ID,
+				Player1EloBefore:       match.Player1EloBefore,
+				Player1EloBeforeReplay: p1.rating.Rating,
+				Player1EloAfter:        match.Player1EloAfter,
+				Player1EloAfterReplay:  result.Player1.Rating,
+				Player2EloBefore:       match.Player2EloBefore,
+				Player2EloBeforeReplay: p2.rating.Rating,
+				Player2EloAfter:        match.Player2EloAfter,
+				Player2EloAfterReplay:  result.Player2.Rating,
+			}
+
+			winnerChanged := (winnerID == nil) != (match.WinnerID == nil) ||
+				(winnerID != nil && *winnerID != *match.WinnerID)
+
+			if winnerChanged ||
+				ratingsDiffer(match.Player1EloBefore, p1.rating.Rating) ||
+				ratingsDiffer(match.Player2EloBefore, p2.rating.Rating) ||
+				ratingsDiffer(match.Player1EloAfter, result.Player1.Rating) ||
+				ratingsDiffer(match.Player2EloAfter, result.Player2.Rating) {
+				report.MatchesChanged = append(report.MatchesChanged, change)
+
+				if !dryRun {
+					if err := tx.Model(&models.Match{}).Where("id = ?", match.ID).Updates(map[string]interface{}{
+						"winner_id":          winnerID,
+						"player1_elo_before": p1.rating.Rating,
+						"player2_elo_before": p2.rating.Rating,
+						"player1_elo_after":  result.Player1.Rating,
+						"player2_elo_after":  result.Player2.Rating,
+						"player1_elo_change": result.Player1Change,
+						"player2_elo_change": result.Player2Change,
+					}).Error; err != nil {
+						return err
+					}
+				}
+			}
+
+			p1.rating = result.Player1
+			p2.rating = result.Player2
+			p1.rating.TotalMatches++
+			p2.rating.TotalMatches++
+			report.MatchesReplayed++
+		}
+
+		startSeasons(nil)
+
+		changed, err := writeSeasonReplay(tx, seasons, seasonRows, seasonStates, dryRun)
+		if err != nil {
+			return err
+		}
+		report.SeasonRatingsChanged = changed
+
+		for _, player := range players {
+			state := states[player.ID]
+			total := state.rating.TotalMatches
+
+			if !ratingsDiffer(player.Elo, state.rating.Rating) &&
+				player.MatchesWon == state.won &&
+				player.MatchesLost == state.lost &&
+				player.MatchesDrawn == state.drawn &&
+				player.TotalMatches == total &&
+				!ratingsDiffer(RatingFromPlayer(&player).Deviation, state.rating.Deviation) {
+				continue
+			}
+
+			report.PlayersChanged = append(report.PlayersChanged, PlayerReplayChange{
+				PlayerID:           player.ID,
+				PlayerName:         player.Name,
+				EloBefore:          player.Elo,
+				EloAfter:           state.rating.Rating,
+				MatchesWonBefore:   player.MatchesWon,
+				MatchesWonAfter:    state.won,
+				MatchesLostBefore:  player.MatchesLost,
+				MatchesLostAfter:   state.lost,
+				MatchesDrawnBefore: player.MatchesDrawn,
+				MatchesDrawnAfter:  state.drawn,
+				TotalMatchesBefore: player.TotalMatches,
+				TotalMatchesAfter:  total,
+			})
+
+			if dryRun {
+				continue
+			}
+
+			if err := tx.Unscoped().Model(&models.Player{}).Where("id = ?", player.ID).Updates(map[string]interface{}{
+				"elo":               state.rating.Rating,
+				"rating_deviation":  state.rating.Deviation,
+				"rating_volatility": state.rating.Volatility,
+				"matches_won":       state.won,
+				"matches_lost":      state.lost,
+				"matches_drawn":     state.drawn,
+				"total_matches":     total,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// writeSeasonReplay compares the replayed season ratings with the stored rows
+// and, unless dryRun is set, rewrites the ones that differ. It returns the
+// number of rows that differ.
+func writeSeasonReplay(tx *gorm.DB, seasons []models.Season, rows []models.SeasonRating, states map[uint]map[uint]*replayState, dryRun bool) (int, error) {
+	stored := make(map[uint]map[uint]*models.SeasonRating, len(seasons))
+	for i := range rows {
+		if stored[rows[i].SeasonID] == nil {
+			stored[rows[i].SeasonID] = make(map[uint]*models.SeasonRating)
+		}
+		stored[rows[i].SeasonID][rows[i].PlayerID] = &rows[i]
+	}
+
+	changed := 0
+	for _, season := range seasons {
+		playerIDs := make([]uint, 0, len(states[season.ID]))
+		for playerID := range states[season.ID] {
+			playerIDs = append(playerIDs, playerID)
+		}
+		sort.Slice(playerIDs, func(i, j int) bool { return playerIDs[i] < playerIDs[j] })
+
+		for _, playerID := range playerIDs {
+			state := states[season.ID][playerID]
+			row := stored[season.ID][playerID]
+			if row == nil {
+				row = &models.SeasonRating{SeasonID: season.ID, PlayerID: playerID}
+			}
+
+			if row.ID != 0 &&
+				!ratingsDiffer(row.StartingElo, state.seed) &&
+				!ratingsDiffer(row.Elo, state.rating.Rating) &&
+				row.MatchesWon == state.won &&
+				row.MatchesLost == state.lost &&
+				row.MatchesDrawn == state.drawn &&
+				row.TotalMatches == state.rating.TotalMatches {
+				continue
+			}
+			changed++
+			if dryRun {
+				continue
+			}
+
+			row.StartingElo = state.seed
+			applySeasonRating(row, state.rating)
+			row.MatchesWon = state.won
+			row.MatchesLost = state.lost
+			row.MatchesDrawn = state.drawn
+			row.TotalMatches = state.rating.TotalMatches
+			if err := tx.Save(row).Error; err != nil {
+				return 0, err
+			}
+		}
+	}
+	return changed, nil
+}
+
+// RecomputeRatings replays the whole match log against the main database and
+// refreshes the championship in the same transaction when anything was written
+func RecomputeRatings(dryRun bool) (*ReplayReport, error) {
+	var report *ReplayReport
+	err := WriteTransaction(func(tx *gorm.DB) error {
+		var err error
+		report, err = ReplayRatings(tx, dryRun)
+		if err != nil || dryRun || len(report.PlayersChanged) == 0 {
+			return err
+		}
+		return RefreshChampion(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// League points awarded in round robin standings
+	roundRobinWinPoints  = 3
+	roundRobinDrawPoints = 1
+)
+
+// bergerRounds returns the pairings of every round of a round robin between
+// n players as indexes into the seeded field, using Berger tables. For an
+// odd field the player paired with the extra "ghost" sits out that round.
+func bergerRounds(n int) [][][2]int {
+	size := n
+	if size%2 == 1 {
+		size++
+	}
+	fixed := size - 1
+
+	rounds := make([][][2]int, 0, size-1)
+	for r := 0; r < size-1; r++ {
+		pairs := make([][2]int, 0, size/2)
+
+		// The fixed player alternates sides every round
+		if r%2 == 0 {
+			pairs = append(pairs, [2]int{r, fixed})
+		} else {
+			pairs = append(pairs, [2]int{fixed, r})
+		}
+		for i := 1; i < size/2; i++ {
+			home := (r + i) % (size - 1)
+			away := (r - i + size - 1) % (size - 1)
+			pairs = append(pairs, [2]int{home, away})
+		}
+
+		// Drop the pairing against the ghost player of an odd field
+		playable := pairs[:0]
+		for _, pair := range pairs {
+			if pair[0] < n && pair[1] < n {
+				playable = append(playable, pair)
+			}
+		}
+		rounds = append(rounds, playable)
+	}
+	return rounds
+}
+
+// generateRoundRobin creates the full fixture list of a round robin. A double
+// round robin plays the whole cycle again with sides swapped.
+func generateRoundRobin(tx *gorm.DB, tournament *models.Tournament, seeded []models.TournamentParticipant) error {
+	rounds := bergerRounds(len(seeded))
+	cycle := len(rounds)
+	if tournament.DoubleRoundRobin {
+		for r := 0; r < cycle; r++ {
+			swapped := make([][2]int, len(rounds[r]))
+			for i, pair := range rounds[r] {
+				swapped[i] = [2]int{pair[1], pair[0]}
+			}
+			rounds = append(rounds, swapped)
+		}
+	}
+
+	tournament.TotalRounds = len(rounds)
+	if err := tx.Model(tournament).Update("total_rounds", tournament.TotalRounds).Error; err != nil {
+		return err
+	}
+
+	fixtures := make([]models.TournamentMatch, 0)
+	for r, pairs := range rounds {
+		for i, pair := range pairs {
+			player1 := seeded[pair[0]].PlayerID
+			player2 := seeded[pair[1]].PlayerID
+			fixtures = append(fixtures, models.TournamentMatch{
+				TournamentID: tournament.ID,
+				Round:        r + 1,
+				Position:     i + 1,
+				Player1ID:    &player1,
+				Player2ID:    &player2,
+			})
+		}
+	}
+	return tx.Create(&fixtures).Error
+}
+
+// progressRoundRobin completes the tournament once every fixture is played
+func progressRoundRobin(tx *gorm.DB, tournament *models.Tournament) error {
+	var pending int64
+	if err := tx.Model(&models.TournamentMatch{}).
+		Where("tournament_id = ? AND completed_at IS NULL", tournament.ID).
+		Count(&pending).Error; err != nil {
+		return err
+	}
+	if pending > 0 {
+		return nil
+	}
+
+	standings, err := tournamentStandings(tx, tournament)
+	if err != nil {
+		return err
+	}
+	return completeTournament(tx, tournament, standings[0].PlayerID)
+}
+
+// roundRobinStandings ranks by league points (3 per win, 1 per draw), then by
+// head-to-head points among the tied players, then score difference and
+// score for
+func roundRobinStandings(results *tournamentResults) []StandingEntry {
+	for _, entry := range results.entries {
+		entry.Points = float64(entry.Won*roundRobinWinPoints + entry.Drawn*roundRobinDrawPoints)
+		entry.HeadToHead = 0
+	}
+
+	// Head-to-head only counts matches between players level on points
+	for _, m := range results.completed {
+		if m.IsBye || m.Player1ID == nil || m.Player2ID == nil {
+			continue
+		}
+		p1, p2 := results.entries[*m.Player1ID], results.entries[*m.Player2ID]
+		if p1 == nil || p2 == nil || p1.Points != p2.Points {
+			continue
+		}
+		switch {
+		case m.WinnerID == nil:
+			p1.HeadToHead += roundRobinDrawPoints
+			p2.HeadToHead += roundRobinDrawPoints
+		case *m.WinnerID == p1.PlayerID:
+			p1.HeadToHead += roundRobinWinPoints
+		default:
+			p2.HeadToHead += roundRobinWinPoints
+		}
+	}
+
+	standings := make([]StandingEntry, 0, len(results.order))
+	for _, id := range results.order {
+		standings = append(standings, *results.entries[id])
+	}
+	sortStandings(standings, func(a, b *StandingEntry) int {
+		if c := compareFloat(a.Points, b.Points); c != 0 {
+			return c
+		}
+		if c := compareFloat(a.HeadToHead, b.HeadToHead); c != 0 {
+			return c
+		}
+		if a.ScoreDifference != b.ScoreDifference {
+			return compareFloat(float64(a.ScoreDifference), float64(b.ScoreDifference))
+		}
+		return compareFloat(float64(a.ScoreFor), float64(b.ScoreFor))
+	})
+	return standings
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"testing"
+
+	"stone-paper-scissors/models"
+)
+
+func TestBergerRounds(t *testing.T) {
+	want := [][][2]int{
+		{{0, 3}, {1, 2}},
+		{{3, 1}, {2, 0}},
+		{{2, 3}, {0, 1}},
+	}
+	got := bergerRounds(4)
+	if len(got) != len(want) {
+		t.Fatalf("got %d rounds, want %d", len(got), len(want))
+	}
+	for r := range want {
+		if len(got[r]) != len(want[r]) {
+			t.Fatalf("round %d: got %v, want %v", r+1, got[r], want[r])
+		}
+		for i := range want[r] {
+			if got[r][i] != want[r][i] {
+				t.Errorf("round %d: got %v, want %v", r+1, got[r], want[r])
+				break
+			}
+		}
+	}
+
+	// Every pair meets exactly once and nobody plays twice in a round
+	for n := 2; n <= 9; n++ {
+		rounds := bergerRounds(n)
+		wantRounds := n - 1
+		if n%2 == 1 {
+			wantRounds = n
+		}
+		if len(rounds) != wantRounds {
+			t.Errorf("%d players: got %d rounds, want %d", n, len(rounds), wantRounds)
+		}
+		met := make(map[[2]int]int)
+		sittingOut := make(map[int]int)
+		for r, pairs := range rounds {
+			playing := make(map[int]bool)
+			for _, pair := range pairs {
+				if playing[pair[0]] || playing[pair[1]] {
+					t.Errorf("%d players, round %d: a player plays twice in %v", n, r+1, pairs)
+				}
+				playing[pair[0]], playing[pair[1]] = true, true
+				low, high := pair[0], pair[1]
+				if low > high {
+					low, high = high, low
+				}
+				met[[2]int{low, high}]++
+			}
+			for i := 0; i < n; i++ {
+				if !playing[i] {
+					sittingOut[i]++
+				}
+			}
+		}
+		if len(met) != n*(n-1)/2 {
+			t.Errorf("%d players: got %d distinct pairings, want %d", n, len(met), n*(n-1)/2)
+		}
+		for pair, count := range met {
+			if count != 1 {
+				t.Errorf("%d players: %v meet %d times", n, pair, count)
+			}
+		}
+		for i := 0; i < n && n%2 == 1; i++ {
+			if sittingOut[i] != 1 {
+				t.Errorf("%d players: player %d sits out %d rounds, want 1", n, i, sittingOut[i])
+			}
+		}
+	}
+}
+
+func TestDoubleRoundRobinStandings(t *testing.T) {
+	setupTestDB(t)
+
+	tournament, seed := startTestTournament(t, models.CreateTournamentRequest{
+		Format:           models.FormatRoundRobin,
+		DoubleRoundRobin: true,
+	}, 3)
+	if tournament.TotalRounds != 6 || len(tournament.Matches) != 6 {
+		t.Fatalf("got %d rounds and %d fixtures, want 6 and 6", tournament.TotalRounds, len(tournament.Matches))
+	}
+
+	// The second cycle repeats the first with sides swapped
+	for _, first := range tournament.Matches[:3] {
+		second := tournamentSlot(t, tournament.ID, "", first.Round+3, first.Position)
+		if *second.Player1ID != *first.Player2ID || *second.Player2ID != *first.Player1ID {
+			t.Errorf("round %d: got %v against %v, want the sides of round %d swapped",
+				second.Round, *second.Player1ID, *second.Player2ID, first.Round)
+		}
+	}
+
+	// Everyone beats one player and loses to the other, and draws the return
+	// legs: level on points and head-to-head, so score difference decides
+	beats := map[uint]*models.Player{seed[0].ID: seed[1], seed[1].ID: seed[2], seed[2].ID: seed[0]}
+	scores := map[uint]int{seed[0].ID: 5, seed[1].ID: 2, seed[2].ID: 3}
+	for _, fixture := range tournament.Matches {
+		if fixture.Round > 3 {
+			playTournamentMatch(t, fixture, nil)
+			continue
+		}
+		winner, loser := *fixture.Player1ID, *fixture.Player2ID
+		if beats[winner].ID != loser {
+			winner, loser = loser, winner
+		}
+		req := models.TournamentResultRequest{Player1Score: scores[winner], Player2Score: 1}
+		if *fixture.Player1ID != winner {
+			req = models.TournamentResultRequest{Player1Score: 1, Player2Score: scores[winner]}
+		}
+		if _, err := RecordTournamentResult(tournament.ID, fixture.ID, req, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	standings, err := GetTournamentStandings(tournament.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []*models.Player{seed[0], seed[2], seed[1]} {
+		got := standings[i]
+		if got.PlayerID != want.ID {
+			t.Errorf("place %d: got %s, want %s", i+1, got.PlayerName, want.Name)
+		}
+		if got.Points != 5 || got.Won != 1 || got.Drawn != 2 || got.Lost != 1 {
+			t.Errorf("%s: got %v points from %d-%d-%d, want 5 from 1-2-1", got.PlayerName, got.Points, got.Won, got.Drawn, got.Lost)
+		}
+	}
+	for i, want := range []int{2, 1, -3} {
+		if got := standings[i].ScoreDifference; got != want {
+			t.Errorf("place %d: got score difference %d, want %d", i+1, got, want)
+		}
+	}
+
+	checkTournamentWinner(t, tournament.ID, seed[0])
+}
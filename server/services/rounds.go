@@ -0,0 +1,51 @@
+package services
+
+import (
+	"strings"
+
+	"stone-paper-scissors/models"
+)
+
+// BuildRounds validates submitted throws and turns them into MatchRound rows.
+// The rounds must produce exactly the submitted score: every won round counts
+// as one point and drawn rounds count for nobody.
+func BuildRounds(rounds []models.RoundRequest, player1Score, player2Score int) ([]models.MatchRound, error) {
+	if len(rounds) == 0 {
+		return nil, nil
+	}
+
+	result := make([]models.MatchRound, 0, len(rounds))
+	player1Wins, player2Wins := 0, 0
+
+	for i, round := range rounds {
+		p1 := models.Throw(strings.ToLower(string(round.Player1Throw)))
+		p2 := models.Throw(strings.ToLower(string(round.Player2Throw)))
+
+		if !p1.Valid() || !p2.Valid() {
+			return nil, invalid("round %d: throws must be stone, paper or scissor", i+1)
+		}
+
+		outcome := models.RoundDraw
+		if p1.Beats(p2) {
+			outcome = models.RoundPlayer1
+			player1Wins++
+		} else if p2.Beats(p1) {
+			outcome = models.RoundPlayer2
+			player2Wins++
+		}
+
+		result = append(result, models.MatchRound{
+			RoundNumber:  i + 1,
+			Player1Throw: p1,
+			Player2Throw: p2,
+			Outcome:      outcome,
+		})
+	}
+
+	if player1Wins != player1Score || player2Wins != player2Score {
+		return nil, invalid("rounds produce a %d-%d score but %d-%d was submitted",
+			player1Wins, player2Wins, player1Score, player2Score)
+	}
+
+	return result, nil
+}
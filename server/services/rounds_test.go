@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+
+	"stone-paper-scissors/models"
+)
+
+func TestBuildRounds(t *testing.T) {
+	rounds, err := BuildRounds([]models.RoundRequest{
+		{Player1Throw: "Stone", Player2Throw: "scissor"},
+		{Player1Throw: "paper", Player2Throw: "paper"},
+		{Player1Throw: "paper", Player2Throw: "scissor"},
+		{Player1Throw: "scissor", Player2Throw: "paper"},
+	}, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.MatchRound{
+		{RoundNumber: 1, Player1Throw: models.ThrowStone, Player2Throw: models.ThrowScissor, Outcome: models.RoundPlayer1},
+		{RoundNumber: 2, Player1Throw: models.ThrowPaper, Player2Throw: models.ThrowPaper, Outcome: models.RoundDraw},
+		{RoundNumber: 3, Player1Throw: models.ThrowPaper, Player2Throw: models.ThrowScissor, Outcome: models.RoundPlayer2},
+		{RoundNumber: 4, Player1Throw: models.ThrowScissor, Player2Throw: models.ThrowPaper, Outcome: models.RoundPlayer1},
+	}
+	if len(rounds) != len(want) {
+		t.Fatalf("got %d rounds, want %d", len(rounds), len(want))
+	}
+	for i := range want {
+		if rounds[i] != want[i] {
+			t.Errorf("round %d: got %+v, want %+v", i+1, rounds[i], want[i])
+		}
+	}
+
+	if rounds, err := BuildRounds(nil, 3, 0); rounds != nil || err != nil {
+		t.Errorf("no rounds: got %v, %v, want none", rounds, err)
+	}
+}
+
+func TestBuildRoundsRejectsInvalidRounds(t *testing.T) {
+	for name, rounds := range map[string][]models.RoundRequest{
+		"unknown throw": {{Player1Throw: "rock", Player2Throw: "paper"}},
+		"missing throw": {{Player1Throw: "stone"}},
+		"wrong score":   {{Player1Throw: "stone", Player2Throw: "paper"}},
+	} {
+		_, err := BuildRounds(rounds, 1, 0)
+		if _, ok := err.(*ValidationError); !ok {
+			t.Errorf("%s: got %v, want a validation error", name, err)
+		}
+	}
+}
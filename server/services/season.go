@@ -0,0 +1,319 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+// DefaultSeasonResetPercent is how far ratings regress toward DefaultRating
+// at the start of a season when no reset is given
+const DefaultSeasonResetPercent = 50.0
+
+// softReset regresses a rating toward DefaultRating by percent (0-100). The
+// season match count starts from zero, deviation and volatility carry over.
+func softReset(rating PlayerRating, percent float64) PlayerRating {
+	rating.Rating = math.Round((rating.Rating-(rating.Rating-DefaultRating)*percent/100)*100) / 100
+	rating.TotalMatches = 0
+	return rating
+}
+
+// RatingFromSeason extracts the rating state of a player within a season
+func RatingFromSeason(row *models.SeasonRating) PlayerRating {
+	rating := PlayerRating{
+		Rating:       row.Elo,
+		Deviation:    row.RatingDeviation,
+		Volatility:   row.RatingVolatility,
+		TotalMatches: row.TotalMatches,
+	}
+	if rating.Deviation == 0 {
+		rating.Deviation = DefaultDeviation
+	}
+	if rating.Volatility == 0 {
+		rating.Volatility = DefaultVolatility
+	}
+	return rating
+}
+
+// applySeasonRating stores a rating system result on a season rating row
+func applySeasonRating(row *models.SeasonRating, rating PlayerRating) {
+	row.Elo = rating.Rating
+	row.RatingDeviation = rating.Deviation
+	row.RatingVolatility = rating.Volatility
+}
+
+// newSeasonRating builds the season row of a player seeded from their all-time rating
+func newSeasonRating(season *models.Season, playerID uint, allTime PlayerRating) models.SeasonRating {
+	seed := softReset(allTime, season.ResetPercent)
+	row := models.SeasonRating{
+		SeasonID:    season.ID,
+		PlayerID:    playerID,
+		StartingElo: seed.Rating,
+	}
+	applySeasonRating(&row, seed)
+	return row
+}
+
+// CreateSeason registers a new season; ratings are only reset when it starts
+func CreateSeason(req models.CreateSeasonRequest, createdByAdminID *uint) (*models.Season, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, invalid("Season name is required")
+	}
+
+	resetPercent := DefaultSeasonResetPercent
+	if req.ResetPercent != nil {
+		resetPercent = *req.ResetPercent
+	}
+	if resetPercent < 0 || resetPercent > 100 {
+		return nil, invalid("Reset percent must be between 0 and 100")
+	}
+
+	var count int64
+	if err := config.DB.Model(&models.Season{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, invalid("A season named %q already exists", name)
+	}
+
+	season := models.Season{
+		Name:             name,
+		Status:           models.SeasonPending,
+		ResetPercent:     resetPercent,
+		CreatedByAdminID: createdByAdminID,
+	}
+	if err := config.DB.Create(&season).Error; err != nil {
+		return nil, err
+	}
+	return &season, nil
+}
+
+// ListSeasons returns every season, newest first
+func ListSeasons() ([]models.Season, error) {
+	var seasons []models.Season
+	err := config.DB.Preload("Champion").Order("created_at DESC, id DESC").Find(&seasons).Error
+	return seasons, err
+}
+
+// GetSeason loads a season with its champion
+func GetSeason(id uint) (*models.Season, error) {
+	var season models.Season
+	if err := config.DB.Preload("Champion").First(&season, id).Error; err != nil {
+		return nil, err
+	}
+	return &season, nil
+}
+
+// ResolveSeason looks a season up by ID, or returns the active season for "current"
+func ResolveSeason(ref string) (*models.Season, error) {
+	if ref == "current" {
+		season, err := activeSeason(config.DB)
+		if err != nil {
+			return nil, err
+		}
+		if season == nil {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return GetSeason(season.ID)
+	}
+
+	id, err := strconv.ParseUint(ref, 10, 32)
+	if err != nil {
+		return nil, invalid("Season must be a season ID or \"current\"")
+	}
+	return GetSeason(uint(id))
+}
+
+// activeSeason returns the season in progress, or nil when there is none
+func activeSeason(db *gorm.DB) (*models.Season, error) {
+	var season models.Season
+	err := db.Where("status = ?", models.SeasonActive).First(&season).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &season, nil
+}
+
+// seasonAt returns the season that was running at t, or nil when there was none
+func seasonAt(db *gorm.DB, t time.Time) (*models.Season, error) {
+	var season models.Season
+	err := db.Where("started_at <= ? AND (ended_at IS NULL OR ended_at > ?)", t, t).
+		Order("started_at DESC").
+		First(&season).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &season, nil
+}
+
+// StartSeason opens a season and seeds every player's season rating with a
+// soft reset of their all-time rating
+func StartSeason(id uint) (*models.Season, error) {
+	err := WriteTransaction(func(tx *gorm.DB) error {
+		var season models.Season
+		if err := tx.First(&season, id).Error; err != nil {
+			return err
+		}
+		if season.Status != models.SeasonPending {
+			return invalid("Season has already started")
+		}
+
+		current, err := activeSeason(tx)
+		if err != nil {
+			return err
+		}
+		if current != nil {
+			return invalid("Season %q is still active, end it first", current.Name)
+		}
+
+		now := time.Now().UTC()
+		season.Status = models.SeasonActive
+		season.StartedAt = &now
+		if err := tx.Save(&season).Error; err != nil {
+			return err
+		}
+
+		var players []models.Player
+		if err := tx.Find(&players).Error; err != nil {
+			return err
+		}
+		if len(players) == 0 {
+			return RefreshChampion(tx)
+		}
+
+		rows := make([]models.SeasonRating, 0, len(players))
+		for i := range players {
+			rows = append(rows, newSeasonRating(&season, players[i].ID, RatingFromPlayer(&players[i])))
+		}
+		if err := tx.Create(&rows).Error; err != nil {
+			return err
+		}
+		return RefreshChampion(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return GetSeason(id)
+}
+
+// EndSeason closes the active season and crowns the top rated player of the
+// season as its champion
+func EndSeason(id uint) (*models.Season, error) {
+	err := WriteTransaction(func(tx *gorm.DB) error {
+		var season models.Season
+		if err := tx.First(&season, id).Error; err != nil {
+			return err
+		}
+		if season.Status != models.SeasonActive {
+			return invalid("Season is not active")
+		}
+
+		now := time.Now().UTC()
+		season.Status = models.SeasonEnded
+		season.EndedAt = &now
+
+		var top models.SeasonRating
+		err := seasonLeaderboardQuery(tx, season.ID).First(&top).Error
+		switch {
+		case err == nil:
+			season.ChampionID = &top.PlayerID
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		if err := tx.Save(&season).Error; err != nil {
+			return err
+		}
+
+		// The season's championship ends with the season
+		return tx.Model(&models.ChampionshipReign{}).
+			Where("season_id = ? AND ended_at IS NULL", season.ID).
+			Update("ended_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return GetSeason(id)
+}
+
+// seasonLeaderboardQuery ranks the players who have played in a season
+func seasonLeaderboardQuery(db *gorm.DB, seasonID uint) *gorm.DB {
+	return db.Model(&models.SeasonRating{}).
+		Where("season_id = ? AND total_matches > 0", seasonID).
+		Order("elo DESC, player_id ASC")
+}
+
+// GetSeasonLeaderboard returns the season ratings of everyone who has played
+// in the season, highest first
+func GetSeasonLeaderboard(seasonID uint, limit, offset int) ([]models.SeasonRating, int64, error) {
+	var total int64
+	if err := seasonLeaderboardQuery(config.DB, seasonID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var ratings []models.SeasonRating
+	err := seasonLeaderboardQuery(config.DB, seasonID).
+		Preload("Player").
+		Limit(limit).
+		Offset(offset).
+		Find(&ratings).Error
+	return ratings, total, err
+}
+
+// recordSeasonResult rates a match within the active season. Players who
+// joined after the season started are seeded from their all-time rating,
+// so it must run before the all-time ratings are updated.
+func recordSeasonResult(tx *gorm.DB, season *models.Season, player1, player2 *models.Player, score1, score2 int, playedAt time.Time) error {
+	rows := make([]models.SeasonRating, 2)
+	ratings := make([]PlayerRating, 2)
+	for i, player := range []*models.Player{player1, player2} {
+		err := tx.Where("season_id = ? AND player_id = ?", season.ID, player.ID).First(&rows[i]).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			rows[i] = newSeasonRating(season, player.ID, RatingFromPlayer(player))
+		} else if err != nil {
+			return err
+		}
+		if ratings[i], err = idleRatingAt(tx, RatingFromSeason(&rows[i]), player.ID, &season.ID, playedAt); err != nil {
+			return err
+		}
+	}
+
+	result := Rating().Update(ratings[0], ratings[1], score1, score2)
+	applySeasonRating(&rows[0], result.Player1)
+	applySeasonRating(&rows[1], result.Player2)
+
+	switch {
+	case score1 > score2:
+		rows[0].MatchesWon++
+		rows[1].MatchesLost++
+	case score2 > score1:
+		rows[1].MatchesWon++
+		rows[0].MatchesLost++
+	default:
+		rows[0].MatchesDrawn++
+		rows[1].MatchesDrawn++
+	}
+
+	for i := range rows {
+		rows[i].TotalMatches++
+		if err := tx.Save(&rows[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+// recordTestMatch records a match played now through RecordMatch
+func recordTestMatch(t *testing.T, player1, player2 *models.Player, score1, score2 int) *models.Match {
+	t.Helper()
+	var match *models.Match
+	err := WriteTransaction(func(tx *gorm.DB) error {
+		var err error
+		match, err = RecordMatch(tx, MatchInput{
+			Player1ID:    player1.ID,
+			Player2ID:    player2.ID,
+			Player1Score: score1,
+			Player2Score: score2,
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return match
+}
+
+// seasonRating loads the season rating of a player
+func seasonRating(t *testing.T, seasonID uint, player *models.Player) models.SeasonRating {
+	t.Helper()
+	var row models.SeasonRating
+	if err := config.DB.Where("season_id = ? AND player_id = ?", seasonID, player.ID).First(&row).Error; err != nil {
+		t.Fatalf("%s: %v", player.Name, err)
+	}
+	return row
+}
+
+// isValidationError reports whether err is a *ValidationError
+func isValidationError(err error) bool {
+	_, ok := err.(*ValidationError)
+	return ok
+}
+
+func TestCreateSeasonValidation(t *testing.T) {
+	setupTestDB(t)
+
+	if _, err := CreateSeason(models.CreateSeasonRequest{Name: "Spring"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	tooMuch := 150.0
+	for name, req := range map[string]models.CreateSeasonRequest{
+		"no name":        {Name: " "},
+		"duplicate name": {Name: "Spring"},
+		"reset over 100": {Name: "Summer", ResetPercent: &tooMuch},
+	} {
+		if _, err := CreateSeason(req, nil); !isValidationError(err) {
+			t.Errorf("%s: got %v, want a validation error", name, err)
+		}
+	}
+}
+
+func TestSeasonSoftResetAndAssignment(t *testing.T) {
+	setupTestDB(t)
+
+	alice := &models.Player{Name: "Alice", Elo: 1400}
+	bob := &models.Player{Name: "Bob", Elo: 800}
+	carol := &models.Player{Name: "Carol", Elo: 1000}
+	create(t, alice, bob, carol)
+
+	// Played before any season
+	before := recordTestMatch(t, bob, carol, 1, 1)
+	if before.SeasonID != nil {
+		t.Errorf("got season %d for a match outside every season", *before.SeasonID)
+	}
+	if err := config.DB.First(bob, bob.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	reset := 25.0
+	season, err := CreateSeason(models.CreateSeasonRequest{Name: "Spring", ResetPercent: &reset}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if season, err = StartSeason(season.ID); err != nil {
+		t.Fatal(err)
+	}
+	if season.Status != models.SeasonActive || season.StartedAt == nil {
+		t.Fatalf("got status %s, started %v, want an active season", season.Status, season.StartedAt)
+	}
+	if _, err := StartSeason(season.ID); !isValidationError(err) {
+		t.Errorf("starting twice: got %v, want a validation error", err)
+	}
+	next, err := CreateSeason(models.CreateSeasonRequest{Name: "Summer"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := StartSeason(next.ID); !isValidationError(err) {
+		t.Errorf("starting a second season: got %v, want a validation error", err)
+	}
+
+	// Ratings regress a quarter of the way toward the default rating
+	for _, player := range []*models.Player{alice, bob} {
+		row := seasonRating(t, season.ID, player)
+		want := math.Round((player.Elo-(player.Elo-DefaultRating)*reset/100)*100) / 100
+		if row.StartingElo != want || row.Elo != want || row.TotalMatches != 0 {
+			t.Errorf("%s: got starting %v, rating %v after %d matches, want %v", player.Name, row.StartingElo, row.Elo, row.TotalMatches, want)
+		}
+	}
+
+	// Players joining mid-season are seeded from their all-time rating too
+	dave := &models.Player{Name: "Dave", Elo: 1200}
+	create(t, dave)
+	during := recordTestMatch(t, alice, bob, 2, 0)
+	recordTestMatch(t, dave, bob, 2, 1)
+	if during.SeasonID == nil || *during.SeasonID != season.ID {
+		t.Errorf("got season %v, want the match counted in %s", during.SeasonID, season.Name)
+	}
+	if row := seasonRating(t, season.ID, dave); row.StartingElo != 1150 || row.MatchesWon != 1 || row.TotalMatches != 1 {
+		t.Errorf("Dave: got starting %v with %d wins in %d matches, want 1150 and a win", row.StartingElo, row.MatchesWon, row.TotalMatches)
+	}
+	if row := seasonRating(t, season.ID, bob); row.MatchesLost != 2 || row.Elo >= row.StartingElo {
+		t.Errorf("Bob: got %d losses, rating %v from %v, want 2 losses and a lower rating", row.MatchesLost, row.Elo, row.StartingElo)
+	}
+
+	// Only players who played are ranked, and the leader is crowned at the end
+	leaderboard, total, err := GetSeasonLeaderboard(season.ID, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || len(leaderboard) != 3 || leaderboard[0].PlayerID != alice.ID {
+		t.Fatalf("got %d ranked players, want Alice leading 3", total)
+	}
+	if season, err = EndSeason(season.ID); err != nil {
+		t.Fatal(err)
+	}
+	if season.Status != models.SeasonEnded || season.ChampionID == nil || *season.ChampionID != alice.ID {
+		t.Errorf("got status %s and champion %v, want ended and won by Alice", season.Status, season.ChampionID)
+	}
+	if _, err := EndSeason(season.ID); !isValidationError(err) {
+		t.Errorf("ending twice: got %v, want a validation error", err)
+	}
+
+	after := recordTestMatch(t, alice, bob, 0, 2)
+	if after.SeasonID != nil {
+		t.Errorf("got season %d for a match after the season ended", *after.SeasonID)
+	}
+	if row := seasonRating(t, season.ID, alice); row.TotalMatches != 1 {
+		t.Errorf("Alice: got %d season matches, want the one played during the season", row.TotalMatches)
+	}
+}
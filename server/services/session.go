@@ -0,0 +1,218 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultAccessTokenTTL is the lifetime of an access token when
+	// ACCESS_TOKEN_TTL is not set
+	DefaultAccessTokenTTL = 15 * time.Minute
+	// DefaultRefreshTokenTTL is the lifetime of a refresh token when
+	// REFRESH_TOKEN_TTL is not set
+	DefaultRefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for an unknown, expired or revoked refresh token
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused is returned when a refresh token that was already
+	// exchanged is presented again; its whole family is revoked
+	ErrRefreshTokenReused = errors.New("refresh token was already used, the session has been revoked")
+)
+
+// AccessTokenTTL returns the configured lifetime of access tokens
+func AccessTokenTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("ACCESS_TOKEN_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return DefaultAccessTokenTTL
+}
+
+// RefreshTokenTTL returns the configured lifetime of refresh tokens
+func RefreshTokenTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("REFRESH_TOKEN_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return DefaultRefreshTokenTTL
+}
+
+// randomToken returns n random bytes encoded for use in URLs and headers
+func randomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken returns the hex SHA-256 of a token as stored in the database
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueRefreshToken stores a new refresh token in a family and returns it
+func issueRefreshToken(db *gorm.DB, adminID uint, familyID string) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	record := models.RefreshToken{
+		AdminID:   adminID,
+		FamilyID:  familyID,
+		TokenHash: HashToken(token),
+		ExpiresAt: time.Now().Add(RefreshTokenTTL()),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// StartSession opens a new session for an admin and returns its family ID
+// and first refresh token
+func StartSession(adminID uint) (familyID, refreshToken string, err error) {
+	familyID, err = randomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err = issueRefreshToken(config.DB, adminID, familyID)
+	if err != nil {
+		return "", "", err
+	}
+	return familyID, refreshToken, nil
+}
+
+// RotateRefreshToken exchanges a refresh token for a new one in the same
+// family. Presenting a token that was already exchanged revokes the family,
+// as it means the token has leaked.
+func RotateRefreshToken(token string) (record *models.RefreshToken, refreshToken string, err error) {
+	var reused bool
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var current models.RefreshToken
+		err := tx.Where("token_hash = ?", HashToken(token)).First(&current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+		if current.RevokedAt != nil || !current.ExpiresAt.After(time.Now()) {
+			return ErrInvalidRefreshToken
+		}
+
+		// Only one exchange of a token can win, concurrent ones count as reuse
+		now := time.Now()
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND rotated_at IS NULL", current.ID).
+			Update("rotated_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Revoked outside this transaction so the revocation is kept
+			reused = true
+			return ErrRefreshTokenReused
+		}
+
+		refreshToken, err = issueRefreshToken(tx, current.AdminID, current.FamilyID)
+		if err != nil {
+			return err
+		}
+		current.RotatedAt = &now
+		record = &current
+		return nil
+	})
+	if reused {
+		if _, revokeErr := RevokeSession(config.DB, token); revokeErr != nil {
+			return nil, "", revokeErr
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return record, refreshToken, nil
+}
+
+// revokeFamilies marks every live refresh token matching the query as revoked
+func revokeFamilies(db *gorm.DB, query string, args ...interface{}) (int64, error) {
+	var families int64
+	if err := db.Model(&models.RefreshToken{}).
+		Where(query, args...).
+		Where("revoked_at IS NULL AND rotated_at IS NULL").
+		Distinct("family_id").
+		Count(&families).Error; err != nil {
+		return 0, err
+	}
+	err := db.Model(&models.RefreshToken{}).
+		Where(query, args...).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now()).Error
+	return families, err
+}
+
+// RevokeSession ends the session a refresh token belongs to and returns the
+// number of sessions ended, 0 for an unknown or already revoked token
+func RevokeSession(db *gorm.DB, token string) (int64, error) {
+	var record models.RefreshToken
+	err := db.Where("token_hash = ?", HashToken(token)).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return revokeFamilies(db, "family_id = ?", record.FamilyID)
+}
+
+// RevokeAdminSessions ends every session of an admin and returns how many were active
+func RevokeAdminSessions(db *gorm.DB, adminID uint) (int64, error) {
+	return revokeFamilies(db, "admin_id = ?", adminID)
+}
+
+// SessionActive reports whether a session family has not been revoked. Access
+// tokens are only honoured while their session is active.
+func SessionActive(familyID string) (bool, error) {
+	var count int64
+	err := config.DB.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CleanupRefreshTokens deletes every expired refresh token
+func CleanupRefreshTokens(db *gorm.DB) (int64, error) {
+	result := db.Where("expires_at <= ?", time.Now()).Delete(&models.RefreshToken{})
+	return result.RowsAffected, result.Error
+}
+
+// StartRefreshTokenCleanup deletes expired refresh tokens every interval in the background
+func StartRefreshTokenCleanup(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			deleted, err := CleanupRefreshTokens(config.DB)
+			if err != nil {
+				log.Printf("Failed to clean up refresh tokens: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("Cleaned up %d expired refresh tokens", deleted)
+			}
+		}
+	}()
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+)
+
+// sessionActive fails the test on a database error
+func sessionActive(t *testing.T, familyID string) bool {
+	t.Helper()
+	active, err := SessionActive(familyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return active
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	setupTestDB(t)
+
+	family, first, err := StartSession(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, second, err := RotateRefreshToken(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.FamilyID != family || record.AdminID != 1 || record.RotatedAt == nil || second == first {
+		t.Errorf("got %+v and the same token %v, want a new token in family %s", record, second == first, family)
+	}
+	_, third, err := RotateRefreshToken(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := RotateRefreshToken("unknown"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("unknown token: got %v, want ErrInvalidRefreshToken", err)
+	}
+
+	// Another session of the same admin is not affected by the reuse below
+	otherFamily, _, err := StartSession(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Presenting an exchanged token again revokes its whole family
+	if _, _, err := RotateRefreshToken(first); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reused token: got %v, want ErrRefreshTokenReused", err)
+	}
+	if sessionActive(t, family) {
+		t.Error("the session is still active after a refresh token was reused")
+	}
+	if _, _, err := RotateRefreshToken(third); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("latest token of a revoked family: got %v, want ErrInvalidRefreshToken", err)
+	}
+	if !sessionActive(t, otherFamily) {
+		t.Error("another session was revoked")
+	}
+}
+
+func TestRefreshTokenExpiryAndRevocation(t *testing.T) {
+	setupTestDB(t)
+
+	family, token, err := StartSession(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.DB.Model(&models.RefreshToken{}).Where("family_id = ?", family).
+		Update("expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := RotateRefreshToken(token); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expired token: got %v, want ErrInvalidRefreshToken", err)
+	}
+	if deleted, err := CleanupRefreshTokens(config.DB); err != nil || deleted != 1 {
+		t.Errorf("cleanup: got %d, %v, want the expired token deleted", deleted, err)
+	}
+
+	// Logging out ends one session, revoking an admin ends all of them
+	first, token, err := StartSession(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := StartSession(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := StartSession(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ended, err := RevokeSession(config.DB, token); err != nil || ended != 1 {
+		t.Errorf("logout: got %d, %v, want one session ended", ended, err)
+	}
+	if sessionActive(t, first) || !sessionActive(t, second) {
+		t.Error("logout did not end exactly its own session")
+	}
+	if ended, err := RevokeAdminSessions(config.DB, 1); err != nil || ended != 1 {
+		t.Errorf("revoking the admin: got %d, %v, want the remaining session ended", ended, err)
+	}
+	if sessionActive(t, second) || !sessionActive(t, other) {
+		t.Error("revoking an admin's sessions did not end exactly theirs")
+	}
+}
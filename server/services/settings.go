@@ -0,0 +1,42 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetSetting returns the value of a setting, "" when it has never been set
+func GetSetting(key string) (string, error) {
+	var setting models.Setting
+	err := config.DB.Where(&models.Setting{Key: key}).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return setting.Value, nil
+}
+
+// SetSetting stores the value of a setting
+func SetSetting(key, value string) error {
+	return config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&models.Setting{Key: key, Value: value}).Error
+}
+
+// TwoFactorRequired reports whether every admin must use two-factor authentication
+func TwoFactorRequired() (bool, error) {
+	value, err := GetSetting(models.SettingRequireTwoFactor)
+	if err != nil || value == "" {
+		return false, err
+	}
+	return strconv.ParseBool(value)
+}
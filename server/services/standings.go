@@ -0,0 +1,212 @@
+package services
+
+import (
+	"sort"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+// StandingEntry is a single row of a tournament standings table
+type StandingEntry struct {
+	Rank            int     `json:"rank"`
+	PlayerID        uint    `json:"player_id"`
+	PlayerName      string  `json:"player_name"`
+	Seed            int     `json:"seed"`
+	Played          int     `json:"played"`
+	Won             int     `json:"won"`
+	Drawn           int     `json:"drawn"`
+	Lost            int     `json:"lost"`
+	Byes            int     `json:"byes"`
+	Points          float64 `json:"points"`
+	ScoreFor        int     `json:"score_for"`
+	ScoreAgainst    int     `json:"score_against"`
+	ScoreDifference int     `json:"score_difference"`
+	HeadToHead      float64 `json:"head_to_head,omitempty"`
+	Buchholz        float64 `json:"buchholz,omitempty"`
+	SonnebornBerger float64 `json:"sonneborn_berger,omitempty"`
+}
+
+// tournamentResults is the played state of a tournament used to build standings
+type tournamentResults struct {
+	entries   map[uint]*StandingEntry
+	order     []uint
+	completed []models.TournamentMatch
+	// opponents lists every real opponent a player has faced (byes excluded)
+	opponents map[uint][]uint
+}
+
+// loadTournamentResults collects participants and completed matches of a tournament
+func loadTournamentResults(db *gorm.DB, tournamentID uint) (*tournamentResults, error) {
+	var participants []models.TournamentParticipant
+	if err := db.Preload("Player").
+		Where("tournament_id = ?", tournamentID).
+		Order("seed ASC, id ASC").
+		Find(&participants).Error; err != nil {
+		return nil, err
+	}
+
+	var matches []models.TournamentMatch
+	if err := db.Preload("Match").
+		Where("tournament_id = ? AND completed_at IS NOT NULL", tournamentID).
+		Order("round ASC, position ASC").
+		Find(&matches).Error; err != nil {
+		return nil, err
+	}
+
+	results := &tournamentResults{
+		entries:   make(map[uint]*StandingEntry, len(participants)),
+		completed: matches,
+		opponents: make(map[uint][]uint),
+	}
+	for _, p := range participants {
+		results.entries[p.PlayerID] = &StandingEntry{
+			PlayerID:   p.PlayerID,
+			PlayerName: p.Player.Name,
+			Seed:       p.Seed,
+		}
+		results.order = append(results.order, p.PlayerID)
+	}
+
+	for _, m := range matches {
+		if m.IsBye {
+			// A bye counts as a win without an opponent
+			if m.WinnerID != nil && results.entries[*m.WinnerID] != nil {
+				entry := results.entries[*m.WinnerID]
+				entry.Byes++
+				entry.Points++
+			}
+			continue
+		}
+		if m.Player1ID == nil || m.Player2ID == nil {
+			continue
+		}
+
+		p1, p2 := results.entries[*m.Player1ID], results.entries[*m.Player2ID]
+		if p1 == nil || p2 == nil {
+			continue
+		}
+		p1.Played++
+		p2.Played++
+		results.opponents[p1.PlayerID] = append(results.opponents[p1.PlayerID], p2.PlayerID)
+		results.opponents[p2.PlayerID] = append(results.opponents[p2.PlayerID], p1.PlayerID)
+
+		if m.Match != nil {
+			p1.ScoreFor += m.Match.Player1Score
+			p1.ScoreAgainst += m.Match.Player2Score
+			p2.ScoreFor += m.Match.Player2Score
+			p2.ScoreAgainst += m.Match.Player1Score
+			p1.ScoreDifference = p1.ScoreFor - p1.ScoreAgainst
+			p2.ScoreDifference = p2.ScoreFor - p2.ScoreAgainst
+		}
+
+		switch {
+		case m.WinnerID == nil:
+			p1.Drawn++
+			p2.Drawn++
+			p1.Points += 0.5
+			p2.Points += 0.5
+		case *m.WinnerID == p1.PlayerID:
+			p1.Won++
+			p2.Lost++
+			p1.Points++
+		default:
+			p2.Won++
+			p1.Lost++
+			p2.Points++
+		}
+	}
+
+	return results, nil
+}
+
+// GetTournamentStandings returns the standings table of a tournament, ranked
+// with the tiebreaks of its format
+func GetTournamentStandings(tournamentID uint) ([]StandingEntry, error) {
+	var tournament models.Tournament
+	if err := config.DB.First(&tournament, tournamentID).Error; err != nil {
+		return nil, err
+	}
+	return tournamentStandings(config.DB, &tournament)
+}
+
+func tournamentStandings(db *gorm.DB, tournament *models.Tournament) ([]StandingEntry, error) {
+	results, err := loadTournamentResults(db, tournament.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch tournament.Format {
+	case models.FormatSwiss:
+		return swissStandings(results), nil
+	case models.FormatRoundRobin:
+		return roundRobinStandings(results), nil
+	case models.FormatDoubleElimination:
+		return eliminationStandings(results, tournament.WinnerID), nil
+	}
+	return plainStandings(results), nil
+}
+
+// eliminationStandings puts the tournament winner first, then ranks by points
+// and fewest losses. In double elimination the runner-up can have more wins
+// than the winner after coming through the losers' bracket.
+func eliminationStandings(results *tournamentResults, winnerID *uint) []StandingEntry {
+	standings := make([]StandingEntry, 0, len(results.order))
+	for _, id := range results.order {
+		standings = append(standings, *results.entries[id])
+	}
+	sortStandings(standings, func(a, b *StandingEntry) int {
+		if winnerID != nil && a.PlayerID != b.PlayerID {
+			if a.PlayerID == *winnerID {
+				return 1
+			}
+			if b.PlayerID == *winnerID {
+				return -1
+			}
+		}
+		if c := compareFloat(a.Points, b.Points); c != 0 {
+			return c
+		}
+		return compareFloat(float64(b.Lost), float64(a.Lost))
+	})
+	return standings
+}
+
+// plainStandings ranks by points only, keeping seed order for ties
+func plainStandings(results *tournamentResults) []StandingEntry {
+	standings := make([]StandingEntry, 0, len(results.order))
+	for _, id := range results.order {
+		standings = append(standings, *results.entries[id])
+	}
+	sortStandings(standings, func(a, b *StandingEntry) int {
+		return compareFloat(a.Points, b.Points)
+	})
+	return standings
+}
+
+// sortStandings orders standings with compare (positive when a ranks above b)
+// and falls back to seed order, then assigns ranks
+func sortStandings(standings []StandingEntry, compare func(a, b *StandingEntry) int) {
+	sort.SliceStable(standings, func(i, j int) bool {
+		if c := compare(&standings[i], &standings[j]); c != 0 {
+			return c > 0
+		}
+		return standings[i].Seed < standings[j].Seed
+	})
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+}
+
+// compareFloat returns 1 when a > b, -1 when a < b and 0 when they are equal
+func compareFloat(a, b float64) int {
+	switch {
+	case a > b:
+		return 1
+	case a < b:
+		return -1
+	}
+	return 0
+}
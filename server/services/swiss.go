@@ -0,0 +1,260 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+// defaultSwissRounds is enough rounds to separate a single undefeated player
+func defaultSwissRounds(players int) int {
+	rounds := 0
+	for size := 1; size < players; size *= 2 {
+		rounds++
+	}
+	return rounds
+}
+
+// swissStandings ranks by points, then Buchholz (sum of opponents' points),
+// then Sonneborn-Berger (points of beaten opponents plus half of drawn ones)
+func swissStandings(results *tournamentResults) []StandingEntry {
+	for id, entry := range results.entries {
+		entry.Buchholz = 0
+		for _, opponent := range results.opponents[id] {
+			entry.Buchholz += results.entries[opponent].Points
+		}
+	}
+
+	for _, m := range results.completed {
+		if m.IsBye || m.Player1ID == nil || m.Player2ID == nil {
+			continue
+		}
+		p1, p2 := results.entries[*m.Player1ID], results.entries[*m.Player2ID]
+		if p1 == nil || p2 == nil {
+			continue
+		}
+		switch {
+		case m.WinnerID == nil:
+			p1.SonnebornBerger += p2.Points / 2
+			p2.SonnebornBerger += p1.Points / 2
+		case *m.WinnerID == p1.PlayerID:
+			p1.SonnebornBerger += p2.Points
+		default:
+			p2.SonnebornBerger += p1.Points
+		}
+	}
+
+	standings := make([]StandingEntry, 0, len(results.order))
+	for _, id := range results.order {
+		standings = append(standings, *results.entries[id])
+	}
+	sortStandings(standings, func(a, b *StandingEntry) int {
+		if c := compareFloat(a.Points, b.Points); c != 0 {
+			return c
+		}
+		if c := compareFloat(a.Buchholz, b.Buchholz); c != 0 {
+			return c
+		}
+		return compareFloat(a.SonnebornBerger, b.SonnebornBerger)
+	})
+	return standings
+}
+
+// swissPairing is a proposed pairing of two players (player2 is 0 for a bye)
+type swissPairing struct {
+	player1 uint
+	player2 uint
+}
+
+// swissSearchLimit bounds the pairings tried while avoiding rematches. The
+// search backtracks and can take exponential time in a large field with many
+// rematches to avoid, all while holding the write lock, so past the limit the
+// round is paired allowing rematches instead.
+const swissSearchLimit = 10000
+
+// pairSwissPlayers pairs a ranked field. Players are paired within their score
+// group top half against bottom half (Dutch system), floating down to the next
+// group when needed, and rematches are avoided unless no other pairing is
+// found.
+func pairSwissPlayers(ranked []StandingEntry, played map[uint]map[uint]bool, hadBye map[uint]bool) []swissPairing {
+	players := append([]StandingEntry(nil), ranked...)
+	var pairings []swissPairing
+
+	// The lowest ranked player without a bye sits out an odd round
+	if len(players)%2 == 1 {
+		byeIndex := len(players) - 1
+		for i := len(players) - 1; i >= 0; i-- {
+			if !hadBye[players[i].PlayerID] {
+				byeIndex = i
+				break
+			}
+		}
+		pairings = append(pairings, swissPairing{player1: players[byeIndex].PlayerID})
+		players = append(players[:byeIndex], players[byeIndex+1:]...)
+	}
+
+	budget := swissSearchLimit
+	if result, ok := pairSwissRecursive(players, played, false, &budget); ok {
+		return append(pairings, result...)
+	}
+	result, _ := pairSwissRecursive(players, played, true, nil)
+	return append(pairings, result...)
+}
+
+// pairSwissRecursive pairs the first remaining player with its preferred
+// opponent and backtracks when the rest of the field cannot be paired. Each
+// pairing tried takes one from budget, and the search fails once it runs
+// out. When rematches are allowed every opponent pairs, so it takes the
+// preferred one that is not a rematch, or else the preferred rematch, without
+// backtracking.
+func pairSwissRecursive(players []StandingEntry, played map[uint]map[uint]bool, allowRematch bool, budget *int) ([]swissPairing, bool) {
+	if len(players) == 0 {
+		return nil, true
+	}
+
+	top := players[0]
+	rest := players[1:]
+
+	candidates := swissCandidates(top, rest)
+	if allowRematch {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return !played[top.PlayerID][rest[candidates[i]].PlayerID] && played[top.PlayerID][rest[candidates[j]].PlayerID]
+		})
+	}
+
+	for _, candidate := range candidates {
+		opponent := rest[candidate]
+		if !allowRematch && played[top.PlayerID][opponent.PlayerID] {
+			continue
+		}
+		if budget != nil {
+			if *budget == 0 {
+				return nil, false
+			}
+			*budget--
+		}
+
+		remaining := make([]StandingEntry, 0, len(rest)-1)
+		remaining = append(remaining, rest[:candidate]...)
+		remaining = append(remaining, rest[candidate+1:]...)
+
+		if result, ok := pairSwissRecursive(remaining, played, allowRematch, budget); ok {
+			return append([]swissPairing{{player1: top.PlayerID, player2: opponent.PlayerID}}, result...), true
+		}
+	}
+
+	return nil, false
+}
+
+// swissCandidates returns the indexes of rest in order of preference as
+// opponents for top: the middle of its own score group first, then the rest
+// of the group, then lower groups
+func swissCandidates(top StandingEntry, rest []StandingEntry) []int {
+	groupSize := 0
+	for groupSize < len(rest) && rest[groupSize].Points == top.Points {
+		groupSize++
+	}
+
+	candidates := make([]int, 0, len(rest))
+	ideal := groupSize / 2
+	for i := ideal; i < groupSize; i++ {
+		candidates = append(candidates, i)
+	}
+	for i := ideal - 1; i >= 0; i-- {
+		candidates = append(candidates, i)
+	}
+	for i := groupSize; i < len(rest); i++ {
+		candidates = append(candidates, i)
+	}
+	return candidates
+}
+
+// pairSwissRound creates the matches of the given Swiss round from the current standings
+func pairSwissRound(tx *gorm.DB, tournament *models.Tournament, round int) error {
+	results, err := loadTournamentResults(tx, tournament.ID)
+	if err != nil {
+		return err
+	}
+	ranked := swissStandings(results)
+
+	played := make(map[uint]map[uint]bool)
+	hadBye := make(map[uint]bool)
+	for _, m := range results.completed {
+		if m.IsBye {
+			if m.WinnerID != nil {
+				hadBye[*m.WinnerID] = true
+			}
+			continue
+		}
+		if m.Player1ID == nil || m.Player2ID == nil {
+			continue
+		}
+		p1, p2 := *m.Player1ID, *m.Player2ID
+		if played[p1] == nil {
+			played[p1] = make(map[uint]bool)
+		}
+		if played[p2] == nil {
+			played[p2] = make(map[uint]bool)
+		}
+		played[p1][p2] = true
+		played[p2][p1] = true
+	}
+
+	pairings := pairSwissPlayers(ranked, played, hadBye)
+
+	// Real pairings take the first boards and the bye the last one
+	sort.SliceStable(pairings, func(i, j int) bool {
+		return pairings[i].player2 != 0 && pairings[j].player2 == 0
+	})
+
+	now := time.Now()
+	for i, pairing := range pairings {
+		player1 := pairing.player1
+		slot := models.TournamentMatch{
+			TournamentID: tournament.ID,
+			Round:        round,
+			Position:     i + 1,
+			Player1ID:    &player1,
+		}
+		if pairing.player2 == 0 {
+			slot.IsBye = true
+			slot.WinnerID = &player1
+			slot.CompletedAt = &now
+		} else {
+			player2 := pairing.player2
+			slot.Player2ID = &player2
+		}
+		if err := tx.Create(&slot).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// progressSwiss pairs the next round once every match of the current round
+// has a result, or completes the tournament after the last round
+func progressSwiss(tx *gorm.DB, tournament *models.Tournament, round int) error {
+	var pending int64
+	if err := tx.Model(&models.TournamentMatch{}).
+		Where("tournament_id = ? AND round = ? AND completed_at IS NULL", tournament.ID, round).
+		Count(&pending).Error; err != nil {
+		return err
+	}
+	if pending > 0 {
+		return nil
+	}
+
+	if round < tournament.TotalRounds {
+		return pairSwissRound(tx, tournament, round+1)
+	}
+
+	standings, err := tournamentStandings(tx, tournament)
+	if err != nil {
+		return err
+	}
+	return completeTournament(tx, tournament, standings[0].PlayerID)
+}
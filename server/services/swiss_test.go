@@ -0,0 +1,119 @@
+package services
+
+import (
+	"testing"
+)
+
+// swissField ranks players 1 to n with the given points
+func swissField(points ...float64) []StandingEntry {
+	ranked := make([]StandingEntry, len(points))
+	for i, p := range points {
+		ranked[i] = StandingEntry{PlayerID: uint(i + 1), Points: p}
+	}
+	return ranked
+}
+
+// swissPlayed records the pairs of players who already met
+func swissPlayed(pairs ...[2]uint) map[uint]map[uint]bool {
+	played := make(map[uint]map[uint]bool)
+	for _, pair := range pairs {
+		for _, p := range [][2]uint{pair, {pair[1], pair[0]}} {
+			if played[p[0]] == nil {
+				played[p[0]] = make(map[uint]bool)
+			}
+			played[p[0]][p[1]] = true
+		}
+	}
+	return played
+}
+
+// checkSwissPairings fails unless every player of the field is paired exactly
+// once, and returns the number of rematches
+func checkSwissPairings(t *testing.T, field int, pairings []swissPairing, played map[uint]map[uint]bool) int {
+	t.Helper()
+	seen := make(map[uint]bool)
+	rematches := 0
+	for _, pairing := range pairings {
+		for _, id := range []uint{pairing.player1, pairing.player2} {
+			if id == 0 {
+				continue
+			}
+			if seen[id] {
+				t.Errorf("player %d is paired twice in %v", id, pairings)
+			}
+			seen[id] = true
+		}
+		if played[pairing.player1][pairing.player2] {
+			rematches++
+		}
+	}
+	if len(seen) != field {
+		t.Errorf("paired %d of %d players: %v", len(seen), field, pairings)
+	}
+	return rematches
+}
+
+func TestPairSwissPlayersAvoidsRematches(t *testing.T) {
+	ranked := swissField(1, 1, 1, 1)
+
+	got := pairSwissPlayers(ranked, swissPlayed(), nil)
+	want := []swissPairing{{1, 3}, {2, 4}}
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("fresh group: got %v, want %v", got, want)
+	}
+
+	played := swissPlayed([2]uint{1, 3}, [2]uint{2, 4})
+	got = pairSwissPlayers(ranked, played, nil)
+	if rematches := checkSwissPairings(t, 4, got, played); rematches != 0 {
+		t.Errorf("got %d rematches in %v, want none", rematches, got)
+	}
+	if got[0] != (swissPairing{1, 4}) {
+		t.Errorf("got %v first, want 1 against 4", got[0])
+	}
+}
+
+func TestPairSwissPlayersGivesByeToLowestWithoutOne(t *testing.T) {
+	ranked := swissField(2, 2, 1, 1, 0)
+
+	got := pairSwissPlayers(ranked, swissPlayed(), map[uint]bool{5: true})
+	checkSwissPairings(t, 5, got, nil)
+	if got[0] != (swissPairing{player1: 4}) {
+		t.Errorf("got %v, want the bye for player 4", got)
+	}
+	byes := 0
+	for _, pairing := range got {
+		if pairing.player2 == 0 {
+			byes++
+		}
+	}
+	if byes != 1 {
+		t.Errorf("got %d byes, want 1", byes)
+	}
+}
+
+func TestPairSwissPlayersForcesRematch(t *testing.T) {
+	ranked := swissField(3, 1, 1, 1)
+	played := swissPlayed([2]uint{1, 2}, [2]uint{1, 3}, [2]uint{1, 4})
+
+	got := pairSwissPlayers(ranked, played, nil)
+	if rematches := checkSwissPairings(t, 4, got, played); rematches != 1 {
+		t.Errorf("got %d rematches in %v, want only player 1's", rematches, got)
+	}
+}
+
+func TestPairSwissPlayersBoundsSearch(t *testing.T) {
+	// The last player has met everyone, so the search without rematches
+	// would try every pairing of the others before giving up
+	const field = 40
+	ranked := swissField(make([]float64, field)...)
+	var pairs [][2]uint
+	for id := uint(1); id < field; id++ {
+		pairs = append(pairs, [2]uint{id, field})
+	}
+	played := swissPlayed(pairs...)
+
+	got := pairSwissPlayers(ranked, played, nil)
+	if rematches := checkSwissPairings(t, field, got, played); rematches != 1 {
+		t.Errorf("got %d rematches, want only player %d's", rematches, field)
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+// ThrowShare is how often a single throw was used
+type ThrowShare struct {
+	Count      int     `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
+
+// ThrowDistribution is the stone/paper/scissor split over a set of rounds
+type ThrowDistribution struct {
+	Total   int        `json:"total"`
+	Stone   ThrowShare `json:"stone"`
+	Paper   ThrowShare `json:"paper"`
+	Scissor ThrowShare `json:"scissor"`
+}
+
+// ThrowSequence is a run of consecutive throws within a single match
+type ThrowSequence struct {
+	Sequence   string  `json:"sequence"`
+	Count      int     `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
+
+// PlayerTendencies is the scouting report of a player's throwing habits
+type PlayerTendencies struct {
+	PlayerID        uint              `json:"player_id"`
+	PlayerName      string            `json:"player_name"`
+	MatchesAnalyzed int               `json:"matches_analyzed"`
+	RoundsAnalyzed  int               `json:"rounds_analyzed"`
+	Distribution    ThrowDistribution `json:"distribution"`
+	AfterWin        ThrowDistribution `json:"after_win"`
+	AfterLoss       ThrowDistribution `json:"after_loss"`
+	AfterDraw       ThrowDistribution `json:"after_draw"`
+	RepeatRate      float64           `json:"repeat_rate"`
+	SwitchRate      float64           `json:"switch_rate"`
+	CommonPairs     []ThrowSequence   `json:"common_2_sequences"`
+	CommonTriples   []ThrowSequence   `json:"common_3_sequences"`
+}
+
+// add counts a single throw
+func (d *ThrowDistribution) add(t models.Throw) {
+	d.Total++
+	switch t {
+	case models.ThrowStone:
+		d.Stone.Count++
+	case models.ThrowPaper:
+		d.Paper.Count++
+	case models.ThrowScissor:
+		d.Scissor.Count++
+	}
+}
+
+// finish fills in the percentages once every throw is counted
+func (d *ThrowDistribution) finish() {
+	d.Stone.Percentage = percentage(d.Stone.Count, d.Total)
+	d.Paper.Percentage = percentage(d.Paper.Count, d.Total)
+	d.Scissor.Percentage = percentage(d.Scissor.Count, d.Total)
+}
+
+func percentage(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) / float64(total) * 100
+}
+
+// topSequences returns the limit most common sequences, most frequent first
+func topSequences(counts map[string]int, total, limit int) []ThrowSequence {
+	sequences := make([]ThrowSequence, 0, len(counts))
+	for sequence, count := range counts {
+		sequences = append(sequences, ThrowSequence{
+			Sequence:   sequence,
+			Count:      count,
+			Percentage: percentage(count, total),
+		})
+	}
+
+	sort.Slice(sequences, func(i, j int) bool {
+		if sequences[i].Count != sequences[j].Count {
+			return sequences[i].Count > sequences[j].Count
+		}
+		return sequences[i].Sequence < sequences[j].Sequence
+	})
+
+	if limit > 0 && len(sequences) > limit {
+		sequences = sequences[:limit]
+	}
+	return sequences
+}
+
+// GetPlayerTendencies analyses every recorded round of a player's matches.
+// Sequences and conditional distributions only look at consecutive rounds
+// within the same match, never across matches.
+func GetPlayerTendencies(player *models.Player, limit int) (*PlayerTendencies, error) {
+	var matches []models.Match
+	err := PlayerMatchesQuery(player.ID).
+		Preload("Rounds", func(db *gorm.DB) *gorm.DB {
+			return db.Order("round_number ASC")
+		}).
+		Order("played_at ASC, id ASC").
+		Find(&matches).Error
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PlayerTendencies{
+		PlayerID:   player.ID,
+		PlayerName: player.Name,
+	}
+
+	pairs := make(map[string]int)
+	triples := make(map[string]int)
+	pairTotal, tripleTotal := 0, 0
+	repeats, switches := 0, 0
+
+	for _, match := range matches {
+		if len(match.Rounds) == 0 {
+			continue
+		}
+		report.MatchesAnalyzed++
+
+		isPlayer1 := match.Player1ID == player.ID
+		throws := make([]models.Throw, 0, len(match.Rounds))
+
+		for i, round := range match.Rounds {
+			throw := round.Player2Throw
+			if isPlayer1 {
+				throw = round.Player1Throw
+			}
+			throws = append(throws, throw)
+
+			report.RoundsAnalyzed++
+			report.Distribution.add(throw)
+
+			if i == 0 {
+				continue
+			}
+
+			// Condition on how the previous round went for this player
+			previous := match.Rounds[i-1].Outcome
+			switch {
+			case previous == models.RoundDraw:
+				report.AfterDraw.add(throw)
+			case (previous == models.RoundPlayer1) == isPlayer1:
+				report.AfterWin.add(throw)
+			default:
+				report.AfterLoss.add(throw)
+			}
+
+			if throw == throws[i-1] {
+				repeats++
+			} else {
+				switches++
+			}
+
+			pairs[sequenceKey(throws[i-1:i+1])]++
+			pairTotal++
+			if i >= 2 {
+				triples[sequenceKey(throws[i-2:i+1])]++
+				tripleTotal++
+			}
+		}
+	}
+
+	report.Distribution.finish()
+	report.AfterWin.finish()
+	report.AfterLoss.finish()
+	report.AfterDraw.finish()
+	report.RepeatRate = percentage(repeats, repeats+switches)
+	report.SwitchRate = percentage(switches, repeats+switches)
+	report.CommonPairs = topSequences(pairs, pairTotal, limit)
+	report.CommonTriples = topSequences(triples, tripleTotal, limit)
+
+	return report, nil
+}
+
+func sequenceKey(throws []models.Throw) string {
+	parts := make([]string, len(throws))
+	for i, t := range throws {
+		parts[i] = string(t)
+	}
+	return strings.Join(parts, "-")
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"stone-paper-scissors/models"
+)
+
+// testRounds builds the rounds of a match from the throws of both players
+func testRounds(t *testing.T, player1, player2 []models.Throw) []models.MatchRound {
+	t.Helper()
+	requests := make([]models.RoundRequest, len(player1))
+	score1, score2 := 0, 0
+	for i := range player1 {
+		requests[i] = models.RoundRequest{Player1Throw: player1[i], Player2Throw: player2[i]}
+		if player1[i].Beats(player2[i]) {
+			score1++
+		} else if player2[i].Beats(player1[i]) {
+			score2++
+		}
+	}
+	rounds, err := BuildRounds(requests, score1, score2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rounds
+}
+
+func TestGetPlayerTendencies(t *testing.T) {
+	setupTestDB(t)
+
+	alice := &models.Player{Name: "Alice", Elo: 1000}
+	bob := &models.Player{Name: "Bob", Elo: 1000}
+	create(t, alice, bob)
+
+	const (
+		stone   = models.ThrowStone
+		paper   = models.ThrowPaper
+		scissor = models.ThrowScissor
+	)
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	first := testMatch(alice, bob, 1, 1, start, nil)
+	first.Rounds = testRounds(t, []models.Throw{stone, stone, paper}, []models.Throw{scissor, paper, paper})
+	// Alice is player 2 here: she wins the first round and loses the second
+	second := testMatch(bob, alice, 1, 1, start.Add(time.Hour), nil)
+	second.Rounds = testRounds(t, []models.Throw{stone, scissor}, []models.Throw{paper, paper})
+	// Matches without rounds are left out
+	create(t, first, second, testMatch(alice, bob, 2, 0, start.Add(2*time.Hour), nil))
+
+	report, err := GetPlayerTendencies(alice, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.MatchesAnalyzed != 2 || report.RoundsAnalyzed != 5 {
+		t.Errorf("got %d matches and %d rounds, want 2 and 5", report.MatchesAnalyzed, report.RoundsAnalyzed)
+	}
+
+	check := func(name string, got ThrowDistribution, total, stones, papers, scissors int) {
+		t.Helper()
+		if got.Total != total || got.Stone.Count != stones || got.Paper.Count != papers || got.Scissor.Count != scissors {
+			t.Errorf("%s: got %+v, want %d throws: %d stone, %d paper, %d scissor", name, got, total, stones, papers, scissors)
+		}
+	}
+	check("distribution", report.Distribution, 5, 2, 3, 0)
+	check("after a win", report.AfterWin, 2, 1, 1, 0)
+	check("after a loss", report.AfterLoss, 1, 0, 1, 0)
+	check("after a draw", report.AfterDraw, 0, 0, 0, 0)
+	if report.Distribution.Paper.Percentage != 60 {
+		t.Errorf("got paper %v%%, want 60%%", report.Distribution.Paper.Percentage)
+	}
+
+	if math.Abs(report.RepeatRate-200.0/3) > 1e-9 || math.Abs(report.SwitchRate-100.0/3) > 1e-9 {
+		t.Errorf("got repeat rate %v, switch rate %v, want 66.7 and 33.3", report.RepeatRate, report.SwitchRate)
+	}
+
+	// Sequences never span two matches
+	wantPairs := []string{"paper-paper", "stone-paper", "stone-stone"}
+	if len(report.CommonPairs) != len(wantPairs) {
+		t.Fatalf("got pairs %+v, want %v", report.CommonPairs, wantPairs)
+	}
+	for i, sequence := range wantPairs {
+		if got := report.CommonPairs[i]; got.Sequence != sequence || got.Count != 1 {
+			t.Errorf("pair %d: got %+v, want %s once", i, got, sequence)
+		}
+	}
+	if len(report.CommonTriples) != 1 || report.CommonTriples[0].Sequence != "stone-stone-paper" || report.CommonTriples[0].Percentage != 100 {
+		t.Errorf("got triples %+v, want stone-stone-paper alone", report.CommonTriples)
+	}
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// DefaultLoginLockout is how long logins are locked out when
+	// LOGIN_LOCKOUT_DURATION is not set
+	DefaultLoginLockout = 15 * time.Minute
+	// loginBackoffBase is the delay after the first failure past the free ones;
+	// it doubles with every further failure
+	loginBackoffBase = time.Second
+	// loginFailureWindow is how long a failure is remembered when no other follows
+	loginFailureWindow = 24 * time.Hour
+)
+
+// throttlePolicy is how failed logins are slowed down for one kind of key
+type throttlePolicy struct {
+	free    int // failures before logins are delayed
+	lockout int // failures at which logins are locked out
+}
+
+// throttlePolicies allow more failures per IP address, which may be shared
+// by several admins, than per username
+var throttlePolicies = map[models.ThrottleKind]throttlePolicy{
+	models.ThrottleUsername: {free: 3, lockout: 10},
+	models.ThrottleIP:       {free: 10, lockout: 50},
+}
+
+// LoginLockoutDuration returns the configured length of a lockout
+func LoginLockoutDuration() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("LOGIN_LOCKOUT_DURATION")); err == nil && d > 0 {
+		return d
+	}
+	return DefaultLoginLockout
+}
+
+// loginDelay returns how long logins are refused after the given number of
+// consecutive failures
+func loginDelay(policy throttlePolicy, failures int) time.Duration {
+	lockout := LoginLockoutDuration()
+	if failures >= policy.lockout {
+		return lockout
+	}
+	if failures < policy.free {
+		return 0
+	}
+	delay := loginBackoffBase << uint(failures-policy.free)
+	if delay > lockout {
+		return lockout
+	}
+	return delay
+}
+
+// LoginThrottledError is returned while logins for a username or IP address
+// are refused
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool // locked out rather than slowed down
+}
+
+func (e *LoginThrottledError) Error() string {
+	wait := e.RetryAfter.Round(time.Second)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	if e.Locked {
+		return fmt.Sprintf("Too many failed login attempts, login is locked for %s", wait)
+	}
+	return fmt.Sprintf("Too many failed login attempts, try again in %s", wait)
+}
+
+// throttleKeys returns the keys a login attempt is counted by
+func throttleKeys(username, ip string) map[models.ThrottleKind]string {
+	return map[models.ThrottleKind]string{
+		models.ThrottleUsername: strings.ToLower(strings.TrimSpace(username)),
+		models.ThrottleIP:       ip,
+	}
+}
+
+// CheckLoginAllowed returns a LoginThrottledError while logins for the
+// username or the IP address are refused
+func CheckLoginAllowed(username, ip string) error {
+	now := time.Now()
+	var throttled *LoginThrottledError
+	for kind, key := range throttleKeys(username, ip) {
+		var row models.LoginThrottle
+		err := config.DB.Where("kind = ? AND throttle_key = ?", kind, key).Limit(1).Find(&row).Error
+		if err != nil {
+			return err
+		}
+		if row.ID == 0 || row.LockedUntil == nil || !row.LockedUntil.After(now) {
+			continue
+		}
+		wait := row.LockedUntil.Sub(now)
+		if throttled == nil || wait > throttled.RetryAfter {
+			throttled = &LoginThrottledError{
+				RetryAfter: wait,
+				Locked:     row.Failures >= throttlePolicies[kind].lockout,
+			}
+		}
+	}
+	if throttled != nil {
+		return throttled
+	}
+	return nil
+}
+
+// RecordLoginFailure counts a failed login against the username and the IP
+// address and stores an audit event. adminID is the admin the username
+// belongs to, nil for an unknown username.
+func RecordLoginFailure(username, ip string, adminID *uint, reason string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for kind, key := range throttleKeys(username, ip) {
+			policy := throttlePolicies[kind]
+			row := models.LoginThrottle{Kind: kind, Key: key}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+				return err
+			}
+			if err := forUpdate(tx).Where("kind = ? AND throttle_key = ?", kind, key).First(&row).Error; err != nil {
+				return err
+			}
+
+			if now.Sub(row.LastFailureAt) > loginFailureWindow {
+				row.Failures = 0
+			}
+			row.Failures++
+			row.LastFailureAt = now
+			row.LockedUntil = nil
+			if delay := loginDelay(policy, row.Failures); delay > 0 {
+				lockedUntil := now.Add(delay)
+				row.LockedUntil = &lockedUntil
+			}
+			if err := tx.Save(&row).Error; err != nil {
+				return err
+			}
+
+			if row.Failures == policy.lockout {
+				lockedAdminID := adminID
+				if kind != models.ThrottleUsername {
+					lockedAdminID = nil
+				}
+				if err := RecordAudit(tx, models.AuditEvent{
+					Type:     models.AuditLoginLocked,
+					AdminID:  lockedAdminID,
+					Username: username,
+					IP:       ip,
+					Detail:   fmt.Sprintf("%s %s locked until %s after %d failed logins", kind, key, row.LockedUntil.UTC().Format(time.RFC3339), row.Failures),
+				}); err != nil {
+					return err
+				}
+			}
+		}
+
+		return RecordAudit(tx, models.AuditEvent{
+			Type:     models.AuditLoginFailed,
+			AdminID:  adminID,
+			Username: username,
+			IP:       ip,
+			Detail:   reason,
+		})
+	})
+}
+
+// RecordLoginSuccess forgets the failed logins of a username. Failures from
+// the IP address still count, so that one valid account cannot be used to
+// keep guessing others.
+func RecordLoginSuccess(username string) error {
+	return config.DB.
+		Where("kind = ? AND throttle_key = ?", models.ThrottleUsername, throttleKeys(username, "")[models.ThrottleUsername]).
+		Delete(&models.LoginThrottle{}).Error
+}
+
+// ListLockouts returns the usernames and IP addresses logins are currently
+// refused for, locked out first
+func ListLockouts() ([]models.LoginThrottle, error) {
+	var rows []models.LoginThrottle
+	err := config.DB.Where("locked_until > ?", time.Now()).
+		Order("failures DESC, locked_until DESC").
+		Find(&rows).Error
+	return rows, err
+}
+
+// UnlockLogin clears the failed logins of a lockout so that logins are
+// allowed again, and records who did it
+func UnlockLogin(id uint, actorID uint) (*models.LoginThrottle, error) {
+	var row models.LoginThrottle
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&row, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&row).Error; err != nil {
+			return err
+		}
+
+		event := models.AuditEvent{
+			Type:    models.AuditLoginUnlocked,
+			ActorID: &actorID,
+			Detail:  fmt.Sprintf("%s %s unlocked after %d failed logins", row.Kind, row.Key, row.Failures),
+		}
+		if row.Kind == models.ThrottleUsername {
+			event.Username = row.Key
+			var admin models.Admin
+			if err := tx.Where("LOWER(username) = ?", row.Key).Limit(1).Find(&admin).Error; err != nil {
+				return err
+			}
+			if admin.ID != 0 {
+				event.AdminID = &admin.ID
+			}
+		} else {
+			event.IP = row.Key
+		}
+		return RecordAudit(tx, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
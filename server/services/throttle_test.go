@@ -0,0 +1,152 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+)
+
+func TestLoginDelay(t *testing.T) {
+	policy := throttlePolicies[models.ThrottleUsername]
+	for failures, want := range map[int]time.Duration{
+		0:  0,
+		2:  0,
+		3:  time.Second,
+		4:  2 * time.Second,
+		9:  64 * time.Second,
+		10: DefaultLoginLockout,
+		25: DefaultLoginLockout,
+	} {
+		if got := loginDelay(policy, failures); got != want {
+			t.Errorf("%d failures: got %v, want %v", failures, got, want)
+		}
+	}
+
+	// The backoff never exceeds the lockout
+	t.Setenv("LOGIN_LOCKOUT_DURATION", "30s")
+	if got := loginDelay(policy, 9); got != 30*time.Second {
+		t.Errorf("got %v, want the 30s lockout", got)
+	}
+}
+
+// failLogins records failed logins for username, each from its own address
+func failLogins(t *testing.T, username string, n int, adminID *uint) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := RecordLoginFailure(username, fmt.Sprintf("10.0.0.%d", i), adminID, "wrong password"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// loginThrottled returns the error refusing a login, nil when it is allowed
+func loginThrottled(t *testing.T, username, ip string) *LoginThrottledError {
+	t.Helper()
+	err := CheckLoginAllowed(username, ip)
+	if err == nil {
+		return nil
+	}
+	var throttled *LoginThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatal(err)
+	}
+	return throttled
+}
+
+func TestLoginThrottleBacksOffAndLocks(t *testing.T) {
+	setupTestDB(t)
+
+	admin := &models.Admin{Username: "root", Email: "root@example.com", PasswordHash: "x", Role: models.RoleSuperAdmin}
+	create(t, admin)
+
+	failLogins(t, "root", 2, &admin.ID)
+	if throttled := loginThrottled(t, "root", "10.1.0.1"); throttled != nil {
+		t.Errorf("after 2 failures: got %v, want logins allowed", throttled)
+	}
+	// Usernames are counted case-insensitively
+	failLogins(t, " Root", 1, &admin.ID)
+	throttled := loginThrottled(t, "ROOT", "10.1.0.1")
+	if throttled == nil || throttled.Locked || throttled.RetryAfter > time.Second {
+		t.Fatalf("after 3 failures: got %v, want a delay of a second", throttled)
+	}
+
+	// A successful login forgets the failures of the username
+	if err := RecordLoginSuccess("root"); err != nil {
+		t.Fatal(err)
+	}
+	if throttled := loginThrottled(t, "root", "10.1.0.1"); throttled != nil {
+		t.Errorf("after a successful login: got %v, want logins allowed", throttled)
+	}
+
+	failLogins(t, "root", 10, &admin.ID)
+	throttled = loginThrottled(t, "root", "10.1.0.1")
+	if throttled == nil || !throttled.Locked || throttled.RetryAfter <= 14*time.Minute {
+		t.Fatalf("after 10 failures: got %v, want locked for 15 minutes", throttled)
+	}
+	var locked []models.AuditEvent
+	if err := config.DB.Where("type = ?", models.AuditLoginLocked).Find(&locked).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(locked) != 1 || locked[0].AdminID == nil || *locked[0].AdminID != admin.ID {
+		t.Errorf("got %d lockout events, want one for the admin", len(locked))
+	}
+
+	// An admin can lift the lockout
+	lockouts, err := ListLockouts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lockouts) != 1 || lockouts[0].Kind != models.ThrottleUsername || lockouts[0].Key != "root" {
+		t.Fatalf("got lockouts %+v, want the username alone", lockouts)
+	}
+	if _, err := UnlockLogin(lockouts[0].ID, admin.ID); err != nil {
+		t.Fatal(err)
+	}
+	if throttled := loginThrottled(t, "root", "10.1.0.1"); throttled != nil {
+		t.Errorf("after unlocking: got %v, want logins allowed", throttled)
+	}
+	var unlocked models.AuditEvent
+	if err := config.DB.Where("type = ?", models.AuditLoginUnlocked).First(&unlocked).Error; err != nil {
+		t.Fatal(err)
+	}
+	if unlocked.AdminID == nil || *unlocked.AdminID != admin.ID || unlocked.ActorID == nil {
+		t.Errorf("got unlock event %+v, want it recorded against the admin", unlocked)
+	}
+}
+
+func TestLoginThrottleByIP(t *testing.T) {
+	setupTestDB(t)
+
+	// Guessing many usernames from one address slows the address down
+	for i := 0; i < 10; i++ {
+		if err := RecordLoginFailure(fmt.Sprintf("user%d", i), "10.0.0.1", nil, "unknown username"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if throttled := loginThrottled(t, "someone", "10.0.0.1"); throttled == nil || throttled.Locked {
+		t.Errorf("got %v, want the address delayed", throttled)
+	}
+	if throttled := loginThrottled(t, "someone", "10.0.0.2"); throttled != nil {
+		t.Errorf("another address: got %v, want logins allowed", throttled)
+	}
+
+	// Failures are forgotten after a quiet day
+	if err := config.DB.Model(&models.LoginThrottle{}).Where("throttle_key = ?", "10.0.0.1").
+		Update("last_failure_at", time.Now().Add(-25*time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordLoginFailure("someone", "10.0.0.1", nil, "unknown username"); err != nil {
+		t.Fatal(err)
+	}
+	var row models.LoginThrottle
+	if err := config.DB.Where("kind = ? AND throttle_key = ?", models.ThrottleIP, "10.0.0.1").First(&row).Error; err != nil {
+		t.Fatal(err)
+	}
+	if row.Failures != 1 || row.LockedUntil != nil {
+		t.Errorf("got %d failures locked until %v, want the count started over", row.Failures, row.LockedUntil)
+	}
+}
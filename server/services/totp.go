@@ -0,0 +1,334 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// totpPeriod is the length of a TOTP time step (RFC 6238)
+	totpPeriod = 30
+	// totpDigits is the length of a TOTP code
+	totpDigits = 6
+	// totpSkew is how many time steps before and after the current one are
+	// accepted, for clocks that drift
+	totpSkew = 1
+	// recoveryCodeCount is how many recovery codes an admin gets
+	recoveryCodeCount = 10
+)
+
+// ErrInvalidTwoFactorCode is returned for a wrong, reused or expired TOTP or recovery code
+var ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+
+// totpEncoding is the base32 alphabet of TOTP secrets, without padding as
+// authenticator apps expect
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// totpCode computes the code of a base32 secret for a time step (RFC 4226
+// dynamic truncation of HMAC-SHA1)
+func totpCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%modulo), nil
+}
+
+// totpStep returns the time step t falls in
+func totpStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+// matchTOTP returns the time step a code is valid for around now, or 0 when
+// it matches none after lastStep
+func matchTOTP(secret, code string, now time.Time, lastStep int64) int64 {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0
+	}
+	current := totpStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		expected, err := totpCode(secret, step)
+		if err == nil && subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step
+		}
+	}
+	return 0
+}
+
+// totpIssuer names the service in authenticator apps, from TOTP_ISSUER
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "Stone-Paper-Scissors"
+}
+
+// TOTPProvisioningURI returns the otpauth:// URI that authenticator apps read
+// from a QR code
+func TOTPProvisioningURI(account, secret string) string {
+	issuer := totpIssuer()
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// BeginTOTPSetup generates a new TOTP secret for an admin, which takes effect
+// once EnableTOTP has verified a code from it
+func BeginTOTPSetup(admin *models.Admin) (string, error) {
+	if admin.TOTPEnabled {
+		return "", invalid("Two-factor authentication is already enabled")
+	}
+	key := make([]byte, 20)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	secret := totpEncoding.EncodeToString(key)
+	err := config.DB.Model(&models.Admin{}).Where("id = ?", admin.ID).Updates(map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_last_step": 0,
+	}).Error
+	if err != nil {
+		return "", err
+	}
+	admin.TOTPSecret = secret
+	return secret, nil
+}
+
+// EnableTOTP turns two-factor authentication on once a code from the secret
+// of BeginTOTPSetup is verified, and returns a new set of recovery codes
+func EnableTOTP(admin *models.Admin, code string) ([]string, error) {
+	if admin.TOTPEnabled {
+		return nil, invalid("Two-factor authentication is already enabled")
+	}
+	if admin.TOTPSecret == "" {
+		return nil, invalid("Start the two-factor setup first")
+	}
+	step := matchTOTP(admin.TOTPSecret, code, time.Now(), 0)
+	if step == 0 {
+		return nil, invalid("Invalid code, check the time on your device")
+	}
+
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Admin{}).Where("id = ?", admin.ID).Updates(map[string]interface{}{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+		}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, admin.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	admin.TOTPEnabled = true
+	admin.TOTPLastStep = step
+	return codes, nil
+}
+
+// DisableTOTP turns two-factor authentication off for an admin and deletes
+// their secret and recovery codes
+func DisableTOTP(db *gorm.DB, adminID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Admin{}).Where("id = ?", adminID).Updates(map[string]interface{}{
+			"totp_secret":    "",
+			"totp_enabled":   false,
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("admin_id = ?", adminID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// normalizeRecoveryCode drops the separators and case of a recovery code
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// replaceRecoveryCodes deletes the recovery codes of an admin and returns a new set
+func replaceRecoveryCodes(tx *gorm.DB, adminID uint) ([]string, error) {
+	if err := tx.Where("admin_id = ?", adminID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, recoveryCodeCount)
+	rows := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(buf))[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+		rows = append(rows, models.RecoveryCode{AdminID: adminID, CodeHash: HashToken(code)})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of an admin with two-factor
+// authentication enabled, after checking a TOTP or recovery code
+func RegenerateRecoveryCodes(admin *models.Admin, code string) ([]string, error) {
+	if !admin.TOTPEnabled {
+		return nil, invalid("Two-factor authentication is not enabled")
+	}
+	if _, err := VerifySecondFactor(admin, code); err != nil {
+		return nil, err
+	}
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, admin.ID)
+		return err
+	})
+	return codes, err
+}
+
+// VerifySecondFactor checks a TOTP code, or else a recovery code, of an admin
+// with two-factor authentication enabled. Either can only be used once; it
+// reports whether a recovery code was used.
+func VerifySecondFactor(admin *models.Admin, code string) (recovery bool, err error) {
+	if !admin.TOTPEnabled {
+		return false, ErrInvalidTwoFactorCode
+	}
+
+	if step := matchTOTP(admin.TOTPSecret, code, time.Now(), admin.TOTPLastStep); step != 0 {
+		// Record the step so that the code cannot be replayed
+		result := config.DB.Model(&models.Admin{}).
+			Where("id = ? AND totp_last_step < ?", admin.ID, step).
+			Update("totp_last_step", step)
+		if result.Error != nil {
+			return false, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return false, ErrInvalidTwoFactorCode
+		}
+		admin.TOTPLastStep = step
+		return false, nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return false, ErrInvalidTwoFactorCode
+	}
+	result := config.DB.Model(&models.RecoveryCode{}).
+		Where("admin_id = ? AND code_hash = ? AND used_at IS NULL", admin.ID, HashToken(normalized)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, ErrInvalidTwoFactorCode
+	}
+	return true, nil
+}
+
+// RemainingRecoveryCodes counts the unused recovery codes of an admin
+func RemainingRecoveryCodes(adminID uint) (int64, error) {
+	var count int64
+	err := config.DB.Model(&models.RecoveryCode{}).
+		Where("admin_id = ? AND used_at IS NULL", adminID).
+		Count(&count).Error
+	return count, err
+}
+
+// ErrInvalidLoginChallenge is returned for an unknown, expired or used login challenge
+var ErrInvalidLoginChallenge = errors.New("invalid or expired login challenge")
+
+// CreateLoginChallenge records the second step of a login for an admin who
+// has given their password, to be completed before ttl. Earlier challenges
+// of the admin stop working.
+func CreateLoginChallenge(adminID uint, ttl time.Duration) (*models.LoginChallenge, error) {
+	tokenID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	challenge := models.LoginChallenge{
+		AdminID:   adminID,
+		TokenID:   tokenID,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("admin_id = ?", adminID).Delete(&models.LoginChallenge{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&challenge).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// PendingLoginChallenge returns the login challenge of an admin with a token
+// ID, unless it has been used or has expired
+func PendingLoginChallenge(tokenID string, adminID uint) (*models.LoginChallenge, error) {
+	var challenge models.LoginChallenge
+	err := config.DB.Where("token_id = ? AND admin_id = ?", tokenID, adminID).First(&challenge).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidLoginChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+	if challenge.UsedAt != nil || !challenge.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidLoginChallenge
+	}
+	return &challenge, nil
+}
+
+// UseLoginChallenge uses up a login challenge, so that its token cannot
+// complete another login, even in a concurrent request
+func UseLoginChallenge(challenge *models.LoginChallenge) error {
+	result := config.DB.Model(&models.LoginChallenge{}).
+		Where("id = ? AND used_at IS NULL", challenge.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidLoginChallenge
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+)
+
+// rfc6238Secret is the SHA1 key of the RFC 6238 test vectors, "12345678901234567890"
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	// The 8 digit codes of RFC 6238 appendix B, truncated to 6 digits
+	for unix, want := range map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	} {
+		got, err := totpCode(rfc6238Secret, totpStep(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("at %d: got %s, want %s", unix, got, want)
+		}
+	}
+	if _, err := totpCode("not base32!", 1); err == nil {
+		t.Error("got a code for an invalid secret")
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := totpStep(now)
+	code := func(step int64) string {
+		t.Helper()
+		code, err := totpCode(rfc6238Secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	// One step of clock drift either way is accepted
+	for _, step := range []int64{current - 1, current, current + 1} {
+		if got := matchTOTP(rfc6238Secret, " "+code(step)+" ", now, 0); got != step {
+			t.Errorf("step %d: got %d", step-current, got)
+		}
+	}
+	// Codes outside the window, or for a step already used, are refused
+	for name, c := range map[string]string{
+		"two steps early": code(current - 2),
+		"two steps late":  code(current + 2),
+		"wrong length":    code(current)[:5],
+		"earlier step":    code(current - 1),
+		"used step":       code(current),
+		"not a code":      "abcdef",
+	} {
+		if got := matchTOTP(rfc6238Secret, c, now, current); got != 0 {
+			t.Errorf("%s: got step %d, want no match", name, got-current)
+		}
+	}
+}
+
+func TestSecondFactorCodesAreSingleUse(t *testing.T) {
+	setupTestDB(t)
+
+	admin := &models.Admin{Username: "root", Email: "root@example.com", PasswordHash: "x", Role: models.RoleSuperAdmin}
+	create(t, admin)
+
+	if _, err := EnableTOTP(admin, "123456"); !isValidationError(err) {
+		t.Errorf("enabling before setup: got %v, want a validation error", err)
+	}
+	secret, err := BeginTOTPSetup(admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := totpStep(time.Now())
+	code, err := totpCode(secret, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, err := EnableTOTP(admin, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount || !admin.TOTPEnabled {
+		t.Fatalf("got %d recovery codes, enabled %v", len(codes), admin.TOTPEnabled)
+	}
+	if _, err := BeginTOTPSetup(admin); !isValidationError(err) {
+		t.Errorf("setting up again: got %v, want a validation error", err)
+	}
+
+	// The code that enabled two-factor authentication cannot log in
+	if _, err := VerifySecondFactor(admin, code); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("replayed TOTP code: got %v, want ErrInvalidTwoFactorCode", err)
+	}
+	next, err := totpCode(secret, current+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recovery, err := VerifySecondFactor(admin, next); err != nil || recovery {
+		t.Errorf("next TOTP code: got recovery %v, %v, want accepted", recovery, err)
+	}
+
+	// Recovery codes work once each, with or without the dash
+	if recovery, err := VerifySecondFactor(admin, codes[0]); err != nil || !recovery {
+		t.Errorf("recovery code: got recovery %v, %v, want accepted", recovery, err)
+	}
+	if _, err := VerifySecondFactor(admin, codes[0]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("used recovery code: got %v, want ErrInvalidTwoFactorCode", err)
+	}
+	if recovery, err := VerifySecondFactor(admin, strings.ToUpper(strings.ReplaceAll(codes[1], "-", ""))); err != nil || !recovery {
+		t.Errorf("recovery code without the dash: got recovery %v, %v, want accepted", recovery, err)
+	}
+	if remaining, err := RemainingRecoveryCodes(admin.ID); err != nil || remaining != recoveryCodeCount-2 {
+		t.Errorf("got %d recovery codes left, %v, want %d", remaining, err, recoveryCodeCount-2)
+	}
+
+	// Regenerating replaces every code
+	regenerated, err := RegenerateRecoveryCodes(admin, codes[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifySecondFactor(admin, codes[3]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("code from before regenerating: got %v, want ErrInvalidTwoFactorCode", err)
+	}
+	if remaining, err := RemainingRecoveryCodes(admin.ID); err != nil || remaining != int64(len(regenerated)) {
+		t.Errorf("got %d recovery codes left, %v, want %d", remaining, err, len(regenerated))
+	}
+
+	if err := DisableTOTP(config.DB, admin.ID); err != nil {
+		t.Fatal(err)
+	}
+	if remaining, err := RemainingRecoveryCodes(admin.ID); err != nil || remaining != 0 {
+		t.Errorf("got %d recovery codes after disabling, %v, want none", remaining, err)
+	}
+}
+
+func TestLoginChallengeSingleUse(t *testing.T) {
+	setupTestDB(t)
+
+	earlier, err := CreateLoginChallenge(1, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge, err := CreateLoginChallenge(1, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PendingLoginChallenge(earlier.TokenID, 1); !errors.Is(err, ErrInvalidLoginChallenge) {
+		t.Errorf("earlier challenge: got %v, want ErrInvalidLoginChallenge", err)
+	}
+	if _, err := PendingLoginChallenge(challenge.TokenID, 2); !errors.Is(err, ErrInvalidLoginChallenge) {
+		t.Errorf("another admin's challenge: got %v, want ErrInvalidLoginChallenge", err)
+	}
+
+	pending, err := PendingLoginChallenge(challenge.TokenID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UseLoginChallenge(pending); err != nil {
+		t.Fatal(err)
+	}
+	if err := UseLoginChallenge(pending); !errors.Is(err, ErrInvalidLoginChallenge) {
+		t.Errorf("using twice: got %v, want ErrInvalidLoginChallenge", err)
+	}
+	if _, err := PendingLoginChallenge(challenge.TokenID, 1); !errors.Is(err, ErrInvalidLoginChallenge) {
+		t.Errorf("used challenge: got %v, want ErrInvalidLoginChallenge", err)
+	}
+
+	expired, err := CreateLoginChallenge(1, -time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PendingLoginChallenge(expired.TokenID, 1); !errors.Is(err, ErrInvalidLoginChallenge) {
+		t.Errorf("expired challenge: got %v, want ErrInvalidLoginChallenge", err)
+	}
+}
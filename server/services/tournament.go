@@ -0,0 +1,320 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+// CreateTournament registers a new tournament with its field of players.
+// Seeding and the schedule are only produced when the tournament starts.
+func CreateTournament(req models.CreateTournamentRequest, createdByAdminID *uint) (*models.Tournament, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, invalid("Tournament name is required")
+	}
+
+	format := req.Format
+	if format == "" {
+		format = models.FormatSingleElimination
+	}
+	if !isKnownFormat(format) {
+		return nil, invalid("Unknown tournament format %q", format)
+	}
+
+	if len(req.PlayerIDs) < 2 {
+		return nil, invalid("A tournament needs at least 2 players")
+	}
+
+	totalRounds := 0
+	if format == models.FormatSwiss {
+		totalRounds = req.Rounds
+		if totalRounds == 0 {
+			totalRounds = defaultSwissRounds(len(req.PlayerIDs))
+		}
+		if totalRounds < 1 || totalRounds >= len(req.PlayerIDs) {
+			return nil, invalid("A Swiss tournament needs between 1 and %d rounds", len(req.PlayerIDs)-1)
+		}
+	}
+
+	seen := make(map[uint]bool, len(req.PlayerIDs))
+	for _, id := range req.PlayerIDs {
+		if seen[id] {
+			return nil, invalid("Player %d is entered more than once", id)
+		}
+		seen[id] = true
+	}
+
+	var count int64
+	if err := config.DB.Model(&models.Player{}).Where("id IN ?", req.PlayerIDs).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if int(count) != len(req.PlayerIDs) {
+		return nil, invalid("One or more players do not exist")
+	}
+
+	tournament := models.Tournament{
+		Name:             name,
+		Format:           format,
+		Status:           models.TournamentPending,
+		TotalRounds:      totalRounds,
+		DoubleRoundRobin: format == models.FormatRoundRobin && req.DoubleRoundRobin,
+		BracketReset:     format == models.FormatDoubleElimination && req.BracketReset,
+		CreatedByAdminID: createdByAdminID,
+	}
+
+	err := WriteTransaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&tournament).Error; err != nil {
+			return err
+		}
+
+		participants := make([]models.TournamentParticipant, 0, len(req.PlayerIDs))
+		for _, id := range req.PlayerIDs {
+			participants = append(participants, models.TournamentParticipant{
+				TournamentID: tournament.ID,
+				PlayerID:     id,
+			})
+		}
+		return tx.Create(&participants).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return GetTournament(tournament.ID)
+}
+
+func isKnownFormat(format models.TournamentFormat) bool {
+	switch format {
+	case models.FormatSingleElimination, models.FormatDoubleElimination, models.FormatSwiss, models.FormatRoundRobin:
+		return true
+	}
+	return false
+}
+
+// GetTournament loads a tournament with its participants and schedule
+func GetTournament(id uint) (*models.Tournament, error) {
+	var tournament models.Tournament
+	err := config.DB.
+		Preload("Winner").
+		Preload("Participants", func(db *gorm.DB) *gorm.DB {
+			return db.Order("seed ASC, id ASC")
+		}).
+		Preload("Participants.Player").
+		Preload("Matches", func(db *gorm.DB) *gorm.DB {
+			return db.Order("round ASC, position ASC")
+		}).
+		Preload("Matches.Player1").
+		Preload("Matches.Player2").
+		First(&tournament, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tournament, nil
+}
+
+// ListTournaments returns tournaments, newest first
+func ListTournaments(limit, offset int) ([]models.Tournament, int64, error) {
+	var tournaments []models.Tournament
+	var total int64
+
+	query := config.DB.Model(&models.Tournament{})
+	query.Count(&total)
+
+	err := query.Preload("Winner").
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&tournaments).Error
+	return tournaments, total, err
+}
+
+// GetTournamentFixtures returns the scheduled matches of a tournament,
+// optionally limited to one round and to played or pending fixtures
+func GetTournamentFixtures(id uint, round int, status string) ([]models.TournamentMatch, error) {
+	var tournament models.Tournament
+	if err := config.DB.First(&tournament, id).Error; err != nil {
+		return nil, err
+	}
+
+	query := config.DB.
+		Preload("Player1").
+		Preload("Player2").
+		Preload("Match").
+		Where("tournament_id = ?", tournament.ID).
+		Order("round ASC, position ASC")
+
+	if round > 0 {
+		query = query.Where("round = ?", round)
+	}
+	switch status {
+	case "":
+	case "played":
+		query = query.Where("completed_at IS NOT NULL")
+	case "pending":
+		query = query.Where("completed_at IS NULL")
+	default:
+		return nil, invalid("Status must be played or pending")
+	}
+
+	var fixtures []models.TournamentMatch
+	err := query.Find(&fixtures).Error
+	return fixtures, err
+}
+
+// StartTournament seeds the field by current rating and generates the schedule
+func StartTournament(id uint) (*models.Tournament, error) {
+	err := WriteTransaction(func(tx *gorm.DB) error {
+		var tournament models.Tournament
+		if err := tx.First(&tournament, id).Error; err != nil {
+			return err
+		}
+		if tournament.Status != models.TournamentPending {
+			return invalid("Tournament has already started")
+		}
+
+		var participants []models.TournamentParticipant
+		if err := tx.Preload("Player").Where("tournament_id = ?", tournament.ID).Find(&participants).Error; err != nil {
+			return err
+		}
+
+		// Seed by current rating, highest first
+		sort.SliceStable(participants, func(i, j int) bool {
+			if participants[i].Player.Elo != participants[j].Player.Elo {
+				return participants[i].Player.Elo > participants[j].Player.Elo
+			}
+			return participants[i].PlayerID < participants[j].PlayerID
+		})
+		for i := range participants {
+			participants[i].Seed = i + 1
+			participants[i].SeedElo = participants[i].Player.Elo
+			if err := tx.Model(&participants[i]).Updates(map[string]interface{}{
+				"seed":     participants[i].Seed,
+				"seed_elo": participants[i].SeedElo,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		tournament.Status = models.TournamentInProgress
+		tournament.StartedAt = &now
+		if err := tx.Save(&tournament).Error; err != nil {
+			return err
+		}
+
+		switch tournament.Format {
+		case models.FormatSingleElimination:
+			return generateSingleElimination(tx, &tournament, participants)
+		case models.FormatDoubleElimination:
+			return generateDoubleElimination(tx, &tournament, participants)
+		case models.FormatSwiss:
+			return pairSwissRound(tx, &tournament, 1)
+		case models.FormatRoundRobin:
+			return generateRoundRobin(tx, &tournament, participants)
+		}
+		return invalid("Unknown tournament format %q", tournament.Format)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return GetTournament(id)
+}
+
+// RecordTournamentResult rates the result of a scheduled tournament match as a
+// normal match and progresses the tournament accordingly
+func RecordTournamentResult(tournamentID, tournamentMatchID uint, req models.TournamentResultRequest, createdByAdminID, createdByAPIKeyID *uint) (*models.TournamentMatch, error) {
+	var slot models.TournamentMatch
+
+	err := WriteTransaction(func(tx *gorm.DB) error {
+		var tournament models.Tournament
+		if err := tx.First(&tournament, tournamentID).Error; err != nil {
+			return err
+		}
+		if tournament.Status != models.TournamentInProgress {
+			return invalid("Tournament is not in progress")
+		}
+
+		// Locked so that two results for the same match cannot both be recorded
+		if err := forUpdate(tx).Where("tournament_id = ?", tournament.ID).First(&slot, tournamentMatchID).Error; err != nil {
+			return err
+		}
+		if slot.CompletedAt != nil {
+			return invalid("This match already has a result")
+		}
+		if slot.Player1ID == nil || slot.Player2ID == nil {
+			return invalid("Both players of this match are not known yet")
+		}
+		if req.Player1Score == req.Player2Score && isElimination(tournament.Format) {
+			return invalid("Knockout matches cannot end in a draw")
+		}
+
+		match, err := RecordMatch(tx, MatchInput{
+			Player1ID:         *slot.Player1ID,
+			Player2ID:         *slot.Player2ID,
+			Player1Score:      req.Player1Score,
+			Player2Score:      req.Player2Score,
+			Rounds:            req.Rounds,
+			CreatedByAdminID:  createdByAdminID,
+			CreatedByAPIKeyID: createdByAPIKeyID,
+		})
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		slot.MatchID = &match.ID
+		slot.WinnerID = match.WinnerID
+		slot.CompletedAt = &now
+		if err := tx.Save(&slot).Error; err != nil {
+			return err
+		}
+
+		if err := progressTournament(tx, &tournament, &slot); err != nil {
+			return err
+		}
+		return RefreshChampion(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.DB.Preload("Player1").Preload("Player2").Preload("Match").First(&slot, slot.ID).Error; err != nil {
+		return nil, err
+	}
+	return &slot, nil
+}
+
+// progressTournament moves a tournament on after one of its matches has a result
+func progressTournament(tx *gorm.DB, tournament *models.Tournament, slot *models.TournamentMatch) error {
+	switch tournament.Format {
+	case models.FormatSingleElimination:
+		return advanceWinner(tx, tournament, slot)
+	case models.FormatDoubleElimination:
+		return advanceDoubleElimination(tx, tournament, slot)
+	case models.FormatSwiss:
+		return progressSwiss(tx, tournament, slot.Round)
+	case models.FormatRoundRobin:
+		return progressRoundRobin(tx, tournament)
+	}
+	return nil
+}
+
+func isElimination(format models.TournamentFormat) bool {
+	return format == models.FormatSingleElimination || format == models.FormatDoubleElimination
+}
+
+// completeTournament closes a tournament and records its winner
+func completeTournament(tx *gorm.DB, tournament *models.Tournament, winnerID uint) error {
+	now := time.Now()
+	tournament.Status = models.TournamentCompleted
+	tournament.WinnerID = &winnerID
+	tournament.CompletedAt = &now
+	return tx.Save(tournament).Error
+}
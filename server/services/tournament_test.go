@@ -0,0 +1,154 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"stone-paper-scissors/config"
+	"stone-paper-scissors/models"
+
+	"gorm.io/gorm"
+)
+
+// startTestTournament starts a tournament between n new players, who are
+// returned in seed order
+func startTestTournament(t *testing.T, req models.CreateTournamentRequest, n int) (*models.Tournament, []*models.Player) {
+	t.Helper()
+	players := make([]*models.Player, n)
+	for i := range players {
+		players[i] = &models.Player{Name: fmt.Sprintf("Seed %d", i+1), Elo: float64(1500 - i*10)}
+		create(t, players[i])
+		req.PlayerIDs = append(req.PlayerIDs, players[i].ID)
+	}
+	if req.Name == "" {
+		req.Name = "Open"
+	}
+	tournament, err := CreateTournament(req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tournament, err = StartTournament(tournament.ID); err != nil {
+		t.Fatal(err)
+	}
+	return tournament, players
+}
+
+// tournamentSlot loads a match of a tournament by its place in the schedule
+func tournamentSlot(t *testing.T, tournamentID uint, bracket models.BracketSide, round, position int) models.TournamentMatch {
+	t.Helper()
+	var slot models.TournamentMatch
+	err := config.DB.Where("tournament_id = ? AND bracket = ? AND round = ? AND position = ?", tournamentID, bracket, round, position).
+		First(&slot).Error
+	if err != nil {
+		t.Fatalf("%s round %d match %d: %v", bracket, round, position, err)
+	}
+	return slot
+}
+
+// playTournamentMatch records a 2-0 win for winner, or a 1-1 draw when winner is nil
+func playTournamentMatch(t *testing.T, slot models.TournamentMatch, winner *models.Player) {
+	t.Helper()
+	req := models.TournamentResultRequest{Player1Score: 1, Player2Score: 1}
+	switch {
+	case winner == nil:
+	case slot.Player1ID != nil && *slot.Player1ID == winner.ID:
+		req = models.TournamentResultRequest{Player1Score: 2}
+	default:
+		req = models.TournamentResultRequest{Player2Score: 2}
+	}
+	if _, err := RecordTournamentResult(slot.TournamentID, slot.ID, req, nil, nil); err != nil {
+		t.Fatalf("round %d match %d: %v", slot.Round, slot.Position, err)
+	}
+}
+
+// checkSlotPlayers fails unless a match is between the given players, nil
+// standing for a side not known yet
+func checkSlotPlayers(t *testing.T, slot models.TournamentMatch, player1, player2 *models.Player) {
+	t.Helper()
+	same := func(id *uint, player *models.Player) bool {
+		if player == nil {
+			return id == nil
+		}
+		return id != nil && *id == player.ID
+	}
+	if !same(slot.Player1ID, player1) || !same(slot.Player2ID, player2) {
+		t.Errorf("%s round %d match %d: got players %v and %v", slot.Bracket, slot.Round, slot.Position, slot.Player1ID, slot.Player2ID)
+	}
+}
+
+// checkTournamentWinner fails unless a tournament is completed and won by winner
+func checkTournamentWinner(t *testing.T, id uint, winner *models.Player) {
+	t.Helper()
+	tournament, err := GetTournament(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tournament.Status != models.TournamentCompleted || tournament.WinnerID == nil || *tournament.WinnerID != winner.ID {
+		t.Errorf("got status %s and winner %v, want completed and won by %s", tournament.Status, tournament.WinnerID, winner.Name)
+	}
+}
+
+func TestSingleEliminationByesAndProgress(t *testing.T) {
+	setupTestDB(t)
+
+	tournament, seed := startTestTournament(t, models.CreateTournamentRequest{Format: models.FormatSingleElimination}, 6)
+	if tournament.TotalRounds != 3 {
+		t.Fatalf("got %d rounds, want 3", tournament.TotalRounds)
+	}
+	slot := func(round, position int) models.TournamentMatch {
+		return tournamentSlot(t, tournament.ID, models.BracketWinners, round, position)
+	}
+
+	// Seeds 1 and 2 get the byes and are through to the second round
+	checkSlotPlayers(t, slot(1, 1), seed[0], nil)
+	checkSlotPlayers(t, slot(1, 2), seed[3], seed[4])
+	checkSlotPlayers(t, slot(1, 3), seed[1], nil)
+	checkSlotPlayers(t, slot(1, 4), seed[2], seed[5])
+	for _, position := range []int{1, 3} {
+		if bye := slot(1, position); !bye.IsBye || bye.CompletedAt == nil {
+			t.Errorf("match %d: got bye %v, completed %v", position, bye.IsBye, bye.CompletedAt)
+		}
+	}
+	checkSlotPlayers(t, slot(2, 1), seed[0], nil)
+	checkSlotPlayers(t, slot(2, 2), seed[1], nil)
+
+	// Knockout matches need a winner, and results are only recorded once
+	draw := models.TournamentResultRequest{Player1Score: 1, Player2Score: 1}
+	if _, err := RecordTournamentResult(tournament.ID, slot(1, 2).ID, draw, nil, nil); err == nil {
+		t.Error("a draw was recorded in a knockout match")
+	}
+	playTournamentMatch(t, slot(1, 2), seed[3])
+	again := models.TournamentResultRequest{Player1Score: 2}
+	if _, err := RecordTournamentResult(tournament.ID, slot(1, 2).ID, again, nil, nil); err == nil {
+		t.Error("a second result was recorded")
+	}
+	if _, err := RecordTournamentResult(tournament.ID, slot(2, 2).ID, again, nil, nil); err == nil {
+		t.Error("a result was recorded before both players were known")
+	}
+
+	playTournamentMatch(t, slot(1, 4), seed[5])
+	checkSlotPlayers(t, slot(2, 1), seed[0], seed[3])
+	checkSlotPlayers(t, slot(2, 2), seed[1], seed[5])
+
+	playTournamentMatch(t, slot(2, 1), seed[0])
+	playTournamentMatch(t, slot(2, 2), seed[5])
+	checkSlotPlayers(t, slot(3, 1), seed[0], seed[5])
+	playTournamentMatch(t, slot(3, 1), seed[5])
+	checkTournamentWinner(t, tournament.ID, seed[5])
+
+	standings, err := GetTournamentStandings(tournament.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if standings[0].PlayerID != seed[5].ID || standings[1].PlayerID != seed[0].ID {
+		t.Errorf("got %s then %s at the top, want Seed 6 then Seed 1", standings[0].PlayerName, standings[1].PlayerName)
+	}
+
+	// The matches the bracket was decided by cannot be deleted
+	err = WriteTransaction(func(tx *gorm.DB) error {
+		return DeleteMatch(tx, *slot(3, 1).MatchID)
+	})
+	if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("deleting the final: got %v, want a validation error", err)
+	}
+}